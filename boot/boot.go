@@ -2,26 +2,37 @@ package boot
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"go-microservice/internal/config"
+	"go-microservice/internal/repository"
+	"go-microservice/internal/service"
+	database "go-microservice/pkg/cache"
 	"go-microservice/pkg/http"
 	"go-microservice/pkg/logger"
+	"go-microservice/pkg/messaging"
+	"go-microservice/pkg/upload"
 
 	"go.uber.org/zap"
 )
 
 type App struct {
-	Config *config.Config
-	Server *http.Server
+	Config   *config.Config
+	Server   *http.Server
+	Services *service.Registry
 }
 
 func Start(ctx context.Context) {
 	app := &App{}
 
+	// Background workers stop when the application shuts down
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Initialize configuration
 	cfg, err := initConfig()
 	if err != nil {
@@ -36,17 +47,20 @@ func Start(ctx context.Context) {
 
 	defer logger.Sync()
 
-	env := os.Getenv("APP_ENV")
-	if env == "" {
-		env = "dev"
-	}
-
 	logger.Info("Configuration loaded successfully",
-		zap.String("env", env),
+		zap.String("env", cfg.Env),
 	)
 
 	logger.Info("Logger initialized successfully")
 
+	// Initialize services
+	app.Services, err = initServices(ctx, cfg)
+	if err != nil {
+		logger.Fatal("Failed to initialize services", zap.Error(err))
+	}
+	service.SetRegistry(app.Services)
+	logger.Info("Services initialized successfully")
+
 	// Initialize HTTP server
 	app.Server = initHTTPServer()
 	logger.Info("HTTP server initialized successfully")
@@ -57,6 +71,11 @@ func Start(ctx context.Context) {
 	}
 
 	logger.Info("Application routes registered successfully")
+
+	// Reload the configuration on SIGHUP and when its files change
+	watchConfig(ctx)
+	watchLogSignals(ctx)
+
 	logger.Info("Application boot sequence completed. Server is running.")
 
 	// Handle shutdown signals
@@ -75,17 +94,311 @@ func Start(ctx context.Context) {
 }
 
 func initConfig() (*config.Config, error) {
-	env := os.Getenv("APP_ENV")
-	if env == "" {
-		env = "dev"
+	return config.LoadConfig(config.LoadOptions{Args: os.Args[1:]})
+}
+
+// watchConfig reloads the configuration on SIGHUP and whenever one of its
+// files changes until ctx is done. Reloaded log levels apply right away.
+func watchConfig(ctx context.Context) {
+	config.OnChange("server", func(old, new *config.Config) {
+		if new.Server.LogLevel == old.Server.LogLevel {
+			return
+		}
+		if err := logger.SetLevel(new.Server.LogLevel); err != nil {
+			logger.Error("Failed to update log level", zap.Error(err))
+			return
+		}
+		logger.Info("Log level updated", zap.String("level", new.Server.LogLevel))
+	})
+
+	if err := config.WatchFiles(func(path string) {
+		reloadConfig("file " + path + " changed")
+	}); err != nil {
+		logger.Error("Failed to watch config files", zap.Error(err))
 	}
-	return config.LoadConfig("config/" + env + ".json")
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reloadConfig("SIGHUP")
+			}
+		}
+	}()
 }
 
+// reloadConfig reloads the configuration. Invalid configurations are
+// logged and rejected, keeping the current one.
+func reloadConfig(trigger string) {
+	changed, err := config.Reload()
+	if err != nil {
+		logger.Error("Config reload rejected, keeping the current configuration",
+			zap.String("trigger", trigger),
+			zap.Error(err))
+		return
+	}
+	logger.Info("Config reloaded",
+		zap.String("trigger", trigger),
+		zap.Strings("changed", changed))
+}
+
+// watchLogSignals turns on debug logging on SIGUSR1, for
+// server.log_debug_ttl when set, and restores the configured level on
+// SIGUSR2 until ctx is done
+func watchLogSignals(ctx context.Context) {
+	usr := make(chan os.Signal, 1)
+	signal.Notify(usr, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(usr)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-usr:
+				server := config.GetConfig().Server
+				if sig == syscall.SIGUSR1 {
+					logger.SetComponentLevel("", "debug", server.LogDebugTTL)
+					logger.Warn("Debug logging turned on by SIGUSR1", zap.Duration("ttl", server.LogDebugTTL))
+					continue
+				}
+				if err := logger.SetLevel(server.LogLevel); err != nil {
+					logger.Error("Failed to restore log level", zap.Error(err))
+					continue
+				}
+				logger.Warn("Log level restored by SIGUSR2", zap.String("level", server.LogLevel))
+			}
+		}
+	}()
+}
+
+// initLogger creates the logger with the configured sinks. Without sinks,
+// JSON logs go to stdout and to server.log_file when it is set.
 func initLogger(cfg *config.Config) error {
-	return logger.Init(cfg.Server.LogLevel, cfg.Server.LogFile)
+	sinks := cfg.Logging.Sinks
+	if len(sinks) == 0 {
+		sinks = []config.LogSinkConfig{{Type: "stdout"}}
+		if cfg.Server.LogFile != "" {
+			sinks = append(sinks, config.LogSinkConfig{
+				Type: "file",
+				File: config.LogFileConfig{Path: cfg.Server.LogFile},
+			})
+		}
+	}
+	return logger.Init(cfg.Server.LogLevel, sinks)
 }
 
 func initHTTPServer() *http.Server {
 	return http.NewServer()
 }
+
+func initServices(ctx context.Context, cfg *config.Config) (*service.Registry, error) {
+	registry := &service.Registry{}
+	store := repository.NewMemoryStore()
+
+	primary, err := initUploader(cfg, cfg.Upload.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s uploader: %w", cfg.Upload.Backend, err)
+	}
+
+	redis := &sharedRedis{config: &cfg.Redis}
+
+	if cfg.Upload.Quota.Enabled {
+		usage, err := initUsageRepository(cfg, redis)
+		if err != nil {
+			return nil, err
+		}
+		registry.Quota = service.NewQuotaService(cfg.Upload.Quota, usage)
+	}
+
+	keys, err := upload.NewKeyGenerator(cfg.Upload.KeyTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	registry.Service = service.NewService(store, primary, keys, registry.Quota)
+
+	// Messaging is optional; the service keeps running without a broker
+	client, err := initMessaging(ctx, cfg)
+	if err != nil {
+		logger.Error("Failed to connect to message broker, messaging disabled", zap.Error(err))
+	}
+	if client != nil {
+		var dedup messaging.DedupStore
+		if cfg.Kafka.Dedup.Enabled {
+			dedup, err = initDedupStore(cfg, redis)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		codec, err := messaging.NewEventCodec(cfg.Kafka.Events)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize event codec: %w", err)
+		}
+
+		deadLetters, err := initDeadLetterStore(cfg, redis)
+		if err != nil {
+			return nil, err
+		}
+
+		registry.Messaging = service.NewMessagingService(client, codec, dedup, deadLetters, logger.Named("messaging"), cfg)
+		registry.DeadLetters = registry.Messaging.DeadLetters()
+		if err := registry.Messaging.StartConsumers(ctx); err != nil {
+			return nil, fmt.Errorf("failed to start consumers: %w", err)
+		}
+
+		// Events stay in the outbox until a broker is available
+		registry.Outbox = service.NewOutboxRelay(cfg.Outbox, store.Outbox(), client, logger.Named("outbox"))
+		go registry.Outbox.Start(ctx)
+
+		if cfg.Messaging.Scheduler.Enabled {
+			scheduleStore, lock, err := initScheduleStore(cfg, redis)
+			if err != nil {
+				return nil, err
+			}
+			scheduler := messaging.NewScheduler(cfg.Messaging.Scheduler, client, scheduleStore, lock)
+			registry.Messaging.UseScheduler(scheduler)
+			go scheduler.Start(ctx)
+		}
+	}
+
+	if cfg.Upload.Lifecycle.Enabled {
+		var cold upload.Uploader
+		if cfg.Upload.Lifecycle.ColdBackend != "" {
+			cold, err = initUploader(cfg, cfg.Upload.Lifecycle.ColdBackend)
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize %s cold uploader: %w", cfg.Upload.Lifecycle.ColdBackend, err)
+			}
+		}
+
+		registry.Lifecycle = service.NewLifecycleService(cfg.Upload.Lifecycle, store.Files(), primary, cold, registry.Quota, client, logger.Named("lifecycle"))
+		go registry.Lifecycle.Start(ctx)
+	}
+
+	return registry, nil
+}
+
+// initMessaging connects to the configured messaging backend. Without an
+// explicit backend the feature flags pick Kafka or NATS; it returns nil if
+// neither is enabled.
+func initMessaging(ctx context.Context, cfg *config.Config) (messaging.Messaging, error) {
+	backend := cfg.Messaging.Backend
+	if backend == "" {
+		switch {
+		case cfg.Features.EnableKafka:
+			backend = "kafka"
+		case cfg.Features.EnableNATS:
+			backend = "nats"
+		default:
+			return nil, nil
+		}
+	}
+	return messaging.NewBackend(ctx, backend, cfg)
+}
+
+// sharedRedis connects to Redis on first use so that all services share one client
+type sharedRedis struct {
+	config *config.RedisConfig
+	client *database.RedisClient
+}
+
+func (r *sharedRedis) get() (*database.RedisClient, error) {
+	if r.client == nil {
+		client, err := database.NewRedisClient(r.config, logger.Named("redis"))
+		if err != nil {
+			return nil, err
+		}
+		r.client = client
+	}
+	return r.client, nil
+}
+
+// initUsageRepository creates the store for storage usage counters
+func initUsageRepository(cfg *config.Config, redis *sharedRedis) (repository.UsageRepository, error) {
+	switch cfg.Upload.Quota.Store {
+	case "", "memory":
+		return repository.NewMemoryUsageRepository(), nil
+	case "redis":
+		client, err := redis.get()
+		if err != nil {
+			return nil, err
+		}
+		return repository.NewRedisUsageRepository(client), nil
+	default:
+		return nil, fmt.Errorf("unsupported quota store %q", cfg.Upload.Quota.Store)
+	}
+}
+
+// initDedupStore creates the store for processed message IDs
+func initDedupStore(cfg *config.Config, redis *sharedRedis) (messaging.DedupStore, error) {
+	switch cfg.Kafka.Dedup.Store {
+	case "", "memory":
+		return messaging.NewMemoryDedupStore(), nil
+	case "redis":
+		client, err := redis.get()
+		if err != nil {
+			return nil, err
+		}
+		return messaging.NewRedisDedupStore(client), nil
+	default:
+		return nil, fmt.Errorf("unsupported dedup store %q", cfg.Kafka.Dedup.Store)
+	}
+}
+
+// initDeadLetterStore creates the store for dead letters shown by the admin API
+func initDeadLetterStore(cfg *config.Config, redis *sharedRedis) (service.DeadLetterStore, error) {
+	switch cfg.Kafka.Retry.DeadLetterStore {
+	case "", "memory":
+		return service.NewMemoryDeadLetterStore(cfg.Kafka.Retry.DeadLetterBuffer), nil
+	case "redis":
+		client, err := redis.get()
+		if err != nil {
+			return nil, err
+		}
+		return service.NewRedisDeadLetterStore(client, cfg.Kafka.Retry.DeadLetterBuffer), nil
+	default:
+		return nil, fmt.Errorf("unsupported dead-letter store %q", cfg.Kafka.Retry.DeadLetterStore)
+	}
+}
+
+// initScheduleStore creates the store for scheduled messages and the lock
+// electing the replica that dispatches them
+func initScheduleStore(cfg *config.Config, redis *sharedRedis) (messaging.ScheduleStore, messaging.LeaderLock, error) {
+	switch cfg.Messaging.Scheduler.Store {
+	case "", "memory":
+		return messaging.NewMemoryScheduleStore(), messaging.LocalLock{}, nil
+	case "redis":
+		client, err := redis.get()
+		if err != nil {
+			return nil, nil, err
+		}
+		return messaging.NewRedisScheduleStore(client), messaging.NewRedisLeaderLock(client, "messaging:scheduler:leader"), nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported scheduler store %q", cfg.Messaging.Scheduler.Store)
+	}
+}
+
+// initUploader creates an uploader for one of the backends configured under "upload"
+func initUploader(cfg *config.Config, backend string) (upload.Uploader, error) {
+	switch backend {
+	case "s3":
+		return upload.NewUploader(backend, &cfg.Upload.S3Config)
+	case "minio":
+		return upload.NewUploader(backend, &cfg.Upload.MinioConfig)
+	case "gcs":
+		return upload.NewUploader(backend, &cfg.Upload.GCSConfig)
+	case "ftp":
+		return upload.NewUploader(backend, &cfg.Upload.FTPConfig)
+	case "sftp":
+		return upload.NewUploader(backend, &cfg.Upload.SFTPConfig)
+	case "local":
+		return upload.NewUploader(backend, &cfg.Upload.LocalConfig)
+	default:
+		return nil, upload.ErrUnsupportedBackend
+	}
+}
@@ -0,0 +1,63 @@
+package boot
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"go-microservice/internal/config"
+	"go-microservice/pkg/logger"
+)
+
+func TestWatchLogSignals(t *testing.T) {
+	dir := t.TempDir()
+	overlay := `{"server": {"log_level": "info", "log_debug_ttl": "200ms"}, "upload": {"backend": "local"}}`
+	if err := os.WriteFile(filepath.Join(dir, "test.json"), []byte(overlay), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.LoadConfig(config.LoadOptions{Dir: dir, Env: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { logger.SetLevel("info") })
+
+	watchLogSignals(t.Context())
+
+	// signal sends sig to the test process and waits for the global level
+	signal := func(sig syscall.Signal, want string) logger.LevelStatus {
+		t.Helper()
+		if err := syscall.Kill(os.Getpid(), sig); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for {
+			status := logger.GetLevels().Global
+			if status.Level == want {
+				return status
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("global level after %v = %q, want %q", sig, status.Level, want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// SIGUSR2 restores the configured level before the TTL is up
+	if status := signal(syscall.SIGUSR1, "debug"); status.RevertAt == nil {
+		t.Error("SIGUSR1 turned on debug logging without the configured TTL")
+	}
+	if status := signal(syscall.SIGUSR2, "info"); status.RevertAt != nil {
+		t.Errorf("SIGUSR2 left a revert at %v", status.RevertAt)
+	}
+
+	// Without SIGUSR2 debug logging ends after the TTL
+	signal(syscall.SIGUSR1, "debug")
+	time.Sleep(300 * time.Millisecond)
+	if got := logger.GetLevels().Global.Level; got != "info" {
+		t.Errorf("global level after the TTL = %q, want info", got)
+	}
+}
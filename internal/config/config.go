@@ -2,55 +2,214 @@ package config
 
 import (
 	"sync"
-
-	"github.com/spf13/viper"
+	"sync/atomic"
+	"time"
 )
 
 var (
-	cfg  *Config
-	once sync.Once
+	current atomic.Pointer[Config]
+	once    sync.Once
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Kafka    KafkaConfig    `mapstructure:"kafka"`
-	Upload   UploadConfig   `mapstructure:"upload"`
-	Features FeaturesConfig `mapstructure:"features"`
-	SFTP     SFTPConfig     `mapstructure:"sftp"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	Kafka     KafkaConfig     `mapstructure:"kafka"`
+	NATS      NATSConfig      `mapstructure:"nats"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Messaging MessagingConfig `mapstructure:"messaging"`
+	Outbox    OutboxConfig    `mapstructure:"outbox"`
+	Upload    UploadConfig    `mapstructure:"upload"`
+	Features  FeaturesConfig  `mapstructure:"features"`
+
+	// Env is the environment whose overlay file was loaded
+	Env string `mapstructure:"-"`
+
+	// secrets holds the keys of the settings resolved from secret references
+	secrets map[string]bool
 }
 
-// ServerConfig holds server-related configuration
+// ServerConfig holds server-related configuration. Admin endpoints require
+// AdminToken as a bearer token and are refused while it is empty. Outside
+// the dev environment AdminToken must be a secret reference such as
+// env://ADMIN_TOKEN rather than a literal. SIGUSR1
+// turns on debug logging for LogDebugTTL, or until SIGUSR2 when it is zero.
+// Port, Environment and LogFile need a restart to change; the other
+// settings are applied when the configuration is reloaded.
 type ServerConfig struct {
-	Port         string `mapstructure:"port"`
-	Environment  string `mapstructure:"environment"`
-	LogLevel     string `mapstructure:"log_level"`
-	LogFile      string `mapstructure:"log_file"`
-	AllowOrigins string `mapstructure:"allow_origins"`
+	Port         string          `mapstructure:"port" validate:"required,numeric"`
+	Environment  string          `mapstructure:"environment"`
+	LogLevel     string          `mapstructure:"log_level" validate:"omitempty,oneof=debug info warn error dpanic panic fatal"`
+	LogFile      string          `mapstructure:"log_file"`
+	LogDebugTTL  time.Duration   `mapstructure:"log_debug_ttl" validate:"min=0"`
+	AllowOrigins string          `mapstructure:"allow_origins"`
+	AdminToken   string          `mapstructure:"admin_token"`
+	RateLimit    RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// LoggingConfig holds the log sinks. Each sink writes the entries at or
+// above its own Level that pass the global and component levels. Without
+// sinks, JSON logs go to stdout and to server.log_file when it is set.
+// Changes need a restart.
+type LoggingConfig struct {
+	Sinks []LogSinkConfig `mapstructure:"sinks" validate:"dive"`
+}
+
+// LogSinkConfig describes one log output. File, Syslog and Remote hold the
+// settings of the file, syslog and http or tcp sinks.
+type LogSinkConfig struct {
+	Type     string          `mapstructure:"type" validate:"required,oneof=stdout stderr file syslog http tcp"`
+	Encoding string          `mapstructure:"encoding" validate:"omitempty,oneof=json console"` // "json" by default
+	Level    string          `mapstructure:"level" validate:"omitempty,oneof=debug info warn error dpanic panic fatal"`
+	File     LogFileConfig   `mapstructure:"file"`
+	Syslog   LogSyslogConfig `mapstructure:"syslog"`
+	Remote   LogRemoteConfig `mapstructure:"remote"`
+}
+
+// LogFileConfig holds file sink settings. The file is rotated when it
+// reaches MaxSizeMB and, if set, every RotateEvery. Rotated files are
+// gzipped with Compress and removed once there are more than MaxBackups or
+// they are older than MaxAgeDays; zero keeps them.
+type LogFileConfig struct {
+	Path        string        `mapstructure:"path"`
+	MaxSizeMB   int           `mapstructure:"max_size_mb" validate:"min=0"` // 100 by default
+	MaxBackups  int           `mapstructure:"max_backups" validate:"min=0"`
+	MaxAgeDays  int           `mapstructure:"max_age_days" validate:"min=0"`
+	RotateEvery time.Duration `mapstructure:"rotate_every" validate:"min=0"`
+	Compress    bool          `mapstructure:"compress"`
+}
+
+// LogSyslogConfig holds syslog sink settings. Without Network entries go to
+// the local syslog daemon.
+type LogSyslogConfig struct {
+	Network  string `mapstructure:"network" validate:"omitempty,oneof=udp tcp unix unixgram"`
+	Address  string `mapstructure:"address"`
+	Tag      string `mapstructure:"tag"`
+	Facility string `mapstructure:"facility" validate:"omitempty,oneof=user daemon local0 local1 local2 local3 local4 local5 local6 local7"` // "user" by default
+}
+
+// LogRemoteConfig holds settings of the http and tcp sinks, which ship
+// entries in the background. Up to BufferSize entries wait to be sent;
+// further entries are dropped so that a slow endpoint never blocks logging.
+// Batches of up to BatchSize entries are sent at least every FlushInterval,
+// to URL as newline-delimited JSON POSTs with Headers or to Address over TCP.
+type LogRemoteConfig struct {
+	URL           string            `mapstructure:"url" validate:"omitempty,url"`
+	Address       string            `mapstructure:"address" validate:"omitempty,hostname_port"`
+	Headers       map[string]string `mapstructure:"headers"`
+	BufferSize    int               `mapstructure:"buffer_size" validate:"min=0"`
+	BatchSize     int               `mapstructure:"batch_size" validate:"min=0"`
+	FlushInterval time.Duration     `mapstructure:"flush_interval" validate:"min=0"`
+	Timeout       time.Duration     `mapstructure:"timeout" validate:"min=0"`
+}
+
+// RateLimitConfig limits the requests each client IP may send. Burst
+// requests are allowed at once, refilled at RequestsPerSecond.
+type RateLimitConfig struct {
+	Enabled           bool    `mapstructure:"enabled"`
+	RequestsPerSecond float64 `mapstructure:"requests_per_second" validate:"min=0"`
+	Burst             int     `mapstructure:"burst" validate:"min=0"`
 }
 
 // RedisConfig holds Redis connection settings
 type RedisConfig struct {
 	Host     string `mapstructure:"host"`
-	Port     string `mapstructure:"port"`
+	Port     string `mapstructure:"port" validate:"omitempty,numeric"`
 	Password string `mapstructure:"password"`
-	DB       int    `mapstructure:"db"`
+	DB       int    `mapstructure:"db" validate:"min=0"`
 }
 
 // KafkaConfig holds Kafka connection and consumer settings
 type KafkaConfig struct {
-	Brokers       []string          `mapstructure:"brokers"`
-	ConsumerGroup string            `mapstructure:"consumer_group"`
-	Topics        map[string]string `mapstructure:"topics"`
-	Security      SecurityConfig    `mapstructure:"security"`
+	Brokers           []string            `mapstructure:"brokers" validate:"dive,hostname_port"`
+	Version           string              `mapstructure:"version"`
+	ClientID          string              `mapstructure:"client_id"`
+	ConsumerGroup     string              `mapstructure:"consumer_group"`
+	Topics            map[string]string   `mapstructure:"topics"`
+	HandlerTimeout    time.Duration       `mapstructure:"handler_timeout"`
+	ReplyTopic        string              `mapstructure:"reply_topic"`
+	AutoOffsetReset   string              `mapstructure:"auto_offset_reset" validate:"omitempty,oneof=earliest latest"` // "earliest" or "latest"
+	SessionTimeout    time.Duration       `mapstructure:"session_timeout"`
+	HeartbeatInterval time.Duration       `mapstructure:"heartbeat_interval"`
+	MaxPollInterval   time.Duration       `mapstructure:"max_poll_interval"`
+	MaxPollRecords    int                 `mapstructure:"max_poll_records" validate:"min=0"`
+	Producer          KafkaProducerConfig `mapstructure:"producer"`
+	Security          SecurityConfig      `mapstructure:"security"`
+	Retry             RetryConfig         `mapstructure:"retry"`
+	Dedup             DedupConfig         `mapstructure:"dedup"`
+	Events            EventsConfig        `mapstructure:"events"`
+}
+
+// KafkaProducerConfig holds Kafka producer settings
+type KafkaProducerConfig struct {
+	Acks        string `mapstructure:"acks" validate:"omitempty,oneof=all leader none"`                  // "all", "leader" or "none"
+	Compression string `mapstructure:"compression" validate:"omitempty,oneof=none gzip snappy lz4 zstd"` // "none", "gzip", "snappy", "lz4" or "zstd"
+	Idempotent  bool   `mapstructure:"idempotent"`
+	MaxRetries  int    `mapstructure:"max_retries"`
+	Partitioner string `mapstructure:"partitioner" validate:"omitempty,oneof=hash crc32 sticky random roundrobin manual"` // "hash", "crc32", "sticky", "random", "roundrobin" or "manual"
 }
 
-// SecurityConfig holds Kafka security settings
+// EventsConfig holds settings for CloudEvents envelopes and event schemas.
+// Schemas are read from SchemaDir; Avro and Protobuf encoding also need a
+// Confluent-compatible schema registry.
+type EventsConfig struct {
+	Source            string               `mapstructure:"source"`
+	SchemaDir         string               `mapstructure:"schema_dir"`
+	ValidateOnPublish bool                 `mapstructure:"validate_on_publish"`
+	ValidateOnConsume bool                 `mapstructure:"validate_on_consume"`
+	Encoding          string               `mapstructure:"encoding" validate:"omitempty,oneof=json avro protobuf"` // "json", "avro" or "protobuf"
+	Registry          SchemaRegistryConfig `mapstructure:"registry"`
+}
+
+// SchemaRegistryConfig holds schema registry connection settings
+type SchemaRegistryConfig struct {
+	URL      string        `mapstructure:"url" validate:"omitempty,url"`
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// DedupConfig holds settings for skipping messages that were already processed
+type DedupConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Store   string        `mapstructure:"store" validate:"omitempty,oneof=memory redis"` // "memory" or "redis"
+	TTL     time.Duration `mapstructure:"ttl"`
+	LockTTL time.Duration `mapstructure:"lock_ttl"`
+}
+
+// RetryConfig holds the retry policy for message consumers. A failing message
+// is retried in process MaxAttempts times with exponential backoff, then sent
+// through one retry topic per entry in Delays and finally to the dead-letter topic.
+type RetryConfig struct {
+	MaxAttempts      int             `mapstructure:"max_attempts" validate:"min=0"`
+	InitialBackoff   time.Duration   `mapstructure:"initial_backoff"`
+	MaxBackoff       time.Duration   `mapstructure:"max_backoff"`
+	Delays           []time.Duration `mapstructure:"delays"`
+	RetrySuffix      string          `mapstructure:"retry_suffix"`
+	DeadLetterSuffix string          `mapstructure:"dead_letter_suffix"`
+	DeadLetterBuffer int             `mapstructure:"dead_letter_buffer"`
+	DeadLetterStore  string          `mapstructure:"dead_letter_store" validate:"omitempty,oneof=memory redis"` // "memory" or "redis"
+}
+
+// OutboxConfig holds settings for the transactional outbox relay. Topics maps
+// aggregate types such as "user" or "file" to the topic their events go to.
+type OutboxConfig struct {
+	PollInterval    time.Duration     `mapstructure:"poll_interval"`
+	BatchSize       int               `mapstructure:"batch_size" validate:"min=0"`
+	Retention       time.Duration     `mapstructure:"retention"`
+	CleanupInterval time.Duration     `mapstructure:"cleanup_interval"`
+	Topics          map[string]string `mapstructure:"topics"`
+}
+
+// SecurityConfig holds Kafka security settings. Enabled turns on SASL
+// authentication; TLS is used when TLS is set or any certificate file is given.
 type SecurityConfig struct {
 	Enabled    bool   `mapstructure:"enabled"`
-	Username   string `mapstructure:"username"`
+	Mechanism  string `mapstructure:"mechanism" validate:"required_if=Enabled true,omitempty,oneof=PLAIN SCRAM-SHA-256 SCRAM-SHA-512"` // "PLAIN", "SCRAM-SHA-256" or "SCRAM-SHA-512"
+	Username   string `mapstructure:"username" validate:"required_if=Enabled true"`
 	Password   string `mapstructure:"password"`
+	TLS        bool   `mapstructure:"tls"`
 	CAFile     string `mapstructure:"ca_file"`
 	CertFile   string `mapstructure:"cert_file"`
 	KeyFile    string `mapstructure:"key_file"`
@@ -59,10 +218,16 @@ type SecurityConfig struct {
 
 // UploadConfig holds upload service configuration
 type UploadConfig struct {
-	Backend     string      `mapstructure:"backend"` // "s3", "minio", or "gcs"
-	S3Config    S3Config    `mapstructure:"s3"`
-	MinioConfig MinioConfig `mapstructure:"minio"`
-	GCSConfig   GCSConfig   `mapstructure:"gcs"`
+	Backend     string          `mapstructure:"backend" validate:"required,oneof=s3 minio gcs ftp sftp local"` // "s3", "minio", "gcs", "ftp", "sftp" or "local"
+	KeyTemplate string          `mapstructure:"key_template"`
+	S3Config    S3Config        `mapstructure:"s3"`
+	MinioConfig MinioConfig     `mapstructure:"minio"`
+	GCSConfig   GCSConfig       `mapstructure:"gcs"`
+	FTPConfig   FTPConfig       `mapstructure:"ftp"`
+	SFTPConfig  SFTPConfig      `mapstructure:"sftp"`
+	LocalConfig LocalConfig     `mapstructure:"local"`
+	Lifecycle   LifecycleConfig `mapstructure:"lifecycle"`
+	Quota       QuotaConfig     `mapstructure:"quota"`
 }
 
 type S3Config struct {
@@ -86,46 +251,206 @@ type GCSConfig struct {
 	CredentialsFile string `mapstructure:"credentials_file"`
 }
 
+// FTPConfig holds FTP connection settings
+type FTPConfig struct {
+	Host     string     `mapstructure:"host"`
+	Port     string     `mapstructure:"port" validate:"omitempty,numeric"`
+	Username string     `mapstructure:"username"`
+	Password string     `mapstructure:"password"`
+	BaseDir  string     `mapstructure:"base_dir"`
+	Pool     PoolConfig `mapstructure:"pool"`
+}
+
+// SFTPConfig holds SFTP connection settings. The server's host key is
+// checked against KnownHostsFile unless InsecureIgnoreHostKey is set.
+type SFTPConfig struct {
+	Host                  string     `mapstructure:"host"`
+	Port                  string     `mapstructure:"port" validate:"omitempty,numeric"`
+	Username              string     `mapstructure:"username"`
+	Password              string     `mapstructure:"password"`
+	PrivateKey            string     `mapstructure:"private_key"`
+	BaseDir               string     `mapstructure:"base_dir"`
+	KnownHostsFile        string     `mapstructure:"known_hosts_file"`
+	InsecureIgnoreHostKey bool       `mapstructure:"insecure_ignore_host_key"`
+	Pool                  PoolConfig `mapstructure:"pool"`
+}
+
+// LocalConfig holds local storage settings
+type LocalConfig struct {
+	BaseDir    string `mapstructure:"base_dir"`
+	BaseURL    string `mapstructure:"base_url"`
+	CreateDirs bool   `mapstructure:"create_dirs"`
+}
+
+// PoolConfig holds connection pool settings shared by connection-oriented
+// backends. Zero values are replaced by defaults.
+type PoolConfig struct {
+	MaxConns       int           `mapstructure:"max_conns" validate:"min=0"`
+	IdleTimeout    time.Duration `mapstructure:"idle_timeout"`
+	DialTimeout    time.Duration `mapstructure:"dial_timeout"`
+	MaxRetries     int           `mapstructure:"max_retries" validate:"min=0"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+}
+
+// LifecycleConfig holds storage retention and tiering policies
+type LifecycleConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	Interval      time.Duration     `mapstructure:"interval"`
+	ColdBackend   string            `mapstructure:"cold_backend" validate:"omitempty,oneof=s3 minio gcs ftp sftp local"` // "s3", "minio", "gcs", "ftp", "sftp" or "local"
+	LegalHoldTags []string          `mapstructure:"legal_hold_tags"`
+	AuditTopic    string            `mapstructure:"audit_topic"`
+	Policies      []LifecyclePolicy `mapstructure:"policies"`
+}
+
+// LifecyclePolicy describes what happens to files matching a path prefix or tag.
+// Policies are evaluated in order and the first match wins.
+type LifecyclePolicy struct {
+	Name                string   `mapstructure:"name"`
+	Prefix              string   `mapstructure:"prefix"`
+	Tags                []string `mapstructure:"tags"`
+	DeleteAfterDays     int      `mapstructure:"delete_after_days"`
+	TransitionAfterDays int      `mapstructure:"transition_after_days"`
+}
+
+// QuotaConfig holds per-user and per-tenant storage limits
+type QuotaConfig struct {
+	Enabled       bool                  `mapstructure:"enabled"`
+	Store         string                `mapstructure:"store" validate:"omitempty,oneof=memory redis"` // "memory" or "redis"
+	DefaultUser   QuotaLimit            `mapstructure:"default_user"`
+	DefaultTenant QuotaLimit            `mapstructure:"default_tenant"`
+	Users         map[string]QuotaLimit `mapstructure:"users" validate:"dive"`
+	Tenants       map[string]QuotaLimit `mapstructure:"tenants" validate:"dive"`
+}
+
+// QuotaLimit holds byte and file-count limits. Zero means unlimited.
+type QuotaLimit struct {
+	MaxBytes int64 `mapstructure:"max_bytes" validate:"min=0"`
+	MaxFiles int64 `mapstructure:"max_files" validate:"min=0"`
+}
+
+// MessagingConfig selects the messaging backend. When Backend is empty the
+// enable_kafka and enable_nats feature flags decide. Consumer groups of every
+// backend are named after kafka.consumer_group.
+type MessagingConfig struct {
+	Backend     string                `mapstructure:"backend" validate:"omitempty,oneof=kafka nats nats-core memory redis"` // "kafka", "nats", "nats-core", "memory" or "redis"
+	Concurrency ConcurrencyConfig     `mapstructure:"concurrency"`
+	Memory      MemoryMessagingConfig `mapstructure:"memory"`
+	Redis       RedisStreamsConfig    `mapstructure:"redis"`
+	Scheduler   SchedulerConfig       `mapstructure:"scheduler"`
+}
+
+// SchedulerConfig holds settings for scheduled delivery. Messages wait in
+// Store until they are due; the replica holding the leader lock publishes
+// them every PollInterval and renews the lock for LockTTL, which must be
+// longer than PollInterval. Failed publishes are retried after RetryDelay.
+type SchedulerConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Store        string        `mapstructure:"store" validate:"omitempty,oneof=memory redis"` // "memory" or "redis"
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	BatchSize    int           `mapstructure:"batch_size" validate:"min=0"`
+	LockTTL      time.Duration `mapstructure:"lock_ttl" validate:"omitempty,gtfield=PollInterval"`
+	RetryDelay   time.Duration `mapstructure:"retry_delay"`
+}
+
+// ConcurrencyConfig sets how many messages the Kafka and NATS consumers
+// handle in parallel per subscription; on Kafka the limits apply to each
+// assigned partition. Messages sharing a partition key keep their order.
+// MaxInFlight bounds the messages received but not yet done, which on Kafka
+// includes messages waiting for an earlier offset to complete. Topics
+// overrides the defaults per topic.
+type ConcurrencyConfig struct {
+	Workers     int                          `mapstructure:"workers" validate:"min=0"`
+	MaxInFlight int                          `mapstructure:"max_in_flight" validate:"min=0"`
+	Topics      map[string]ConcurrencyConfig `mapstructure:"topics" validate:"dive"`
+}
+
+// MemoryMessagingConfig holds settings for the in-process messaging backend
+type MemoryMessagingConfig struct {
+	Delivery        string        `mapstructure:"delivery" validate:"omitempty,oneof=at-most-once at-least-once"` // "at-most-once" or "at-least-once"
+	BufferSize      int           `mapstructure:"buffer_size" validate:"min=0"`
+	MaxDeliveries   int           `mapstructure:"max_deliveries"`
+	RedeliveryDelay time.Duration `mapstructure:"redelivery_delay"`
+}
+
+// RedisStreamsConfig holds settings for the Redis Streams messaging backend,
+// which connects with the top-level redis settings. Messages left pending
+// for ClaimMinIdle, for example by a crashed replica, are claimed again every
+// ClaimInterval until they were delivered MaxDeliveries times.
+type RedisStreamsConfig struct {
+	Consumer      string        `mapstructure:"consumer"`
+	KeyPrefix     string        `mapstructure:"key_prefix"`
+	MaxLen        int64         `mapstructure:"max_len"`
+	BatchSize     int64         `mapstructure:"batch_size"`
+	Block         time.Duration `mapstructure:"block"`
+	ClaimInterval time.Duration `mapstructure:"claim_interval"`
+	ClaimMinIdle  time.Duration `mapstructure:"claim_min_idle"`
+	MaxDeliveries int64         `mapstructure:"max_deliveries"`
+}
+
+// NATSConfig holds NATS JetStream connection, stream and consumer settings
+type NATSConfig struct {
+	URL           string             `mapstructure:"url" validate:"omitempty,url"`
+	Name          string             `mapstructure:"name"`
+	Username      string             `mapstructure:"username"`
+	Password      string             `mapstructure:"password"`
+	Token         string             `mapstructure:"token"`
+	MaxReconnects int                `mapstructure:"max_reconnects" validate:"min=-1"` // -1 reconnects forever
+	ReconnectWait time.Duration      `mapstructure:"reconnect_wait"`
+	KeySubjects   bool               `mapstructure:"key_subjects"` // publish keyed messages to <topic>.<key>
+	Stream        NATSStreamConfig   `mapstructure:"stream"`
+	Consumer      NATSConsumerConfig `mapstructure:"consumer"`
+}
+
+// NATSStreamConfig describes the JetStream stream that stores all subjects
+// the service publishes to. The stream is created or updated at startup.
+type NATSStreamConfig struct {
+	Name     string        `mapstructure:"name"`
+	Subjects []string      `mapstructure:"subjects"`
+	Storage  string        `mapstructure:"storage" validate:"omitempty,oneof=file memory"` // "file" or "memory"
+	Replicas int           `mapstructure:"replicas" validate:"min=0,max=5"`
+	MaxAge   time.Duration `mapstructure:"max_age"`
+}
+
+// NATSConsumerConfig holds settings for durable pull consumers. Replicas
+// sharing the same durable name split the messages of a subject between them.
+// A failed message is redelivered after the next entry of Backoff, until it
+// has been delivered MaxDeliver times.
+type NATSConsumerConfig struct {
+	Durable       string          `mapstructure:"durable"`
+	AckWait       time.Duration   `mapstructure:"ack_wait"`
+	MaxDeliver    int             `mapstructure:"max_deliver" validate:"min=-1"`
+	MaxAckPending int             `mapstructure:"max_ack_pending"`
+	Backoff       []time.Duration `mapstructure:"backoff"`
+	FetchBatch    int             `mapstructure:"fetch_batch"`
+}
+
 // FeaturesConfig holds feature flags
 type FeaturesConfig struct {
 	EnableRedis bool `mapstructure:"enable_redis"`
 	EnableKafka bool `mapstructure:"enable_kafka"`
+	EnableNATS  bool `mapstructure:"enable_nats"`
 }
 
-// SFTPConfig holds SFTP-specific configuration
-type SFTPConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	BaseDir  string `mapstructure:"base_dir"`
-}
-
-// LoadConfig loads configuration from file and environment variables, singleton style.
-func LoadConfig(configPath string) (*Config, error) {
+// LoadConfig loads the configuration once, singleton style. See load for
+// how settings are layered and Reload for refreshing them.
+func LoadConfig(opts LoadOptions) (*Config, error) {
 	var err error
 
 	once.Do(func() {
-		viper.SetConfigFile(configPath)
-		viper.AutomaticEnv()
-
-		if e := viper.ReadInConfig(); e != nil {
-			err = e
+		var loaded *Config
+		if loaded, err = load(opts); err != nil {
 			return
 		}
-
-		var loaded Config
-		if e := viper.Unmarshal(&loaded); e != nil {
-			err = e
-			return
-		}
-
-		cfg = &loaded
+		loadOpts = opts
+		current.Store(loaded)
 	})
 
-	return cfg, err
+	return current.Load(), err
 }
 
+// GetConfig returns the current configuration. Callers that must see
+// reloaded settings call it again instead of keeping the result.
 func GetConfig() *Config {
-	return cfg
+	return current.Load()
 }
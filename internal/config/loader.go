@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix prefixes the environment variables overriding settings, so
+// APP_REDIS_PASSWORD sets redis.password
+const EnvPrefix = "APP"
+
+// BaseFile is the file in the config directory holding the settings shared
+// by all environments
+const BaseFile = "base.json"
+
+// LoadOptions tell LoadConfig where to find the configuration. Zero values
+// fall back to the defaults below; the --config-dir and --env flags in Args
+// take precedence over Dir and Env.
+type LoadOptions struct {
+	Dir  string   // directory of the base and overlay files, "config" by default
+	Env  string   // overlay loaded from <Dir>/<Env>.json, APP_ENV or "dev" by default
+	Args []string // command-line flags, usually os.Args[1:]
+}
+
+// withDefaults fills in the unset options
+func (o LoadOptions) withDefaults() LoadOptions {
+	if o.Dir == "" {
+		o.Dir = "config"
+	}
+	if o.Env == "" {
+		o.Env = os.Getenv(EnvPrefix + "_ENV")
+	}
+	if o.Env == "" {
+		o.Env = "dev"
+	}
+	return o
+}
+
+// newFlagSet defines the command-line flags read by load
+func newFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.ParseErrorsWhitelist.UnknownFlags = true
+	flags.String("config-dir", "", "directory holding base.json and the environment overlays")
+	flags.String("env", "", "environment overlay to load, overrides APP_ENV")
+	flags.String("port", "", "HTTP port, overrides server.port")
+	flags.String("log-level", "", "log level, overrides server.log_level")
+	flags.StringArray("set", nil, "override a setting, e.g. --set redis.db=2")
+	return flags
+}
+
+// parseOptions applies the --config-dir and --env flags and the defaults to
+// opts and returns the parsed flags
+func parseOptions(opts LoadOptions) (LoadOptions, *pflag.FlagSet, error) {
+	flags := newFlagSet()
+	if err := flags.Parse(opts.Args); err != nil {
+		return opts, nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+	if dir, _ := flags.GetString("config-dir"); dir != "" {
+		opts.Dir = dir
+	}
+	if env, _ := flags.GetString("env"); env != "" {
+		opts.Env = env
+	}
+	return opts.withDefaults(), flags, nil
+}
+
+// files returns the base and overlay file paths
+func (o LoadOptions) files() (base, overlay string) {
+	return filepath.Join(o.Dir, BaseFile), filepath.Join(o.Dir, o.Env+".json")
+}
+
+// load builds the configuration from layers, each overriding the one
+// before: defaults, the optional base file, the environment overlay, APP_
+// environment variables and command-line flags. Secret references are
+// then resolved and the result is validated.
+func load(opts LoadOptions) (*Config, error) {
+	opts, flags, err := parseOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	v := viper.New()
+	setDefaults(v, opts.Env)
+
+	// Files: the base file is optional, the overlay is not
+	base, overlay := opts.files()
+	if err := mergeFile(v, base, true); err != nil {
+		return nil, err
+	}
+	if err := mergeFile(v, overlay, false); err != nil {
+		return nil, err
+	}
+
+	// Environment variables. Keys missing from the files are bound
+	// explicitly, since AutomaticEnv only applies to keys viper knows.
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	bindEnvs(v, reflect.TypeOf(Config{}), "")
+
+	// Flags
+	if err := v.BindPFlag("server.port", flags.Lookup("port")); err != nil {
+		return nil, err
+	}
+	if err := v.BindPFlag("server.log_level", flags.Lookup("log-level")); err != nil {
+		return nil, err
+	}
+	sets, _ := flags.GetStringArray("set")
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", set)
+		}
+		v.Set(key, value)
+	}
+
+	var loaded Config
+	if err := v.Unmarshal(&loaded); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	loaded.Env = opts.Env
+
+	if err := resolveSecrets(&loaded); err != nil {
+		return nil, err
+	}
+	if err := loaded.Validate(); err != nil {
+		return nil, err
+	}
+	return &loaded, nil
+}
+
+// setDefaults sets the values used when no file, variable or flag sets them
+func setDefaults(v *viper.Viper, env string) {
+	v.SetDefault("server.port", "8080")
+	v.SetDefault("server.environment", env)
+	v.SetDefault("server.log_level", "info")
+	v.SetDefault("redis.host", "localhost")
+	v.SetDefault("redis.port", "6379")
+}
+
+// mergeFile merges a config file into v. Missing optional files are skipped.
+func mergeFile(v *viper.Viper, path string, optional bool) error {
+	if _, err := os.Stat(path); optional && errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	v.SetConfigFile(path)
+	if err := v.MergeInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// bindEnvs binds an environment variable to every setting of t, which is a
+// struct with mapstructure tags. Map entries cannot be listed up front and
+// are only overridable when a file sets them.
+func bindEnvs(v *viper.Viper, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := prefix + name
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			bindEnvs(v, field.Type, key+".")
+		case reflect.Map:
+		default:
+			v.BindEnv(key)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes the named files into a temporary config directory
+// and returns it
+func writeConfig(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	// Each layer sets server.port and redis.db; the layers below it also
+	// set a key of their own, which must survive
+	files := map[string]string{
+		BaseFile: `{
+			"server": {"port": "8081", "log_level": "warn"},
+			"redis": {"db": 1, "host": "base-redis"},
+			"upload": {"backend": "local"}
+		}`,
+		"test.json": `{
+			"server": {"port": "8082"},
+			"redis": {"db": 2, "password": "overlay"}
+		}`,
+	}
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		env      map[string]string
+		args     []string
+		wantPort string
+		wantDB   int
+	}{
+		{
+			name:     "defaults",
+			files:    map[string]string{"test.json": `{"upload": {"backend": "local"}}`},
+			wantPort: "8080",
+		},
+		{
+			name:     "base file over defaults",
+			files:    map[string]string{BaseFile: files[BaseFile], "test.json": `{}`},
+			wantPort: "8081",
+			wantDB:   1,
+		},
+		{
+			name:     "environment file over base file",
+			files:    files,
+			wantPort: "8082",
+			wantDB:   2,
+		},
+		{
+			name:     "environment variables over files",
+			files:    files,
+			env:      map[string]string{"APP_SERVER_PORT": "8083", "APP_REDIS_DB": "3"},
+			wantPort: "8083",
+			wantDB:   3,
+		},
+		{
+			name:     "flags over environment variables",
+			files:    files,
+			env:      map[string]string{"APP_SERVER_PORT": "8083", "APP_REDIS_DB": "3"},
+			args:     []string{"--port", "8084", "--set", "redis.db=4"},
+			wantPort: "8084",
+			wantDB:   4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			loaded, err := load(LoadOptions{Dir: writeConfig(t, tt.files), Env: "test", Args: tt.args})
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if loaded.Server.Port != tt.wantPort {
+				t.Errorf("server.port = %q, want %q", loaded.Server.Port, tt.wantPort)
+			}
+			if loaded.Redis.DB != tt.wantDB {
+				t.Errorf("redis.db = %d, want %d", loaded.Redis.DB, tt.wantDB)
+			}
+		})
+	}
+}
+
+func TestLoadKeepsLowerLayers(t *testing.T) {
+	t.Setenv("APP_REDIS_PASSWORD", "from-env")
+
+	dir := writeConfig(t, map[string]string{
+		BaseFile: `{
+			"server": {"log_level": "warn"},
+			"redis": {"host": "base-redis"},
+			"upload": {"backend": "local"}
+		}`,
+		"test.json": `{"redis": {"password": "overlay"}}`,
+	})
+	loaded, err := load(LoadOptions{Dir: dir, Env: "test", Args: []string{"--port", "9000"}})
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	if loaded.Server.LogLevel != "warn" {
+		t.Errorf("server.log_level = %q, want the base file value", loaded.Server.LogLevel)
+	}
+	if loaded.Redis.Host != "base-redis" {
+		t.Errorf("redis.host = %q, want the base file value", loaded.Redis.Host)
+	}
+	if loaded.Redis.Password != "from-env" {
+		t.Errorf("redis.password = %q, want the environment variable", loaded.Redis.Password)
+	}
+	if loaded.Server.Environment != "test" || loaded.Env != "test" {
+		t.Errorf("environment = %q/%q, want test", loaded.Server.Environment, loaded.Env)
+	}
+}
+
+func TestLoadEnvironmentFromFlags(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"dev.json":     `{"upload": {"backend": "local"}}`,
+		"staging.json": `{"server": {"port": "7000"}, "upload": {"backend": "local"}}`,
+	})
+
+	loaded, err := load(LoadOptions{Args: []string{"--config-dir", dir, "--env", "staging"}})
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if loaded.Env != "staging" || loaded.Server.Port != "7000" {
+		t.Errorf("loaded env %q port %q, want the staging overlay", loaded.Env, loaded.Server.Port)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "missing environment file",
+			files:   map[string]string{BaseFile: `{"upload": {"backend": "local"}}`},
+			wantErr: "test.json",
+		},
+		{
+			name:    "malformed file",
+			files:   map[string]string{"test.json": `{"upload": `},
+			wantErr: "failed to read config file",
+		},
+		{
+			name:    "set without value",
+			files:   map[string]string{"test.json": `{"upload": {"backend": "local"}}`},
+			args:    []string{"--set", "redis.db"},
+			wantErr: `invalid --set "redis.db"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(LoadOptions{Dir: writeConfig(t, tt.files), Env: "test", Args: tt.args})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("load() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
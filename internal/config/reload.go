@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ChangeHandler is called after a reload changed a section of the
+// configuration, with the configuration before and after the reload
+type ChangeHandler func(old, new *Config)
+
+var (
+	// loadOpts are the options LoadConfig was called with, reused by Reload
+	loadOpts LoadOptions
+	reloadMu sync.Mutex
+
+	handlers   = make(map[string][]ChangeHandler)
+	handlersMu sync.RWMutex
+)
+
+// OnChange registers a handler called whenever a reload changes section,
+// a top-level key such as "server" or "redis"
+func OnChange(section string, handler ChangeHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	handlers[section] = append(handlers[section], handler)
+}
+
+// Reload loads the configuration again with the options given to
+// LoadConfig. A valid configuration atomically replaces the current one and
+// the handlers of the sections that changed are called; an invalid one is
+// rejected and the current configuration kept. It returns the changed
+// sections.
+func Reload() ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := current.Load()
+	if old == nil {
+		return nil, errors.New("config is not loaded")
+	}
+
+	loaded, err := load(loadOpts)
+	if err != nil {
+		return nil, err
+	}
+	current.Store(loaded)
+
+	changed := changedSections(old, loaded)
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	for _, section := range changed {
+		for _, handler := range handlers[section] {
+			handler(old, loaded)
+		}
+	}
+	return changed, nil
+}
+
+// changedSections returns the top-level keys whose settings differ
+func changedSections(old, new *Config) []string {
+	var changed []string
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := 0; i < oldValue.NumField(); i++ {
+		name, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("mapstructure"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// WatchFiles calls onChange with the path of the base or overlay file
+// whenever it is written or replaced, for example by a Kubernetes
+// ConfigMap update. Files missing when it is called are not watched.
+func WatchFiles(onChange func(path string)) error {
+	opts, _, err := parseOptions(loadOpts)
+	if err != nil {
+		return err
+	}
+
+	base, overlay := opts.files()
+	for _, path := range []string{base, overlay} {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		// Each file gets its own viper instance, which watches one file
+		watcher := viper.New()
+		watcher.SetConfigFile(path)
+		watcher.OnConfigChange(func(fsnotify.Event) {
+			onChange(path)
+		})
+		watcher.WatchConfig()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// loadForReload loads the configuration in dir as LoadConfig would, without
+// its run-once guard, and restores the package state when the test finishes
+func loadForReload(t *testing.T, dir string) *Config {
+	t.Helper()
+
+	prevOpts, prevConfig := loadOpts, current.Load()
+	handlersMu.Lock()
+	prevHandlers := handlers
+	handlers = make(map[string][]ChangeHandler)
+	handlersMu.Unlock()
+	t.Cleanup(func() {
+		loadOpts = prevOpts
+		current.Store(prevConfig)
+		handlersMu.Lock()
+		handlers = prevHandlers
+		handlersMu.Unlock()
+	})
+
+	loadOpts = LoadOptions{Dir: dir, Env: "test"}
+	loaded, err := load(loadOpts)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	current.Store(loaded)
+	return loaded
+}
+
+// rewrite replaces the overlay file in dir
+func rewrite(t *testing.T, dir, overlay string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "test.json"), []byte(overlay), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"test.json": `{"server": {"port": "8081"}, "upload": {"backend": "local"}}`,
+	})
+	before := loadForReload(t, dir)
+
+	var called []string
+	for _, section := range []string{"server", "upload"} {
+		OnChange(section, func(old, new *Config) { called = append(called, section) })
+	}
+
+	for name, overlay := range map[string]string{
+		"invalid":   `{"server": {"port": "8082"}, "upload": {"backend": "dropbox"}}`,
+		"malformed": `{"server": `,
+	} {
+		rewrite(t, dir, overlay)
+		changed, err := Reload()
+		if err == nil {
+			t.Fatalf("Reload() of the %s file succeeded, changed %v", name, changed)
+		}
+		if GetConfig() != before {
+			t.Errorf("Reload() of the %s file replaced the current config", name)
+		}
+	}
+	if GetConfig().Server.Port != "8081" {
+		t.Errorf("server.port = %q after rejected reloads, want 8081", GetConfig().Server.Port)
+	}
+	if len(called) > 0 {
+		t.Errorf("handlers of %v called by rejected reloads", called)
+	}
+}
+
+func TestReloadNotifiesChangedSections(t *testing.T) {
+	dir := writeConfig(t, map[string]string{
+		"test.json": `{"server": {"port": "8081"}, "redis": {"db": 1}, "upload": {"backend": "local"}}`,
+	})
+	before := loadForReload(t, dir)
+
+	type call struct {
+		section  string
+		old, new *Config
+	}
+	var calls []call
+	for _, section := range []string{"server", "redis", "upload"} {
+		OnChange(section, func(old, new *Config) {
+			calls = append(calls, call{section, old, new})
+		})
+	}
+
+	rewrite(t, dir, `{"server": {"port": "8081"}, "redis": {"db": 2}, "upload": {"backend": "local"}}`)
+	changed, err := Reload()
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	after := GetConfig()
+
+	if !slices.Equal(changed, []string{"redis"}) {
+		t.Errorf("Reload() changed %v, want [redis]", changed)
+	}
+	if after == before || after.Redis.DB != 2 {
+		t.Errorf("current config has redis.db %d, want the reloaded 2", after.Redis.DB)
+	}
+	if len(calls) != 1 || calls[0].section != "redis" {
+		t.Fatalf("handlers called for %v, want only redis", calls)
+	}
+	if calls[0].old != before || calls[0].new != after {
+		t.Error("redis handler not called with the configs before and after the reload")
+	}
+
+	// Reloading unchanged files changes nothing and calls no handler
+	calls = nil
+	if changed, err := Reload(); err != nil || len(changed) > 0 || len(calls) > 0 {
+		t.Errorf("second Reload() = %v, %v with %d handler calls, want no changes", changed, err, len(calls))
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// SecretResolver returns the secret a reference points to. ref is the part
+// of the setting after the resolver's prefix.
+type SecretResolver func(ref string) (string, error)
+
+// secretResolvers maps reference prefixes to their resolvers
+var secretResolvers = make(map[string]SecretResolver)
+
+// RegisterSecretResolver makes settings starting with prefix resolve
+// through resolver when the configuration is loaded
+func RegisterSecretResolver(prefix string, resolver SecretResolver) {
+	secretResolvers[prefix] = resolver
+}
+
+func init() {
+	// secret://file/run/secrets/redis_password reads /run/secrets/redis_password
+	RegisterSecretResolver("secret://file", readSecretFile)
+	// env://REDIS_PASSWORD reads the REDIS_PASSWORD environment variable
+	RegisterSecretResolver("env://", lookupSecretEnv)
+}
+
+// readSecretFile reads a secret from a file such as a mounted Docker or
+// Kubernetes secret, without the trailing newline
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// lookupSecretEnv reads a secret from an environment variable
+func lookupSecretEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveSecrets replaces every string setting holding a secret reference
+// with the secret and records the setting in c.secrets. All failing
+// references are reported together.
+func resolveSecrets(c *Config) error {
+	var errs []error
+	c.secrets = make(map[string]bool)
+	resolveValue(reflect.ValueOf(c).Elem(), "", c.secrets, &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to resolve secrets: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// resolveValue resolves the secret references in v, the setting at key,
+// and marks the settings it resolved in resolved
+func resolveValue(v reflect.Value, key string, resolved map[string]bool, errs *[]error) {
+	switch v.Kind() {
+	case reflect.String:
+		for prefix, resolver := range secretResolvers {
+			ref, ok := strings.CutPrefix(v.String(), prefix)
+			if !ok {
+				continue
+			}
+			secret, err := resolver(ref)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: %w", key, err))
+				return
+			}
+			v.SetString(secret)
+			resolved[key] = true
+			return
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("mapstructure"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			resolveValue(v.Field(i), joinKey(key, name), resolved, errs)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			resolveValue(v.Index(i), fmt.Sprintf("%s[%d]", key, i), resolved, errs)
+		}
+	case reflect.Map:
+		// Map values cannot be set in place, so each is resolved in a copy
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			resolveValue(value, joinKey(key, fmt.Sprint(iter.Key())), resolved, errs)
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+}
+
+// joinKey appends name to a dotted setting key
+func joinKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
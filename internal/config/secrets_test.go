@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadResolvesSecrets(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "redis_password")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_KAFKA_PASSWORD", "from-env")
+	// References set through environment variables resolve too
+	t.Setenv("APP_UPLOAD_FTP_PASSWORD", "env://TEST_FTP_PASSWORD")
+	t.Setenv("TEST_FTP_PASSWORD", "ftp")
+
+	dir := writeConfig(t, map[string]string{
+		"test.json": `{
+			"redis": {"password": "secret://file` + secretFile + `"},
+			"kafka": {"security": {"password": "env://TEST_KAFKA_PASSWORD"}},
+			"upload": {"backend": "local"}
+		}`,
+	})
+	loaded, err := load(LoadOptions{Dir: dir, Env: "test"})
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	if loaded.Redis.Password != "from-file" {
+		t.Errorf("redis.password = %q, want the file content without the newline", loaded.Redis.Password)
+	}
+	if loaded.Kafka.Security.Password != "from-env" {
+		t.Errorf("kafka.security.password = %q, want the environment variable", loaded.Kafka.Security.Password)
+	}
+	if loaded.Upload.FTPConfig.Password != "ftp" {
+		t.Errorf("upload.ftp.password = %q, want the environment variable", loaded.Upload.FTPConfig.Password)
+	}
+}
+
+func TestLoadReportsEveryUnresolvedSecret(t *testing.T) {
+	missingFile := filepath.Join(t.TempDir(), "missing")
+	dir := writeConfig(t, map[string]string{
+		"test.json": `{
+			"redis": {"password": "secret://file` + missingFile + `"},
+			"kafka": {"security": {"password": "env://TEST_UNSET_PASSWORD"}},
+			"upload": {"backend": "local"}
+		}`,
+	})
+
+	_, err := load(LoadOptions{Dir: dir, Env: "test"})
+	if err == nil {
+		t.Fatal("load() succeeded with unresolvable secrets")
+	}
+	for _, want := range []string{"failed to resolve secrets", "redis.password", "kafka.security.password", "TEST_UNSET_PASSWORD"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("load() error = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestRegisterSecretResolver(t *testing.T) {
+	RegisterSecretResolver("test://", func(ref string) (string, error) {
+		return strings.ToUpper(ref), nil
+	})
+	t.Cleanup(func() { delete(secretResolvers, "test://") })
+
+	dir := writeConfig(t, map[string]string{
+		"test.json": `{"redis": {"password": "test://vault"}, "upload": {"backend": "local"}}`,
+	})
+	loaded, err := load(LoadOptions{Dir: dir, Env: "test"})
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if loaded.Redis.Password != "VAULT" {
+		t.Errorf("redis.password = %q, want the resolved reference", loaded.Redis.Password)
+	}
+}
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validate checks the validate tags of the config structs and names fields
+// after their mapstructure keys
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		return name
+	})
+	return v
+}
+
+// ValidationError lists every invalid setting found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration and reports all invalid settings at
+// once as a *ValidationError
+func (c *Config) Validate() error {
+	var problems []string
+
+	// Field rules from the validate tags
+	if err := validate.Struct(c); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return fmt.Errorf("failed to validate config: %w", err)
+		}
+		for _, fieldErr := range fieldErrs {
+			problems = append(problems, describeFieldError(fieldErr))
+		}
+	}
+
+	// Settings required by the selected backends
+	problems = append(problems, c.dependencyProblems()...)
+
+	// Credentials that must not be written into the config files
+	if c.Server.AdminToken != "" && c.Server.Environment != "dev" && !c.secrets["server.admin_token"] {
+		problems = append(problems, "server.admin_token: must be a secret reference such as env://ADMIN_TOKEN outside dev")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// describeFieldError turns a failed tag rule into a message keyed by the
+// setting, such as "server.port: is required"
+func describeFieldError(fieldErr validator.FieldError) string {
+	// Namespaces start with the Config type name
+	_, key, _ := strings.Cut(fieldErr.Namespace(), ".")
+
+	var problem string
+	switch fieldErr.Tag() {
+	case "required", "required_if":
+		problem = "is required"
+	case "oneof":
+		problem = fmt.Sprintf("must be one of %s, got %q", strings.ReplaceAll(fieldErr.Param(), " ", ", "), fieldErr.Value())
+	case "numeric":
+		problem = fmt.Sprintf("must be a number, got %q", fieldErr.Value())
+	case "min":
+		problem = "must be at least " + fieldErr.Param()
+	case "max":
+		problem = "must be at most " + fieldErr.Param()
+	case "gtfield":
+		problem = "must be greater than " + snakeCase(fieldErr.Param())
+	case "url":
+		problem = fmt.Sprintf("must be a URL, got %q", fieldErr.Value())
+	case "hostname_port":
+		problem = fmt.Sprintf("must be host:port, got %q", fieldErr.Value())
+	default:
+		problem = "fails the " + fieldErr.Tag() + " rule"
+	}
+	return key + ": " + problem
+}
+
+// dependencyProblems reports settings that are only required because of
+// other settings, such as the brokers of the selected messaging backend
+func (c *Config) dependencyProblems() []string {
+	var problems []string
+	require := func(set bool, key, reason string) {
+		if !set {
+			problems = append(problems, key+": is required by "+reason)
+		}
+	}
+
+	// Messaging backend, picked as in boot when none is set
+	backend := c.Messaging.Backend
+	if backend == "" {
+		switch {
+		case c.Features.EnableKafka:
+			backend = "kafka"
+		case c.Features.EnableNATS:
+			backend = "nats"
+		}
+	}
+	switch backend {
+	case "kafka":
+		require(len(c.Kafka.Brokers) > 0, "kafka.brokers", "the kafka messaging backend")
+	case "nats", "nats-core":
+		require(c.NATS.URL != "", "nats.url", "the "+backend+" messaging backend")
+	}
+
+	// Stores kept in Redis
+	redisUsers := []struct {
+		used   bool
+		reason string
+	}{
+		{backend == "redis", "the redis messaging backend"},
+		{c.Kafka.Dedup.Enabled && c.Kafka.Dedup.Store == "redis", "kafka.dedup.store"},
+		{c.Upload.Quota.Enabled && c.Upload.Quota.Store == "redis", "upload.quota.store"},
+		{c.Messaging.Scheduler.Enabled && c.Messaging.Scheduler.Store == "redis", "messaging.scheduler.store"},
+	}
+	for _, user := range redisUsers {
+		if user.used {
+			require(c.Redis.Host != "", "redis.host", user.reason)
+		}
+	}
+
+	// Upload backends
+	uploadBackends := []string{c.Upload.Backend}
+	if cold := c.Upload.Lifecycle.ColdBackend; cold != c.Upload.Backend {
+		uploadBackends = append(uploadBackends, cold)
+	}
+	for _, uploadBackend := range uploadBackends {
+		switch uploadBackend {
+		case "s3":
+			require(c.Upload.S3Config.Bucket != "", "upload.s3.bucket", "the s3 upload backend")
+			require(c.Upload.S3Config.Region != "", "upload.s3.region", "the s3 upload backend")
+		case "minio":
+			require(c.Upload.MinioConfig.Endpoint != "", "upload.minio.endpoint", "the minio upload backend")
+			require(c.Upload.MinioConfig.Bucket != "", "upload.minio.bucket", "the minio upload backend")
+		case "gcs":
+			require(c.Upload.GCSConfig.Bucket != "", "upload.gcs.bucket", "the gcs upload backend")
+		}
+	}
+
+	// Log sinks
+	for i, sink := range c.Logging.Sinks {
+		key := fmt.Sprintf("logging.sinks[%d]", i)
+		switch sink.Type {
+		case "file":
+			require(sink.File.Path != "", key+".file.path", "file sinks")
+		case "syslog":
+			require(sink.Syslog.Network == "" || sink.Syslog.Address != "", key+".syslog.address", "syslog sinks with a network")
+		case "http":
+			require(sink.Remote.URL != "", key+".remote.url", "http sinks")
+		case "tcp":
+			require(sink.Remote.Address != "", key+".remote.address", "tcp sinks")
+		}
+	}
+	return problems
+}
+
+// snakeCase converts a Go field name such as PollInterval to its
+// mapstructure key poll_interval
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		overlay      string
+		args         []string
+		wantProblems []string
+	}{
+		{
+			name:    "unknown backend",
+			overlay: `{"upload": {"backend": "dropbox"}}`,
+			wantProblems: []string{
+				`upload.backend: must be one of s3, minio, gcs, ftp, sftp, local, got "dropbox"`,
+			},
+		},
+		{
+			name:    "empty port",
+			overlay: `{"upload": {"backend": "local"}}`,
+			args:    []string{"--set", "server.port="},
+			wantProblems: []string{
+				"server.port: is required",
+			},
+		},
+		{
+			name:    "settings required by the backend",
+			overlay: `{"upload": {"backend": "s3"}}`,
+			wantProblems: []string{
+				"upload.s3.bucket: is required by the s3 upload backend",
+				"upload.s3.region: is required by the s3 upload backend",
+			},
+		},
+		{
+			name:    "literal admin token",
+			overlay: `{"server": {"admin_token": "hunter2"}, "upload": {"backend": "local"}}`,
+			wantProblems: []string{
+				"server.admin_token: must be a secret reference such as env://ADMIN_TOKEN outside dev",
+			},
+		},
+		{
+			name:    "every problem at once",
+			overlay: `{"server": {"port": "http"}, "upload": {"backend": "minio"}}`,
+			wantProblems: []string{
+				`server.port: must be a number, got "http"`,
+				"upload.minio.endpoint: is required by the minio upload backend",
+				"upload.minio.bucket: is required by the minio upload backend",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfig(t, map[string]string{"test.json": tt.overlay})
+			_, err := load(LoadOptions{Dir: dir, Env: "test", Args: tt.args})
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("load() error = %v, want a *ValidationError", err)
+			}
+			if !slices.Equal(validationErr.Problems, tt.wantProblems) {
+				t.Errorf("problems = %q, want %q", validationErr.Problems, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestLoadAcceptsAdminTokens(t *testing.T) {
+	t.Setenv("TEST_ADMIN_TOKEN", "from-env")
+
+	tests := []struct {
+		name  string
+		env   string
+		token string
+		want  string
+	}{
+		{"reference", "test", "env://TEST_ADMIN_TOKEN", "from-env"},
+		{"literal in dev", "dev", "dev-admin-token", "dev-admin-token"},
+		{"none", "test", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfig(t, map[string]string{
+				tt.env + ".json": `{"server": {"admin_token": "` + tt.token + `"}, "upload": {"backend": "local"}}`,
+			})
+			loaded, err := load(LoadOptions{Dir: dir, Env: tt.env})
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if loaded.Server.AdminToken != tt.want {
+				t.Errorf("server.admin_token = %q, want %q", loaded.Server.AdminToken, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"go-microservice/internal/service"
+)
+
+// DeadLetterController handles dead-letter inspection and replay HTTP requests
+type DeadLetterController struct {
+	logger  *zap.Logger
+	service *service.DeadLetterService
+}
+
+// NewDeadLetterController creates a new dead-letter controller
+func NewDeadLetterController(logger *zap.Logger, svc *service.DeadLetterService) *DeadLetterController {
+	return &DeadLetterController{
+		logger:  logger,
+		service: svc,
+	}
+}
+
+// ListDeadLetters handles GET /admin/messaging/dlq request. The optional
+// topic query parameter filters by original topic.
+func (c *DeadLetterController) ListDeadLetters(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	letters, err := c.service.List(ctx.Request.Context(), ctx.Query("topic"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+		"count":        len(letters),
+	})
+}
+
+// GetDeadLetter handles GET /admin/messaging/dlq/:id request
+func (c *DeadLetterController) GetDeadLetter(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	letter, err := c.service.Get(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, letter)
+}
+
+// ReplayDeadLetter handles POST /admin/messaging/dlq/:id/replay request
+func (c *DeadLetterController) ReplayDeadLetter(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	letter, err := c.service.Replay(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "replayed",
+		"id":     letter.ID,
+		"topic":  letter.OriginalTopic,
+	})
+}
+
+// DiscardDeadLetter handles DELETE /admin/messaging/dlq/:id request
+func (c *DeadLetterController) DiscardDeadLetter(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	if err := c.service.Discard(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// enabled fails the request when messaging is not configured
+func (c *DeadLetterController) enabled(ctx *gin.Context) bool {
+	if c.service == nil {
+		ctx.Error(errMessagingDisabled)
+		return false
+	}
+	return true
+}
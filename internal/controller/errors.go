@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/messaging"
+)
+
+// Errors of requests the controllers reject themselves
+var (
+	errMessagingDisabled = apperror.New(apperror.CodeUnavailable, "messaging is not enabled")
+	errQuotasDisabled    = apperror.New(apperror.CodeUnavailable, "storage quotas are not enabled")
+	errUserIDRequired    = apperror.New(apperror.CodeValidation, "user ID is required")
+	errFileIDRequired    = apperror.New(apperror.CodeValidation, "file ID is required")
+)
+
+func init() {
+	// Messaging errors surfaced by the messaging endpoints
+	apperror.Register(messaging.ErrSchemaValidation, apperror.CodeValidation)
+	apperror.Register(messaging.ErrSchemaNotFound, apperror.CodeValidation)
+	apperror.Register(messaging.ErrInvalidOffsetReset, apperror.CodeValidation)
+	apperror.Register(messaging.ErrSubscriptionNotFound, apperror.CodeNotFound)
+	apperror.Register(messaging.ErrTopicNotFound, apperror.CodeNotFound)
+	apperror.Register(messaging.ErrScheduledNotFound, apperror.CodeNotFound)
+	apperror.Register(messaging.ErrGroupActive, apperror.CodeConflict)
+	apperror.Register(messaging.ErrAdminNotSupported, apperror.CodeNotImplemented)
+	apperror.Register(messaging.ErrNotConnected, apperror.CodeUnavailable)
+}
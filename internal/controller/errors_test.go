@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-microservice/internal/middleware"
+	"go-microservice/internal/response"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/messaging"
+)
+
+func TestMessagingErrorsMapToProblems(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   apperror.Code
+		wantDetail string
+	}{
+		{messaging.ErrSchemaValidation, http.StatusBadRequest, apperror.CodeValidation, "orders: " + messaging.ErrSchemaValidation.Error()},
+		{messaging.ErrSchemaNotFound, http.StatusBadRequest, apperror.CodeValidation, "orders: " + messaging.ErrSchemaNotFound.Error()},
+		{messaging.ErrInvalidOffsetReset, http.StatusBadRequest, apperror.CodeValidation, "orders: " + messaging.ErrInvalidOffsetReset.Error()},
+		{messaging.ErrSubscriptionNotFound, http.StatusNotFound, apperror.CodeNotFound, "orders: " + messaging.ErrSubscriptionNotFound.Error()},
+		{messaging.ErrTopicNotFound, http.StatusNotFound, apperror.CodeNotFound, "orders: " + messaging.ErrTopicNotFound.Error()},
+		{messaging.ErrScheduledNotFound, http.StatusNotFound, apperror.CodeNotFound, "orders: " + messaging.ErrScheduledNotFound.Error()},
+		{messaging.ErrGroupActive, http.StatusConflict, apperror.CodeConflict, "orders: " + messaging.ErrGroupActive.Error()},
+		// Server errors hide the wrapping context
+		{messaging.ErrAdminNotSupported, http.StatusNotImplemented, apperror.CodeNotImplemented, messaging.ErrAdminNotSupported.Error()},
+		{messaging.ErrNotConnected, http.StatusServiceUnavailable, apperror.CodeUnavailable, messaging.ErrNotConnected.Error()},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			router := gin.New()
+			router.Use(middleware.ErrorHandler())
+			router.GET("/", func(c *gin.Context) { c.Error(fmt.Errorf("orders: %w", tt.err)) })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var problem response.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("body %s is not a problem: %v", w.Body, err)
+			}
+			if w.Code != tt.wantStatus || problem.Status != tt.wantStatus || problem.Code != tt.wantCode {
+				t.Errorf("got %d with %d %s, want %d %s", w.Code, problem.Status, problem.Code, tt.wantStatus, tt.wantCode)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
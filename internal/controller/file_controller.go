@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 
 	"go-microservice/internal/models"
 	"go-microservice/internal/service"
+	"go-microservice/pkg/apperror"
 )
 
 // FileController handles file-related HTTP requests
@@ -29,40 +31,32 @@ func NewFileController(logger *zap.Logger, service service.Service) *FileControl
 func (c *FileController) UploadFile(ctx *gin.Context) {
 	file, err := ctx.FormFile("file")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.Wrap(err, apperror.CodeValidation, "file is required"))
 		return
 	}
 
 	// Open the uploaded file
 	src, err := file.Open()
 	if err != nil {
-		c.logger.Error("Failed to open uploaded file", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process file"})
+		ctx.Error(fmt.Errorf("failed to open uploaded file: %w", err))
 		return
 	}
 	defer src.Close()
 
-	// Read file content
-	content := make([]byte, file.Size)
-	if _, err := src.Read(content); err != nil {
-		c.logger.Error("Failed to read file content", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
-		return
-	}
-
 	// Create file model
 	fileModel := &models.File{
 		Name:        file.Filename,
 		Size:        file.Size,
 		ContentType: file.Header.Get("Content-Type"),
+		UserID:      ctx.PostForm("user_id"),
+		TenantID:    ctx.PostForm("tenant_id"),
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
 	// Upload file using service
-	if err := c.service.UploadFile(ctx, fileModel); err != nil {
-		c.logger.Error("Failed to upload file", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
+	if err := c.service.UploadFile(ctx.Request.Context(), fileModel, src); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -76,14 +70,13 @@ func (c *FileController) UploadFile(ctx *gin.Context) {
 func (c *FileController) DownloadFile(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if id == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "File ID is required"})
+		ctx.Error(errFileIDRequired)
 		return
 	}
 
-	file, err := c.service.DownloadFile(ctx, id)
+	file, err := c.service.DownloadFile(ctx.Request.Context(), id)
 	if err != nil {
-		c.logger.Error("Failed to download file", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download file"})
+		ctx.Error(err)
 		return
 	}
 
@@ -94,13 +87,12 @@ func (c *FileController) DownloadFile(ctx *gin.Context) {
 func (c *FileController) DeleteFile(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if id == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "File ID is required"})
+		ctx.Error(errFileIDRequired)
 		return
 	}
 
-	if err := c.service.DeleteFile(ctx, id); err != nil {
-		c.logger.Error("Failed to delete file", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
+	if err := c.service.DeleteFile(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"go-microservice/internal/service"
+	"go-microservice/pkg/apperror"
+)
+
+// LifecycleController handles storage lifecycle HTTP requests
+type LifecycleController struct {
+	logger  *zap.Logger
+	service *service.LifecycleService
+}
+
+// NewLifecycleController creates a new lifecycle controller
+func NewLifecycleController(logger *zap.Logger, svc *service.LifecycleService) *LifecycleController {
+	return &LifecycleController{
+		logger:  logger,
+		service: svc,
+	}
+}
+
+// GetReport handles GET /storage/lifecycle/report request. It evaluates all
+// policies in dry-run mode and returns the actions that would be taken.
+func (c *LifecycleController) GetReport(ctx *gin.Context) {
+	if c.service == nil {
+		ctx.Error(apperror.New(apperror.CodeUnavailable, "storage lifecycle is not enabled"))
+		return
+	}
+
+	report, err := c.service.Run(ctx.Request.Context(), true)
+	if err != nil {
+		ctx.Error(fmt.Errorf("failed to build lifecycle report: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
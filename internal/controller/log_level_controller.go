@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/logger"
+)
+
+// LogLevelController handles runtime log level HTTP requests
+type LogLevelController struct {
+	logger *zap.Logger
+}
+
+// NewLogLevelController creates a new log level controller
+func NewLogLevelController(logger *zap.Logger) *LogLevelController {
+	return &LogLevelController{
+		logger: logger,
+	}
+}
+
+// setLevelRequest is the body of log level changes. A TTL such as "15m"
+// reverts the change after that time.
+type setLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+	TTL   string `json:"ttl"`
+}
+
+// GetLevels handles GET /admin/log/levels request
+func (c *LogLevelController) GetLevels(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, logger.GetLevels())
+}
+
+// SetLevel handles PUT /admin/log/levels request, which sets the global level
+func (c *LogLevelController) SetLevel(ctx *gin.Context) {
+	c.setLevel(ctx, "")
+}
+
+// SetComponentLevel handles PUT /admin/log/levels/:component request
+func (c *LogLevelController) SetComponentLevel(ctx *gin.Context) {
+	c.setLevel(ctx, ctx.Param("component"))
+}
+
+// ResetComponentLevel handles DELETE /admin/log/levels/:component request.
+// The component logs at the global level again.
+func (c *LogLevelController) ResetComponentLevel(ctx *gin.Context) {
+	component := ctx.Param("component")
+	if err := logger.ResetComponentLevel(component); err != nil {
+		ctx.Error(apperror.New(apperror.CodeValidation, err.Error()))
+		return
+	}
+
+	logger.FromContext(ctx.Request.Context()).Warn("Component log level reset",
+		zap.String("component", component),
+		zap.String("client_ip", ctx.ClientIP()))
+	ctx.JSON(http.StatusOK, logger.GetLevels())
+}
+
+// setLevel sets the level of component, or the global level when it is empty
+func (c *LogLevelController) setLevel(ctx *gin.Context, component string) {
+	var req setLevelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperror.Invalid(err))
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+			ctx.Error(apperror.New(apperror.CodeValidation, "invalid ttl"))
+			return
+		}
+	}
+
+	if err := logger.SetComponentLevel(component, req.Level, ttl); err != nil {
+		ctx.Error(apperror.New(apperror.CodeValidation, err.Error()))
+		return
+	}
+
+	// Logged at warn so the change shows up at every level but error
+	logger.FromContext(ctx.Request.Context()).Warn("Log level changed",
+		zap.String("component", component),
+		zap.String("level", req.Level),
+		zap.Duration("ttl", ttl),
+		zap.String("client_ip", ctx.ClientIP()))
+	ctx.JSON(http.StatusOK, logger.GetLevels())
+}
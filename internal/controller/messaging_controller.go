@@ -2,9 +2,15 @@ package controller
 
 import (
 	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go-microservice/internal/service"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/logger"
+	"go-microservice/pkg/messaging"
 	"go.uber.org/zap"
 )
 
@@ -22,47 +28,326 @@ func NewMessagingController(service *service.MessagingService, logger *zap.Logge
 	}
 }
 
-// PublishMessage handles HTTP requests to publish messages
+// PublishMessage handles POST /messages request. Messages with deliver_at
+// or a delay such as "15m" are scheduled instead of published right away.
 func (c *MessagingController) PublishMessage(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
 	var req struct {
-		Topic   string          `json:"topic" binding:"required"`
-		Payload json.RawMessage `json:"payload" binding:"required"`
+		Topic     string          `json:"topic" binding:"required"`
+		Key       string          `json:"key"`
+		Type      string          `json:"type"`
+		Payload   json.RawMessage `json:"payload" binding:"required"`
+		DeliverAt *time.Time      `json:"deliver_at"`
+		Delay     string          `json:"delay"`
 	}
 
 	// Bind and validate request
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		c.logger.Error("Failed to bind request",
-			zap.Error(err))
-		ctx.JSON(400, gin.H{
-			"error": "Invalid request body",
-		})
+		ctx.Error(apperror.Invalid(err))
+		return
+	}
+
+	// Event type defaults to the topic name
+	if req.Type == "" {
+		req.Type = req.Topic
+	}
+
+	event := service.OutgoingMessage{
+		Topic:   req.Topic,
+		Key:     req.Key,
+		Type:    req.Type,
+		Payload: req.Payload,
+	}
+	if req.DeliverAt != nil || req.Delay != "" {
+		c.scheduleMessage(ctx, event, req.DeliverAt, req.Delay)
 		return
 	}
 
 	// Publish message
-	if err := c.service.PublishMessage(ctx.Request.Context(), req.Topic, req.Payload); err != nil {
-		c.logger.Error("Failed to publish message",
-			zap.Error(err),
-			zap.String("topic", req.Topic))
-		ctx.JSON(500, gin.H{
-			"error": "Failed to publish message",
-		})
+	msg, err := c.service.PublishMessage(ctx.Request.Context(), event)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	// Return success response
 	ctx.JSON(200, gin.H{
-		"status":  "success",
-		"message": "Message published successfully",
+		"status":    "success",
+		"message":   "Message published successfully",
+		"key":       msg.Key,
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+	})
+}
+
+// scheduleMessage schedules a message from POST /messages for delivery at
+// deliverAt or after delay
+func (c *MessagingController) scheduleMessage(ctx *gin.Context, event service.OutgoingMessage, deliverAt *time.Time, delay string) {
+	var at time.Time
+	switch {
+	case deliverAt != nil && delay != "":
+		ctx.Error(apperror.New(apperror.CodeValidation, "deliver_at and delay cannot be combined"))
+		return
+	case deliverAt != nil:
+		at = *deliverAt
+	default:
+		d, err := time.ParseDuration(delay)
+		if err != nil || d < 0 {
+			ctx.Error(apperror.New(apperror.CodeValidation, "invalid delay"))
+			return
+		}
+		at = time.Now().Add(d)
+	}
+
+	scheduled, err := c.service.ScheduleMessage(ctx.Request.Context(), event, at)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"status":     "scheduled",
+		"id":         scheduled.ID,
+		"deliver_at": scheduled.DeliverAt,
 	})
 }
 
-// GetTopics handles HTTP requests to get available topics
+// PublishBatch handles POST /messages/batch request. It publishes up to 500
+// messages and reports the result of each.
+func (c *MessagingController) PublishBatch(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	var req struct {
+		Messages []struct {
+			Topic   string          `json:"topic" binding:"required"`
+			Key     string          `json:"key"`
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload" binding:"required"`
+		} `json:"messages" binding:"required,min=1,max=500,dive"`
+	}
+
+	// Bind and validate request
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperror.Invalid(err))
+		return
+	}
+
+	events := make([]service.OutgoingMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		// Event type defaults to the topic name
+		if msg.Type == "" {
+			msg.Type = msg.Topic
+		}
+		events[i] = service.OutgoingMessage{Topic: msg.Topic, Key: msg.Key, Type: msg.Type, Payload: msg.Payload}
+	}
+
+	msgs, errs := c.service.PublishBatch(ctx.Request.Context(), events)
+
+	results := make([]gin.H, len(errs))
+	failed := 0
+	for i, err := range errs {
+		results[i] = gin.H{"index": i, "topic": events[i].Topic, "status": "published"}
+		if err == nil {
+			results[i]["partition"] = msgs[i].Partition
+			results[i]["offset"] = msgs[i].Offset
+			continue
+		}
+
+		// Each failure is reported like a problem of its own
+		failed++
+		appErr := apperror.From(err)
+		results[i]["status"] = "failed"
+		results[i]["error"] = appErr.Message
+		results[i]["code"] = appErr.Code
+		if appErr.Code.Status() >= http.StatusInternalServerError {
+			logger.FromContext(ctx.Request.Context()).Error("Failed to publish message",
+				zap.Error(err),
+				zap.String("topic", events[i].Topic))
+		}
+	}
+
+	// Partial failures are reported per message
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	ctx.JSON(status, gin.H{
+		"published": len(errs) - failed,
+		"failed":    failed,
+		"results":   results,
+	})
+}
+
+// GetTopics handles GET /admin/messaging/topics request. It lists the
+// consumed topics with their partition counts.
 func (c *MessagingController) GetTopics(ctx *gin.Context) {
-	// In a real implementation, you might want to fetch this from configuration or Kafka
-	topics := []string{"topic1", "topic2", "topic3"}
+	if !c.enabled(ctx) {
+		return
+	}
 
-	ctx.JSON(200, gin.H{
+	topics, err := c.service.DescribeTopics(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
 		"topics": topics,
+		"count":  len(topics),
+	})
+}
+
+// GetLag handles GET /admin/messaging/lag request. The optional group query
+// parameter selects another consumer group than the service's own.
+func (c *MessagingController) GetLag(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	lag, err := c.service.Lag(ctx.Request.Context(), ctx.Query("group"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, lag)
+}
+
+// GetSubscriptions handles GET /admin/messaging/subscriptions request
+func (c *MessagingController) GetSubscriptions(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	subscriptions, err := c.service.Subscriptions()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"subscriptions": subscriptions,
+		"count":         len(subscriptions),
 	})
-} 
\ No newline at end of file
+}
+
+// PauseSubscription handles POST /admin/messaging/subscriptions/:topic/pause
+// request. Only this instance stops consuming the topic.
+func (c *MessagingController) PauseSubscription(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	topic := ctx.Param("topic")
+	if err := c.service.PauseTopic(topic); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "paused",
+		"topic":  topic,
+	})
+}
+
+// ResumeSubscription handles POST /admin/messaging/subscriptions/:topic/resume request
+func (c *MessagingController) ResumeSubscription(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	topic := ctx.Param("topic")
+	if err := c.service.ResumeTopic(topic); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "resumed",
+		"topic":  topic,
+	})
+}
+
+// ResetOffsets handles POST /admin/messaging/offsets/reset request. The
+// group and topics default to the service's group and consumed topics.
+func (c *MessagingController) ResetOffsets(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	var req struct {
+		Group  string   `json:"group"`
+		Topics []string `json:"topics"`
+		messaging.OffsetReset
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(apperror.Invalid(err))
+		return
+	}
+
+	if err := c.service.ResetOffsets(ctx.Request.Context(), req.Group, req.Topics, req.OffsetReset); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "reset",
+		"to":     req.To,
+	})
+}
+
+// GetScheduledMessages handles GET /admin/messaging/scheduled request. The
+// optional limit query parameter bounds the messages listed.
+func (c *MessagingController) GetScheduledMessages(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		ctx.Error(apperror.New(apperror.CodeValidation, "invalid limit"))
+		return
+	}
+
+	scheduled, err := c.service.ScheduledMessages(ctx.Request.Context(), limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// GetScheduledMessage handles GET /admin/messaging/scheduled/:id request
+func (c *MessagingController) GetScheduledMessage(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	scheduled, err := c.service.ScheduledMessage(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// CancelScheduledMessage handles DELETE /admin/messaging/scheduled/:id request
+func (c *MessagingController) CancelScheduledMessage(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	if err := c.service.CancelScheduled(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// enabled fails the request when messaging is not configured
+func (c *MessagingController) enabled(ctx *gin.Context) bool {
+	if c.service == nil {
+		ctx.Error(errMessagingDisabled)
+		return false
+	}
+	return true
+}
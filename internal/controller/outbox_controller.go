@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"go-microservice/internal/service"
+)
+
+// OutboxController handles transactional outbox HTTP requests
+type OutboxController struct {
+	logger  *zap.Logger
+	service *service.OutboxRelay
+}
+
+// NewOutboxController creates a new outbox controller
+func NewOutboxController(logger *zap.Logger, svc *service.OutboxRelay) *OutboxController {
+	return &OutboxController{
+		logger:  logger,
+		service: svc,
+	}
+}
+
+// GetStats handles GET /admin/messaging/outbox request. It reports the
+// outbox backlog, relay lag and publish counters.
+func (c *OutboxController) GetStats(ctx *gin.Context) {
+	if c.service == nil {
+		ctx.Error(errMessagingDisabled)
+		return
+	}
+
+	stats, err := c.service.Stats(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"go-microservice/internal/models"
+	"go-microservice/internal/service"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/logger"
+)
+
+// QuotaController handles storage quota administration HTTP requests
+type QuotaController struct {
+	logger  *zap.Logger
+	service *service.QuotaService
+}
+
+// NewQuotaController creates a new quota controller
+func NewQuotaController(logger *zap.Logger, svc *service.QuotaService) *QuotaController {
+	return &QuotaController{
+		logger:  logger,
+		service: svc,
+	}
+}
+
+// GetUserQuota handles GET /admin/quotas/users/:id request
+func (c *QuotaController) GetUserQuota(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+	ctx.JSON(http.StatusOK, c.service.UserQuota(ctx.Param("id")))
+}
+
+// SetUserQuota handles PUT /admin/quotas/users/:id request
+func (c *QuotaController) SetUserQuota(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	var quota models.StorageQuota
+	if err := ctx.ShouldBindJSON(&quota); err != nil {
+		ctx.Error(apperror.Invalid(err))
+		return
+	}
+
+	id := ctx.Param("id")
+	c.service.SetUserQuota(id, quota)
+	logger.FromContext(ctx.Request.Context()).Info("User storage quota updated",
+		zap.String("user_id", id),
+		zap.Int64("max_bytes", quota.MaxBytes),
+		zap.Int64("max_files", quota.MaxFiles))
+
+	ctx.JSON(http.StatusOK, quota)
+}
+
+// GetTenantQuota handles GET /admin/quotas/tenants/:id request
+func (c *QuotaController) GetTenantQuota(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+	ctx.JSON(http.StatusOK, c.service.TenantQuota(ctx.Param("id")))
+}
+
+// SetTenantQuota handles PUT /admin/quotas/tenants/:id request
+func (c *QuotaController) SetTenantQuota(ctx *gin.Context) {
+	if !c.enabled(ctx) {
+		return
+	}
+
+	var quota models.StorageQuota
+	if err := ctx.ShouldBindJSON(&quota); err != nil {
+		ctx.Error(apperror.Invalid(err))
+		return
+	}
+
+	id := ctx.Param("id")
+	c.service.SetTenantQuota(id, quota)
+	logger.FromContext(ctx.Request.Context()).Info("Tenant storage quota updated",
+		zap.String("tenant_id", id),
+		zap.Int64("max_bytes", quota.MaxBytes),
+		zap.Int64("max_files", quota.MaxFiles))
+
+	ctx.JSON(http.StatusOK, quota)
+}
+
+// enabled fails the request when quotas are not configured
+func (c *QuotaController) enabled(ctx *gin.Context) bool {
+	if c.service == nil {
+		ctx.Error(errQuotasDisabled)
+		return false
+	}
+	return true
+}
@@ -4,21 +4,24 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
 
 	"go-microservice/internal/models"
 	"go-microservice/internal/service"
-	"go-microservice/pkg/logger"
+	"go-microservice/pkg/apperror"
 )
 
 // UserController handles user-related HTTP requests
 type UserController struct {
 	service service.Service
+	quotas  *service.QuotaService
 }
 
 // NewUserController creates a new user controller
-func NewUserController() *UserController {
-	return &UserController{}
+func NewUserController(svc service.Service, quotas *service.QuotaService) *UserController {
+	return &UserController{
+		service: svc,
+		quotas:  quotas,
+	}
 }
 
 // GetUsers handles GET /users request
@@ -33,13 +36,12 @@ func (c *UserController) GetUsers(ctx *gin.Context) {
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	var user models.User
 	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.Invalid(err))
 		return
 	}
 
-	if err := c.service.CreateUser(ctx, &user); err != nil {
-		logger.Error("Failed to create user", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	if err := c.service.CreateUser(ctx.Request.Context(), &user); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -50,19 +52,18 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 func (c *UserController) GetUser(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if id == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		ctx.Error(errUserIDRequired)
 		return
 	}
 
-	user, err := c.service.GetUser(ctx, id)
+	user, err := c.service.GetUser(ctx.Request.Context(), id)
 	if err != nil {
-		logger.Error("Failed to get user", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		ctx.Error(err)
 		return
 	}
 
 	if user == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		ctx.Error(apperror.New(apperror.CodeNotFound, "user not found"))
 		return
 	}
 
@@ -73,20 +74,19 @@ func (c *UserController) GetUser(ctx *gin.Context) {
 func (c *UserController) UpdateUser(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if id == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		ctx.Error(errUserIDRequired)
 		return
 	}
 
 	var user models.User
 	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.Invalid(err))
 		return
 	}
 
 	user.ID = id
-	if err := c.service.UpdateUser(ctx, &user); err != nil {
-		logger.Error("Failed to update user", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+	if err := c.service.UpdateUser(ctx.Request.Context(), &user); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -97,15 +97,36 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 func (c *UserController) DeleteUser(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if id == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+		ctx.Error(errUserIDRequired)
 		return
 	}
 
-	if err := c.service.DeleteUser(ctx, id); err != nil {
-		logger.Error("Failed to delete user", zap.Error(err))
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+	if err := c.service.DeleteUser(ctx.Request.Context(), id); err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
+
+// GetStorageUsage handles GET /users/:id/storage request
+func (c *UserController) GetStorageUsage(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		ctx.Error(errUserIDRequired)
+		return
+	}
+
+	if c.quotas == nil {
+		ctx.Error(errQuotasDisabled)
+		return
+	}
+
+	report, err := c.quotas.GetUserReport(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"go-microservice/internal/response"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminAuth requires the token returned by token as a bearer token. The
+// token is read on every request so that it can be rotated by a reload;
+// while it is empty all requests are refused.
+func AdminAuth(token func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := token()
+		if expected == "" {
+			response.Error(c, apperror.New(apperror.CodeForbidden, "admin API is disabled"))
+			return
+		}
+
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(expected)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			response.Error(c, apperror.New(apperror.CodeUnauthorized, "a valid admin token is required"))
+			return
+		}
+
+		// Attribute admin changes in the request logs
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), zap.String("subject", "admin")))
+		c.Next()
+	}
+}
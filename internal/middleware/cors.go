@@ -1,10 +1,38 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"sync/atomic"
 
-// CORSMiddleware adds CORS headers to responses
-func CORSMiddleware(allowOrigins string) gin.HandlerFunc {
+	"github.com/gin-gonic/gin"
+)
+
+// CORS adds CORS headers for origins that can be changed while serving
+type CORS struct {
+	allowOrigins atomic.Value // string
+}
+
+// NewCORS creates a CORS middleware allowing allowOrigins. No headers are
+// added while allowOrigins is empty.
+func NewCORS(allowOrigins string) *CORS {
+	c := &CORS{}
+	c.SetAllowOrigins(allowOrigins)
+	return c
+}
+
+// SetAllowOrigins changes the allowed origins for subsequent requests
+func (cors *CORS) SetAllowOrigins(allowOrigins string) {
+	cors.allowOrigins.Store(allowOrigins)
+}
+
+// Middleware returns the gin handler adding the headers
+func (cors *CORS) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		allowOrigins := cors.allowOrigins.Load().(string)
+		if allowOrigins == "" {
+			c.Next()
+			return
+		}
+
 		c.Writer.Header().Set("Access-Control-Allow-Origin", allowOrigins)
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
@@ -17,3 +45,8 @@ func CORSMiddleware(allowOrigins string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// CORSMiddleware adds CORS headers to responses
+func CORSMiddleware(allowOrigins string) gin.HandlerFunc {
+	return NewCORS(allowOrigins).Middleware()
+}
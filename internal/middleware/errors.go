@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-microservice/internal/response"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ErrorHandler turns the last error a handler added with c.Error into an
+// RFC 7807 problem details response, see apperror.From. Server errors are
+// logged with their cause. Handlers that wrote a response keep it.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		appErr := apperror.From(err)
+		if appErr.Code.Status() >= http.StatusInternalServerError {
+			logger.FromContext(c.Request.Context()).Error("Request failed",
+				zap.String("code", string(appErr.Code)),
+				zap.Error(err))
+		}
+		response.Error(c, appErr)
+	}
+}
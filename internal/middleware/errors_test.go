@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go-microservice/internal/response"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/logger"
+)
+
+func TestErrorHandlerWritesProblemDetails(t *testing.T) {
+	invalid := apperror.New(apperror.CodeValidation, "invalid request body")
+	invalid.Fields = map[string]string{"topic": "is required"}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   apperror.Code
+		wantDetail string
+		wantFields map[string]string
+		wantLogged bool
+	}{
+		{
+			name:       "not found",
+			err:        fmt.Errorf("get file: %w", apperror.New(apperror.CodeNotFound, "file not found")),
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperror.CodeNotFound,
+			wantDetail: "file not found",
+		},
+		{
+			name:       "validation with fields",
+			err:        invalid,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperror.CodeValidation,
+			wantDetail: "invalid request body",
+			wantFields: map[string]string{"topic": "is required"},
+		},
+		{
+			name:       "upstream failure",
+			err:        apperror.Wrap(errors.New("dial tcp 10.0.0.7:21"), apperror.CodeUpstream, "storage unavailable"),
+			wantStatus: http.StatusBadGateway,
+			wantCode:   apperror.CodeUpstream,
+			wantDetail: "storage unavailable",
+			wantLogged: true,
+		},
+		{
+			name:       "unclassified error",
+			err:        errors.New("pq: password authentication failed"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   apperror.CodeInternal,
+			wantDetail: "internal server error",
+			wantLogged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			core, logs := observer.New(zapcore.InfoLevel)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), zap.New(core)))
+			}, LoggingMiddleware(), ErrorHandler())
+			router.GET("/files/:id", func(c *gin.Context) { c.Error(tt.err) })
+
+			req := httptest.NewRequest(http.MethodGet, "/files/f1", nil)
+			req.Header.Set(HeaderRequestID, "req-1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, response.ProblemContentType) {
+				t.Errorf("Content-Type = %q, want %s", ct, response.ProblemContentType)
+			}
+			var problem response.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("body %s is not a problem: %v", w.Body, err)
+			}
+			want := response.Problem{
+				Type:      "about:blank",
+				Title:     http.StatusText(tt.wantStatus),
+				Status:    tt.wantStatus,
+				Detail:    tt.wantDetail,
+				Instance:  "/files/f1",
+				Code:      tt.wantCode,
+				RequestID: "req-1",
+				Errors:    tt.wantFields,
+			}
+			if !reflect.DeepEqual(problem, want) {
+				t.Errorf("problem = %+v, want %+v", problem, want)
+			}
+
+			// Causes are logged for server errors but never sent
+			if cause := errors.Unwrap(apperror.From(tt.err)); cause != nil && strings.Contains(w.Body.String(), cause.Error()) {
+				t.Errorf("body %s contains the cause", w.Body)
+			}
+			if logged := logs.FilterMessage("Request failed").Len() == 1; logged != tt.wantLogged {
+				t.Errorf("logged = %v, want %v", logged, tt.wantLogged)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerKeepsWrittenResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "queued")
+		c.Error(errors.New("notification failed"))
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusAccepted || w.Body.String() != "queued" {
+		t.Errorf("response = %d %s, want the handler's", w.Code, w.Body)
+	}
+}
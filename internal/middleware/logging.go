@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"strings"
 	"time"
 
 	"go-microservice/pkg/logger"
@@ -10,12 +11,45 @@ import (
 	"go.uber.org/zap"
 )
 
-// LoggingMiddleware adds logging to all requests
+// Headers identifying a request. The user and tenant headers are claims made
+// by the caller, such as a gateway, and are not verified here.
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceParent = "traceparent"
+	HeaderUserID      = "X-User-ID"
+	HeaderTenantID    = "X-Tenant-ID"
+)
+
+// maxRequestIDLength bounds request IDs taken from the X-Request-ID header
+const maxRequestIDLength = 128
+
+// redactedHeaders are logged without their values
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+}
+
+// LoggingMiddleware stores a request logger in the request context, see
+// logger.FromContext, and logs every request when it completes. The logger
+// carries the request ID, method, route template, the trace and span IDs
+// of a W3C traceparent header and the user and tenant headers as claimed_
+// fields. The authenticated subject is only added by authentication
+// middleware, such as AdminAuth.
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		requestID := uuid.New().String()
+
+		// Keep the caller's request ID so logs of several services line up
+		requestID := c.GetHeader(HeaderRequestID)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.New().String()
+		}
 		c.Set("request_id", requestID)
+		c.Header(HeaderRequestID, requestID)
+
+		ctx := logger.WithContext(c.Request.Context(), requestFields(c, requestID)...)
+		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
 
@@ -28,14 +62,15 @@ func LoggingMiddleware() gin.HandlerFunc {
 
 		headers := make(map[string]string)
 		for k, v := range c.Request.Header {
-			if len(v) > 0 {
+			if redactedHeaders[k] {
+				headers[k] = "[REDACTED]"
+			} else if len(v) > 0 {
 				headers[k] = v[0]
 			}
 		}
 
-		logger.Info("HTTP Request",
-			zap.String("request_id", requestID),
-			zap.String("method", c.Request.Method),
+		// Handlers may have added fields, such as the authenticated subject
+		logger.FromContext(c.Request.Context()).Info("HTTP Request",
 			zap.String("path", c.Request.URL.Path),
 			zap.String("remote_addr", c.ClientIP()),
 			zap.Any("headers", headers),
@@ -45,3 +80,48 @@ func LoggingMiddleware() gin.HandlerFunc {
 		)
 	}
 }
+
+// requestFields returns the fields identifying a request in its logs
+func requestFields(c *gin.Context, requestID string) []zap.Field {
+	fields := []zap.Field{
+		zap.String("request_id", requestID),
+		zap.String("method", c.Request.Method),
+		zap.String("route", c.FullPath()),
+	}
+	if traceID, spanID, ok := parseTraceParent(c.GetHeader(HeaderTraceParent)); ok {
+		fields = append(fields, zap.String("trace_id", traceID), zap.String("span_id", spanID))
+	}
+	// Any client can send these headers, so they are kept apart from the
+	// authenticated subject
+	if userID := c.GetHeader(HeaderUserID); userID != "" {
+		fields = append(fields, zap.String("claimed_user_id", userID))
+	}
+	if tenantID := c.GetHeader(HeaderTenantID); tenantID != "" {
+		fields = append(fields, zap.String("claimed_tenant_id", tenantID))
+	}
+	return fields
+}
+
+// parseTraceParent returns the trace and parent span IDs of a W3C
+// traceparent header such as 00-<32 hex trace ID>-<16 hex span ID>-01
+func parseTraceParent(header string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if !isHex(parts[1]) || !isHex(parts[2]) ||
+		parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// isHex reports whether s only holds lowercase hex digits
+func isHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go-microservice/pkg/logger"
+)
+
+// observedRouter returns a router logging requests to the returned observer
+func observedRouter(handlers ...gin.HandlerFunc) (*gin.Engine, *observer.ObservedLogs) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logger.NewContext(c.Request.Context(), zap.New(core)))
+	}, LoggingMiddleware())
+	router.GET("/admin", append(handlers, func(c *gin.Context) { c.Status(http.StatusNoContent) })...)
+	return router, logs
+}
+
+func TestLoggingMiddlewareMarksIdentityHeadersAsClaimed(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string
+		wantSubject string
+	}{
+		{"unauthenticated", "", ""},
+		{"authenticated admin", "secret", "admin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, logs := observedRouter(AdminAuth(func() string { return "secret" }))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set(HeaderUserID, "forged-user")
+			req.Header.Set(HeaderTenantID, "forged-tenant")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := logs.FilterMessage("HTTP Request").All()
+			if len(entries) != 1 {
+				t.Fatalf("logged %d requests, want 1", len(entries))
+			}
+			fields := entries[0].ContextMap()
+			if fields["claimed_user_id"] != "forged-user" || fields["claimed_tenant_id"] != "forged-tenant" {
+				t.Errorf("claimed fields = %v, %v, want the headers", fields["claimed_user_id"], fields["claimed_tenant_id"])
+			}
+			if subject, _ := fields["subject"].(string); subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			if _, ok := fields["tenant"]; ok {
+				t.Error("tenant header logged as an unmarked field")
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package middleware
 
 import (
-	"net/http"
 	"sync"
 
+	"go-microservice/internal/response"
+	"go-microservice/pkg/apperror"
+
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
@@ -27,6 +29,20 @@ func NewRateLimiter(r rate.Limit, b int, keyFunc KeyFunc) *RateLimiter {
 	}
 }
 
+// SetLimit changes the rate and burst of all keys, including those that
+// already have a limiter. rate.Inf lets every request through.
+func (rl *RateLimiter) SetLimit(r rate.Limit, b int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.rate = r
+	rl.burst = b
+	for _, limiter := range rl.limiters {
+		limiter.SetLimit(r)
+		limiter.SetBurst(b)
+	}
+}
+
 func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
@@ -45,9 +61,7 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 		limiter := rl.getLimiter(key)
 
 		if !limiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "Too Many Requests",
-			})
+			response.Error(c, apperror.New(apperror.CodeRateLimited, "too many requests"))
 			return
 		}
 		c.Next()
@@ -14,14 +14,59 @@ type User struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Storage classes a file can be kept in
+const (
+	StorageClassStandard = "standard"
+	StorageClassCold     = "cold"
+)
+
 // File represents a file in the system
 type File struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Path        string    `json:"path"`
-	Size        int64     `json:"size"`
-	ContentType string    `json:"content_type"`
-	UserID      string    `json:"user_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-} 
\ No newline at end of file
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	UserID       string    `json:"user_id"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	LegalHold    bool      `json:"legal_hold"`
+	StorageClass string    `json:"storage_class,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// StorageUsage holds storage consumption counters
+type StorageUsage struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+}
+
+// StorageQuota holds storage limits. Zero means unlimited.
+type StorageQuota struct {
+	MaxBytes int64 `json:"max_bytes"`
+	MaxFiles int64 `json:"max_files"`
+}
+
+// OutboxMessage is an event recorded in the same transaction as the data
+// change it describes, waiting to be published by the outbox relay
+type OutboxMessage struct {
+	ID            string            `json:"id"`
+	Sequence      int64             `json:"sequence"`
+	AggregateType string            `json:"aggregate_type"`
+	AggregateID   string            `json:"aggregate_id"`
+	EventType     string            `json:"event_type"`
+	Payload       []byte            `json:"payload"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Attempts      int               `json:"attempts"`
+	LastError     string            `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time        `json:"delivered_at,omitempty"`
+}
+
+// OutboxStats summarizes the state of the outbox
+type OutboxStats struct {
+	Pending       int        `json:"pending"`
+	Delivered     int        `json:"delivered"`
+	OldestPending *time.Time `json:"oldest_pending,omitempty"`
+}
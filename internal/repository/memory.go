@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go-microservice/internal/models"
+)
+
+// MemoryFileRepository is an in-memory FileRepository for development and tests
+type MemoryFileRepository struct {
+	files map[string]models.File
+	mu    sync.RWMutex
+}
+
+// NewMemoryFileRepository creates an empty in-memory file repository
+func NewMemoryFileRepository() *MemoryFileRepository {
+	return &MemoryFileRepository{
+		files: make(map[string]models.File),
+	}
+}
+
+// Create implements the FileRepository interface
+func (r *MemoryFileRepository) Create(ctx context.Context, file *models.File) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.files[file.ID] = *file
+	return nil
+}
+
+// Get implements the FileRepository interface
+func (r *MemoryFileRepository) Get(ctx context.Context, id string) (*models.File, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	file, ok := r.files[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &file, nil
+}
+
+// List implements the FileRepository interface. Files are ordered by creation time.
+func (r *MemoryFileRepository) List(ctx context.Context) ([]*models.File, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	files := make([]*models.File, 0, len(r.files))
+	for _, file := range r.files {
+		f := file
+		files = append(files, &f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt.Before(files[j].CreatedAt)
+	})
+	return files, nil
+}
+
+// Update implements the FileRepository interface
+func (r *MemoryFileRepository) Update(ctx context.Context, file *models.File) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.files[file.ID]; !ok {
+		return ErrNotFound
+	}
+	r.files[file.ID] = *file
+	return nil
+}
+
+// Delete implements the FileRepository interface
+func (r *MemoryFileRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.files[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.files, id)
+	return nil
+}
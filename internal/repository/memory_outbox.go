@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go-microservice/internal/models"
+)
+
+// MemoryOutboxRepository is an in-memory OutboxRepository for development and tests
+type MemoryOutboxRepository struct {
+	messages map[string]*models.OutboxMessage
+	sequence int64
+	mu       sync.Mutex
+}
+
+// NewMemoryOutboxRepository creates an empty in-memory outbox
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{
+		messages: make(map[string]*models.OutboxMessage),
+	}
+}
+
+// Add implements the OutboxRepository interface
+func (r *MemoryOutboxRepository) Add(ctx context.Context, msg *models.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sequence++
+	msg.Sequence = r.sequence
+
+	stored := *msg
+	r.messages[msg.ID] = &stored
+	return nil
+}
+
+// Pending implements the OutboxRepository interface
+func (r *MemoryOutboxRepository) Pending(ctx context.Context, limit int) ([]*models.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []*models.OutboxMessage
+	for _, msg := range r.messages {
+		if msg.DeliveredAt == nil {
+			m := *msg
+			pending = append(pending, &m)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Sequence < pending[j].Sequence
+	})
+
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+// MarkDelivered implements the OutboxRepository interface
+func (r *MemoryOutboxRepository) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg, ok := r.messages[id]
+	if !ok {
+		return ErrNotFound
+	}
+	msg.Attempts++
+	msg.LastError = ""
+	msg.DeliveredAt = &at
+	return nil
+}
+
+// MarkFailed implements the OutboxRepository interface
+func (r *MemoryOutboxRepository) MarkFailed(ctx context.Context, id string, cause error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg, ok := r.messages[id]
+	if !ok {
+		return ErrNotFound
+	}
+	msg.Attempts++
+	msg.LastError = cause.Error()
+	return nil
+}
+
+// DeleteDelivered implements the OutboxRepository interface
+func (r *MemoryOutboxRepository) DeleteDelivered(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for id, msg := range r.messages {
+		if msg.DeliveredAt != nil && msg.DeliveredAt.Before(before) {
+			delete(r.messages, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Stats implements the OutboxRepository interface
+func (r *MemoryOutboxRepository) Stats(ctx context.Context) (models.OutboxStats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stats models.OutboxStats
+	for _, msg := range r.messages {
+		if msg.DeliveredAt != nil {
+			stats.Delivered++
+			continue
+		}
+
+		stats.Pending++
+		if stats.OldestPending == nil || msg.CreatedAt.Before(*stats.OldestPending) {
+			createdAt := msg.CreatedAt
+			stats.OldestPending = &createdAt
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-microservice/internal/models"
+)
+
+// MemoryStore is an in-memory Store for development and tests. Transactions
+// are serialized; data changes are applied immediately and undone on
+// rollback, while outbox events only become visible on commit so the relay
+// never publishes events of a rolled back transaction.
+type MemoryStore struct {
+	users  *MemoryUserRepository
+	files  *MemoryFileRepository
+	outbox *MemoryOutboxRepository
+	txMu   sync.Mutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:  NewMemoryUserRepository(),
+		files:  NewMemoryFileRepository(),
+		outbox: NewMemoryOutboxRepository(),
+	}
+}
+
+// Users implements the Tx interface
+func (s *MemoryStore) Users() UserRepository { return s.users }
+
+// Files implements the Tx interface
+func (s *MemoryStore) Files() FileRepository { return s.files }
+
+// Outbox implements the Tx interface
+func (s *MemoryStore) Outbox() OutboxRepository { return s.outbox }
+
+// WithinTx implements the Store interface
+func (s *MemoryStore) WithinTx(ctx context.Context, fn func(tx Tx) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	tx := &memoryTx{store: s}
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	return tx.commit(ctx)
+}
+
+// memoryTx records how to undo its changes and buffers outbox events
+type memoryTx struct {
+	store  *MemoryStore
+	undo   []func()
+	events []*models.OutboxMessage
+}
+
+func (tx *memoryTx) Users() UserRepository         { return &txUserRepository{tx: tx} }
+func (tx *memoryTx) Files() FileRepository         { return &txFileRepository{tx: tx} }
+func (tx *memoryTx) Outbox() OutboxRepository      { return &txOutboxRepository{tx: tx} }
+func (tx *memoryTx) onRollback(undo func())        { tx.undo = append(tx.undo, undo) }
+func (tx *memoryTx) stage(e *models.OutboxMessage) { tx.events = append(tx.events, e) }
+
+// rollback undoes all changes in reverse order
+func (tx *memoryTx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+}
+
+// commit publishes the buffered outbox events
+func (tx *memoryTx) commit(ctx context.Context) error {
+	for _, event := range tx.events {
+		if err := tx.store.outbox.Add(ctx, event); err != nil {
+			tx.rollback()
+			return err
+		}
+	}
+	return nil
+}
+
+// txUserRepository records undo steps for user changes
+type txUserRepository struct {
+	tx *memoryTx
+}
+
+func (r *txUserRepository) Create(ctx context.Context, user *models.User) error {
+	users := r.tx.store.users
+	if err := users.Create(ctx, user); err != nil {
+		return err
+	}
+	id := user.ID
+	r.tx.onRollback(func() { users.Delete(context.Background(), id) })
+	return nil
+}
+
+func (r *txUserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	return r.tx.store.users.Get(ctx, id)
+}
+
+func (r *txUserRepository) Update(ctx context.Context, user *models.User) error {
+	users := r.tx.store.users
+	previous, err := users.Get(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if err := users.Update(ctx, user); err != nil {
+		return err
+	}
+	r.tx.onRollback(func() { users.Update(context.Background(), previous) })
+	return nil
+}
+
+func (r *txUserRepository) Delete(ctx context.Context, id string) error {
+	users := r.tx.store.users
+	previous, err := users.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := users.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.tx.onRollback(func() { users.Create(context.Background(), previous) })
+	return nil
+}
+
+// txFileRepository records undo steps for file changes
+type txFileRepository struct {
+	tx *memoryTx
+}
+
+func (r *txFileRepository) Create(ctx context.Context, file *models.File) error {
+	files := r.tx.store.files
+	if err := files.Create(ctx, file); err != nil {
+		return err
+	}
+	id := file.ID
+	r.tx.onRollback(func() { files.Delete(context.Background(), id) })
+	return nil
+}
+
+func (r *txFileRepository) Get(ctx context.Context, id string) (*models.File, error) {
+	return r.tx.store.files.Get(ctx, id)
+}
+
+func (r *txFileRepository) List(ctx context.Context) ([]*models.File, error) {
+	return r.tx.store.files.List(ctx)
+}
+
+func (r *txFileRepository) Update(ctx context.Context, file *models.File) error {
+	files := r.tx.store.files
+	previous, err := files.Get(ctx, file.ID)
+	if err != nil {
+		return err
+	}
+	if err := files.Update(ctx, file); err != nil {
+		return err
+	}
+	r.tx.onRollback(func() { files.Update(context.Background(), previous) })
+	return nil
+}
+
+func (r *txFileRepository) Delete(ctx context.Context, id string) error {
+	files := r.tx.store.files
+	previous, err := files.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := files.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.tx.onRollback(func() { files.Create(context.Background(), previous) })
+	return nil
+}
+
+// txOutboxRepository buffers new events until the transaction commits
+type txOutboxRepository struct {
+	tx *memoryTx
+}
+
+func (r *txOutboxRepository) Add(ctx context.Context, msg *models.OutboxMessage) error {
+	r.tx.stage(msg)
+	return nil
+}
+
+func (r *txOutboxRepository) Pending(ctx context.Context, limit int) ([]*models.OutboxMessage, error) {
+	return r.tx.store.outbox.Pending(ctx, limit)
+}
+
+func (r *txOutboxRepository) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	return r.tx.store.outbox.MarkDelivered(ctx, id, at)
+}
+
+func (r *txOutboxRepository) MarkFailed(ctx context.Context, id string, cause error) error {
+	return r.tx.store.outbox.MarkFailed(ctx, id, cause)
+}
+
+func (r *txOutboxRepository) DeleteDelivered(ctx context.Context, before time.Time) (int, error) {
+	return r.tx.store.outbox.DeleteDelivered(ctx, before)
+}
+
+func (r *txOutboxRepository) Stats(ctx context.Context) (models.OutboxStats, error) {
+	return r.tx.store.outbox.Stats(ctx)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go-microservice/internal/models"
+)
+
+// MemoryUsageRepository is an in-memory UsageRepository for single-node deployments
+type MemoryUsageRepository struct {
+	usage map[string]models.StorageUsage
+	mu    sync.Mutex
+}
+
+// NewMemoryUsageRepository creates an empty in-memory usage repository
+func NewMemoryUsageRepository() *MemoryUsageRepository {
+	return &MemoryUsageRepository{
+		usage: make(map[string]models.StorageUsage),
+	}
+}
+
+// Reserve implements the UsageRepository interface
+func (r *MemoryUsageRepository) Reserve(ctx context.Context, delta models.StorageUsage, scopes ...UsageScope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check every limit before touching any counter
+	for _, scope := range scopes {
+		current := r.usage[scope.Key]
+		if exceeds(current, delta, scope.Limit) {
+			return fmt.Errorf("%w: %s", ErrLimitExceeded, scope.Key)
+		}
+	}
+
+	for _, scope := range scopes {
+		current := r.usage[scope.Key]
+		current.Bytes += delta.Bytes
+		current.Files += delta.Files
+		r.usage[scope.Key] = current
+	}
+	return nil
+}
+
+// Release implements the UsageRepository interface
+func (r *MemoryUsageRepository) Release(ctx context.Context, delta models.StorageUsage, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, key := range keys {
+		current := r.usage[key]
+		current.Bytes = max(current.Bytes-delta.Bytes, 0)
+		current.Files = max(current.Files-delta.Files, 0)
+		r.usage[key] = current
+	}
+	return nil
+}
+
+// Get implements the UsageRepository interface
+func (r *MemoryUsageRepository) Get(ctx context.Context, key string) (models.StorageUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.usage[key], nil
+}
+
+// exceeds reports whether adding delta to current would break the limit
+func exceeds(current, delta models.StorageUsage, limit models.StorageQuota) bool {
+	if limit.MaxBytes > 0 && current.Bytes+delta.Bytes > limit.MaxBytes {
+		return true
+	}
+	if limit.MaxFiles > 0 && current.Files+delta.Files > limit.MaxFiles {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"go-microservice/internal/models"
+)
+
+// MemoryUserRepository is an in-memory UserRepository for development and tests
+type MemoryUserRepository struct {
+	users map[string]models.User
+	mu    sync.RWMutex
+}
+
+// NewMemoryUserRepository creates an empty in-memory user repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users: make(map[string]models.User),
+	}
+}
+
+// Create implements the UserRepository interface
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.ID] = *user
+	return nil
+}
+
+// Get implements the UserRepository interface
+func (r *MemoryUserRepository) Get(ctx context.Context, id string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// Update implements the UserRepository interface
+func (r *MemoryUserRepository) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; !ok {
+		return ErrNotFound
+	}
+	r.users[user.ID] = *user
+	return nil
+}
+
+// Delete implements the UserRepository interface
+func (r *MemoryUserRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
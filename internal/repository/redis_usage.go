@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go-microservice/internal/models"
+	database "go-microservice/pkg/cache"
+)
+
+// reserveScript checks every limit and only then increments all counters, so
+// a reservation either applies to every key or to none.
+// KEYS: counter hashes. ARGV: bytes, files, then max_bytes/max_files per key.
+const reserveScript = `
+local bytes = tonumber(ARGV[1])
+local files = tonumber(ARGV[2])
+for i, key in ipairs(KEYS) do
+	local curBytes = tonumber(redis.call('HGET', key, 'bytes') or '0')
+	local curFiles = tonumber(redis.call('HGET', key, 'files') or '0')
+	local maxBytes = tonumber(ARGV[1 + 2 * i])
+	local maxFiles = tonumber(ARGV[2 + 2 * i])
+	if maxBytes > 0 and curBytes + bytes > maxBytes then
+		return i
+	end
+	if maxFiles > 0 and curFiles + files > maxFiles then
+		return i
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('HINCRBY', key, 'bytes', bytes)
+	redis.call('HINCRBY', key, 'files', files)
+end
+return 0
+`
+
+// releaseScript decrements counters without letting them drop below zero
+const releaseScript = `
+local bytes = tonumber(ARGV[1])
+local files = tonumber(ARGV[2])
+for _, key in ipairs(KEYS) do
+	local curBytes = tonumber(redis.call('HGET', key, 'bytes') or '0')
+	local curFiles = tonumber(redis.call('HGET', key, 'files') or '0')
+	redis.call('HSET', key, 'bytes', math.max(curBytes - bytes, 0), 'files', math.max(curFiles - files, 0))
+end
+return 0
+`
+
+// RedisUsageRepository keeps usage counters in Redis so they are shared by all replicas
+type RedisUsageRepository struct {
+	client *database.RedisClient
+	prefix string
+}
+
+// NewRedisUsageRepository creates a Redis-backed usage repository
+func NewRedisUsageRepository(client *database.RedisClient) *RedisUsageRepository {
+	return &RedisUsageRepository{
+		client: client,
+		prefix: "storage:usage:",
+	}
+}
+
+// Reserve implements the UsageRepository interface
+func (r *RedisUsageRepository) Reserve(ctx context.Context, delta models.StorageUsage, scopes ...UsageScope) error {
+	keys := make([]string, 0, len(scopes))
+	args := []interface{}{delta.Bytes, delta.Files}
+	for _, scope := range scopes {
+		keys = append(keys, r.prefix+scope.Key)
+		args = append(args, scope.Limit.MaxBytes, scope.Limit.MaxFiles)
+	}
+
+	result, err := r.client.Eval(ctx, reserveScript, keys, args...)
+	if err != nil {
+		return fmt.Errorf("failed to reserve usage: %w", err)
+	}
+
+	if failed, ok := result.(int64); ok && failed > 0 {
+		return fmt.Errorf("%w: %s", ErrLimitExceeded, scopes[failed-1].Key)
+	}
+	return nil
+}
+
+// Release implements the UsageRepository interface
+func (r *RedisUsageRepository) Release(ctx context.Context, delta models.StorageUsage, keys ...string) error {
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, r.prefix+key)
+	}
+
+	if _, err := r.client.Eval(ctx, releaseScript, prefixed, delta.Bytes, delta.Files); err != nil {
+		return fmt.Errorf("failed to release usage: %w", err)
+	}
+	return nil
+}
+
+// Get implements the UsageRepository interface
+func (r *RedisUsageRepository) Get(ctx context.Context, key string) (models.StorageUsage, error) {
+	values, err := r.client.HGetAll(ctx, r.prefix+key)
+	if err != nil {
+		return models.StorageUsage{}, fmt.Errorf("failed to get usage: %w", err)
+	}
+
+	var usage models.StorageUsage
+	usage.Bytes, _ = strconv.ParseInt(values["bytes"], 10, 64)
+	usage.Files, _ = strconv.ParseInt(values["files"], 10, 64)
+	return usage, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-microservice/internal/models"
+	"go-microservice/pkg/apperror"
+)
+
+// Common errors
+var (
+	ErrNotFound      = apperror.New(apperror.CodeNotFound, "record not found")
+	ErrLimitExceeded = apperror.New(apperror.CodeQuotaExceeded, "usage limit exceeded")
+)
+
+// UserRepository defines persistence operations for users
+type UserRepository interface {
+	// Create stores a new user
+	Create(ctx context.Context, user *models.User) error
+
+	// Get returns the user with the given ID
+	Get(ctx context.Context, id string) (*models.User, error)
+
+	// Update replaces an existing user
+	Update(ctx context.Context, user *models.User) error
+
+	// Delete removes the user with the given ID
+	Delete(ctx context.Context, id string) error
+}
+
+// FileRepository defines persistence operations for file records
+type FileRepository interface {
+	// Create stores a new file record
+	Create(ctx context.Context, file *models.File) error
+
+	// Get returns the file record with the given ID
+	Get(ctx context.Context, id string) (*models.File, error)
+
+	// List returns all file records
+	List(ctx context.Context) ([]*models.File, error)
+
+	// Update replaces an existing file record
+	Update(ctx context.Context, file *models.File) error
+
+	// Delete removes the file record with the given ID
+	Delete(ctx context.Context, id string) error
+}
+
+// UsageScope identifies a usage counter and the limit it must stay within
+type UsageScope struct {
+	Key   string
+	Limit models.StorageQuota
+}
+
+// UsageRepository maintains storage usage counters
+type UsageRepository interface {
+	// Reserve atomically adds delta to every scope. If any scope would exceed
+	// its limit nothing is changed and ErrLimitExceeded is returned.
+	Reserve(ctx context.Context, delta models.StorageUsage, scopes ...UsageScope) error
+
+	// Release subtracts delta from the counters with the given keys
+	Release(ctx context.Context, delta models.StorageUsage, keys ...string) error
+
+	// Get returns the current usage for a key
+	Get(ctx context.Context, key string) (models.StorageUsage, error)
+}
+
+// OutboxRepository stores events waiting to be published
+type OutboxRepository interface {
+	// Add records a new event. The repository assigns its sequence number.
+	Add(ctx context.Context, msg *models.OutboxMessage) error
+
+	// Pending returns up to limit undelivered events in sequence order
+	Pending(ctx context.Context, limit int) ([]*models.OutboxMessage, error)
+
+	// MarkDelivered records that an event has been published
+	MarkDelivered(ctx context.Context, id string, at time.Time) error
+
+	// MarkFailed records a failed publish attempt
+	MarkFailed(ctx context.Context, id string, cause error) error
+
+	// DeleteDelivered removes events delivered before the given time and
+	// returns how many were removed
+	DeleteDelivered(ctx context.Context, before time.Time) (int, error)
+
+	// Stats returns the number of pending and delivered events
+	Stats(ctx context.Context) (models.OutboxStats, error)
+}
+
+// Tx exposes the repositories taking part in a transaction
+type Tx interface {
+	Users() UserRepository
+	Files() FileRepository
+	Outbox() OutboxRepository
+}
+
+// Store gives access to the repositories and runs transactions across them.
+// Outside a transaction every call commits on its own.
+type Store interface {
+	Tx
+
+	// WithinTx runs fn in a transaction. Changes made through tx are
+	// committed if fn returns nil and rolled back otherwise.
+	WithinTx(ctx context.Context, fn func(tx Tx) error) error
+}
@@ -3,19 +3,31 @@ package response
 import (
 	"net/http"
 
+	"go-microservice/pkg/apperror"
+
 	"github.com/gin-gonic/gin"
 )
 
+// ProblemContentType is the media type of Problem responses
+const ProblemContentType = "application/problem+json"
+
 type SuccessResponse struct {
 	Status  string      `json:"status"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
-type ErrorResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+// Problem is an RFC 7807 problem details error response. Code, RequestID
+// and Errors are extension members.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      apperror.Code     `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    map[string]string `json:"errors,omitempty"`
 }
 
 // Success sends a successful JSON response
@@ -27,11 +39,19 @@ func Success(c *gin.Context, data interface{}, message string) {
 	})
 }
 
-// Error sends a standardized error JSON response
-func Error(c *gin.Context, code int, message string) {
-	c.AbortWithStatusJSON(code, ErrorResponse{
-		Status:  "error",
-		Message: message,
-		Code:    code,
+// Error sends err as a problem details response and aborts the request.
+// The cause of err is not included.
+func Error(c *gin.Context, err *apperror.Error) {
+	status := err.Code.Status()
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    err.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      err.Code,
+		RequestID: c.GetString("request_id"),
+		Errors:    err.Fields,
 	})
 }
@@ -20,12 +20,19 @@ func RegisterRoutes(router *gin.Engine) {
 	// Register v1 routes
 	v1Group := router.Group("/api/v1")
 	for _, route := range v1.RegisterRoutes() {
-		v1Group.Handle(route.Method, route.Path, route.Handler)
+		v1Group.Handle(route.Method, route.Path, handlers(route)...)
 	}
 
 	// Register v2 routes
 	v2Group := router.Group("/api/v2")
 	for _, route := range v2.RegisterRoutes() {
-		v2Group.Handle(route.Method, route.Path, route.Handler)
+		v2Group.Handle(route.Method, route.Path, handlers(route)...)
 	}
 }
+
+// handlers returns the middleware and handler of a route
+func handlers(route types.Route) []gin.HandlerFunc {
+	chain := make([]gin.HandlerFunc, 0, len(route.Middleware)+1)
+	chain = append(chain, route.Middleware...)
+	return append(chain, route.Handler)
+}
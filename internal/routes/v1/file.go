@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"go-microservice/internal/controller"
+	"go-microservice/internal/service"
+	"go-microservice/internal/types"
+	"go-microservice/pkg/logger"
+)
+
+// Automatically register when this file is imported.
+func init() {
+	routeRegistry = append(routeRegistry, RegisterFileRoutes)
+}
+
+// RegisterFileRoutes registers all file-related routes
+func RegisterFileRoutes() []types.Route {
+	fileController := controller.NewFileController(logger.Get(), service.GetRegistry().Service)
+
+	return []types.Route{
+		{
+			Method:  "POST",
+			Path:    "/files",
+			Handler: fileController.UploadFile,
+		},
+		{
+			Method:  "GET",
+			Path:    "/files/:id",
+			Handler: fileController.DownloadFile,
+		},
+		{
+			Method:  "DELETE",
+			Path:    "/files/:id",
+			Handler: fileController.DeleteFile,
+		},
+	}
+}
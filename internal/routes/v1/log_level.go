@@ -0,0 +1,46 @@
+package v1
+
+import (
+	"go-microservice/internal/controller"
+	"go-microservice/internal/types"
+	"go-microservice/pkg/logger"
+)
+
+// Automatically register when this file is imported.
+func init() {
+	routeRegistry = append(routeRegistry, RegisterLogLevelRoutes)
+}
+
+// RegisterLogLevelRoutes registers the runtime log level routes, which
+// require the admin token
+func RegisterLogLevelRoutes() []types.Route {
+	logLevelController := controller.NewLogLevelController(logger.Get())
+	admin := adminOnly()
+
+	return []types.Route{
+		{
+			Method:     "GET",
+			Path:       "/admin/log/levels",
+			Handler:    logLevelController.GetLevels,
+			Middleware: admin,
+		},
+		{
+			Method:     "PUT",
+			Path:       "/admin/log/levels",
+			Handler:    logLevelController.SetLevel,
+			Middleware: admin,
+		},
+		{
+			Method:     "PUT",
+			Path:       "/admin/log/levels/:component",
+			Handler:    logLevelController.SetComponentLevel,
+			Middleware: admin,
+		},
+		{
+			Method:     "DELETE",
+			Path:       "/admin/log/levels/:component",
+			Handler:    logLevelController.ResetComponentLevel,
+			Middleware: admin,
+		},
+	}
+}
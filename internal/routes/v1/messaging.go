@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"go-microservice/internal/controller"
+	"go-microservice/internal/service"
+	"go-microservice/internal/types"
+	"go-microservice/pkg/logger"
+)
+
+// Automatically register when this file is imported.
+func init() {
+	routeRegistry = append(routeRegistry, RegisterMessagingRoutes)
+}
+
+// RegisterMessagingRoutes registers all messaging administration routes
+func RegisterMessagingRoutes() []types.Route {
+	services := service.GetRegistry()
+	deadLetterController := controller.NewDeadLetterController(logger.Get(), services.DeadLetters)
+	outboxController := controller.NewOutboxController(logger.Get(), services.Outbox)
+	messagingController := controller.NewMessagingController(services.Messaging, logger.Get())
+	admin := adminOnly()
+
+	return []types.Route{
+		{
+			Method:  "POST",
+			Path:    "/messages",
+			Handler: messagingController.PublishMessage,
+		},
+		{
+			Method:  "POST",
+			Path:    "/messages/batch",
+			Handler: messagingController.PublishBatch,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/topics",
+			Handler:    messagingController.GetTopics,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/lag",
+			Handler:    messagingController.GetLag,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/subscriptions",
+			Handler:    messagingController.GetSubscriptions,
+			Middleware: admin,
+		},
+		{
+			Method:     "POST",
+			Path:       "/admin/messaging/subscriptions/:topic/pause",
+			Handler:    messagingController.PauseSubscription,
+			Middleware: admin,
+		},
+		{
+			Method:     "POST",
+			Path:       "/admin/messaging/subscriptions/:topic/resume",
+			Handler:    messagingController.ResumeSubscription,
+			Middleware: admin,
+		},
+		{
+			Method:     "POST",
+			Path:       "/admin/messaging/offsets/reset",
+			Handler:    messagingController.ResetOffsets,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/scheduled",
+			Handler:    messagingController.GetScheduledMessages,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/scheduled/:id",
+			Handler:    messagingController.GetScheduledMessage,
+			Middleware: admin,
+		},
+		{
+			Method:     "DELETE",
+			Path:       "/admin/messaging/scheduled/:id",
+			Handler:    messagingController.CancelScheduledMessage,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/dlq",
+			Handler:    deadLetterController.ListDeadLetters,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/dlq/:id",
+			Handler:    deadLetterController.GetDeadLetter,
+			Middleware: admin,
+		},
+		{
+			Method:     "POST",
+			Path:       "/admin/messaging/dlq/:id/replay",
+			Handler:    deadLetterController.ReplayDeadLetter,
+			Middleware: admin,
+		},
+		{
+			Method:     "DELETE",
+			Path:       "/admin/messaging/dlq/:id",
+			Handler:    deadLetterController.DiscardDeadLetter,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/messaging/outbox",
+			Handler:    outboxController.GetStats,
+			Middleware: admin,
+		},
+	}
+}
@@ -0,0 +1,20 @@
+package v1
+
+import "testing"
+
+func TestMessagingAdminRoutesRequireAdminToken(t *testing.T) {
+	routes := RegisterMessagingRoutes()
+	for _, prefix := range []string{
+		"/admin/messaging/topics",
+		"/admin/messaging/lag",
+		"/admin/messaging/subscriptions",
+		"/admin/messaging/offsets",
+		"/admin/messaging/outbox",
+		"/admin/messaging/scheduled",
+		"/admin/messaging/dlq",
+	} {
+		t.Run(prefix, func(t *testing.T) {
+			requireAdminAuth(t, routes, prefix)
+		})
+	}
+}
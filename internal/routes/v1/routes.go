@@ -1,6 +1,10 @@
 package v1
 
 import (
+	"github.com/gin-gonic/gin"
+
+	"go-microservice/internal/config"
+	"go-microservice/internal/middleware"
 	"go-microservice/internal/types"
 )
 
@@ -15,3 +19,11 @@ func RegisterRoutes() []types.Route {
 	}
 	return routes
 }
+
+// adminOnly is the middleware of administration routes, which require the
+// admin token
+func adminOnly() []gin.HandlerFunc {
+	return []gin.HandlerFunc{middleware.AdminAuth(func() string {
+		return config.GetConfig().Server.AdminToken
+	})}
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"go-microservice/internal/config"
+	"go-microservice/internal/types"
+)
+
+// adminToken is the admin token config/dev.json reads from ADMIN_TOKEN
+const adminToken = "dev-admin-token"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("ADMIN_TOKEN", adminToken)
+	if _, err := config.LoadConfig(config.LoadOptions{Dir: "../../../config", Env: "dev"}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// serve handles a request with routes mounted like RegisterRoutes does
+func serve(routes []types.Route, method, path, token string) *httptest.ResponseRecorder {
+	engine := gin.New()
+	for _, route := range routes {
+		engine.Handle(route.Method, route.Path, append(route.Middleware, route.Handler)...)
+	}
+
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// requireAdminAuth checks that every route under prefix refuses requests
+// without the admin token and lets them through with it
+func requireAdminAuth(t *testing.T, routes []types.Route, prefix string) {
+	t.Helper()
+
+	var checked int
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, prefix) {
+			continue
+		}
+		checked++
+
+		// Path parameters are filled in with a placeholder
+		path := route.Path
+		for _, segment := range strings.Split(route.Path, "/") {
+			if strings.HasPrefix(segment, ":") {
+				path = strings.Replace(path, segment, "x", 1)
+			}
+		}
+
+		if w := serve(routes, route.Method, path, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without token: status %d, want %d", route.Method, route.Path, w.Code, http.StatusUnauthorized)
+		}
+		if w := serve(routes, route.Method, path, "wrong"); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s with wrong token: status %d, want %d", route.Method, route.Path, w.Code, http.StatusUnauthorized)
+		}
+		if w := serve(routes, route.Method, path, adminToken); w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden {
+			t.Errorf("%s %s with admin token: status %d", route.Method, route.Path, w.Code)
+		}
+	}
+	if checked == 0 {
+		t.Fatalf("no routes under %s", prefix)
+	}
+}
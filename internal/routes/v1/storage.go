@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"go-microservice/internal/controller"
+	"go-microservice/internal/service"
+	"go-microservice/internal/types"
+	"go-microservice/pkg/logger"
+)
+
+// Automatically register when this file is imported.
+func init() {
+	routeRegistry = append(routeRegistry, RegisterStorageRoutes)
+}
+
+// RegisterStorageRoutes registers all storage-related routes
+func RegisterStorageRoutes() []types.Route {
+	services := service.GetRegistry()
+	lifecycleController := controller.NewLifecycleController(logger.Get(), services.Lifecycle)
+	quotaController := controller.NewQuotaController(logger.Get(), services.Quota)
+	admin := adminOnly()
+
+	return []types.Route{
+		{
+			Method:     "GET",
+			Path:       "/storage/lifecycle/report",
+			Handler:    lifecycleController.GetReport,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/quotas/users/:id",
+			Handler:    quotaController.GetUserQuota,
+			Middleware: admin,
+		},
+		{
+			Method:     "PUT",
+			Path:       "/admin/quotas/users/:id",
+			Handler:    quotaController.SetUserQuota,
+			Middleware: admin,
+		},
+		{
+			Method:     "GET",
+			Path:       "/admin/quotas/tenants/:id",
+			Handler:    quotaController.GetTenantQuota,
+			Middleware: admin,
+		},
+		{
+			Method:     "PUT",
+			Path:       "/admin/quotas/tenants/:id",
+			Handler:    quotaController.SetTenantQuota,
+			Middleware: admin,
+		},
+	}
+}
@@ -0,0 +1,22 @@
+package v1
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestQuotaRoutesRequireAdminToken(t *testing.T) {
+	routes := RegisterStorageRoutes()
+	requireAdminAuth(t, routes, "/admin/quotas/")
+
+	// Quota changes in particular must not go through unauthenticated
+	for _, path := range []string{"/admin/quotas/users/u1", "/admin/quotas/tenants/t1"} {
+		if w := serve(routes, http.MethodPut, path, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("PUT %s without token: status %d, want %d", path, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestLifecycleReportRequiresAdminToken(t *testing.T) {
+	requireAdminAuth(t, RegisterStorageRoutes(), "/storage/lifecycle/report")
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"go-microservice/internal/controller"
+	"go-microservice/internal/service"
 	"go-microservice/internal/types"
 )
 
@@ -12,7 +13,8 @@ func init() {
 
 // RegisterUserRoutes registers all user-related routes
 func RegisterUserRoutes() []types.Route {
-	userController := controller.NewUserController()
+	services := service.GetRegistry()
+	userController := controller.NewUserController(services.Service, services.Quota)
 
 	return []types.Route{
 		{
@@ -40,5 +42,10 @@ func RegisterUserRoutes() []types.Route {
 			Path:    "/users/:id",
 			Handler: userController.DeleteUser,
 		},
+		{
+			Method:  "GET",
+			Path:    "/users/:id/storage",
+			Handler: userController.GetStorageUsage,
+		},
 	}
 }
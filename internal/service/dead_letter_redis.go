@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	database "go-microservice/pkg/cache"
+	"go-microservice/pkg/messaging"
+)
+
+// deadLetterAddScript stores a dead letter, indexes it by receive time and
+// drops the oldest letters beyond the capacity. A letter recorded again keeps
+// its place in the index.
+// KEYS: index, letters. ARGV: ID, receive time in milliseconds, record JSON, capacity.
+const deadLetterAddScript = `
+redis.call('ZADD', KEYS[1], 'NX', ARGV[2], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+local excess = redis.call('ZCARD', KEYS[1]) - tonumber(ARGV[4])
+if excess > 0 then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, excess - 1)
+	redis.call('ZREM', KEYS[1], unpack(oldest))
+	redis.call('HDEL', KEYS[2], unpack(oldest))
+end
+return 1
+`
+
+// deadLetterGetScript returns the record JSON of a dead letter.
+// KEYS: letters. ARGV: ID.
+const deadLetterGetScript = `
+return redis.call('HGET', KEYS[1], ARGV[1])
+`
+
+// deadLetterListScript returns the record JSON of all dead letters, oldest first.
+// KEYS: index, letters.
+const deadLetterListScript = `
+local ids = redis.call('ZRANGE', KEYS[1], 0, -1)
+if #ids == 0 then
+	return {}
+end
+return redis.call('HMGET', KEYS[2], unpack(ids))
+`
+
+// deadLetterRemoveScript deletes a dead letter and returns 1 if it existed.
+// KEYS: index, letters. ARGV: ID.
+const deadLetterRemoveScript = `
+redis.call('HDEL', KEYS[2], ARGV[1])
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`
+
+// deadLetterRecord is a dead letter as stored in Redis. The message is kept
+// beside the letter so that replays publish the original key and payload.
+type deadLetterRecord struct {
+	Letter  *DeadLetter        `json:"letter"`
+	Message *messaging.Message `json:"message"`
+}
+
+// RedisDeadLetterStore keeps dead letters in Redis so that all replicas show
+// the same letters and they survive restarts. A sorted set indexes the IDs by
+// receive time; the letters are stored as JSON in a hash.
+type RedisDeadLetterStore struct {
+	client   *database.RedisClient
+	capacity int
+	index    string
+	letters  string
+}
+
+// NewRedisDeadLetterStore creates a Redis-backed store keeping up to capacity letters
+func NewRedisDeadLetterStore(client *database.RedisClient, capacity int) *RedisDeadLetterStore {
+	if capacity <= 0 {
+		capacity = defaultDeadLetterBuffer
+	}
+
+	return &RedisDeadLetterStore{
+		client:   client,
+		capacity: capacity,
+		index:    "messaging:dlq",
+		letters:  "messaging:dlq:letters",
+	}
+}
+
+// Add implements the DeadLetterStore interface
+func (s *RedisDeadLetterStore) Add(ctx context.Context, letter *DeadLetter, msg *messaging.Message) error {
+	data, err := json.Marshal(deadLetterRecord{Letter: letter, Message: msg})
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter %s: %w", letter.ID, err)
+	}
+
+	_, err = s.client.Eval(ctx, deadLetterAddScript, []string{s.index, s.letters},
+		letter.ID, letter.ReceivedAt.UnixMilli(), data, s.capacity)
+	if err != nil {
+		return fmt.Errorf("failed to store dead letter %s: %w", letter.ID, err)
+	}
+	return nil
+}
+
+// Get implements the DeadLetterStore interface
+func (s *RedisDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, *messaging.Message, error) {
+	result, err := s.client.Eval(ctx, deadLetterGetScript, []string{s.letters}, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get dead letter %s: %w", id, err)
+	}
+	if result == nil {
+		return nil, nil, ErrDeadLetterNotFound
+	}
+
+	records, err := decodeDeadLetters([]interface{}{result})
+	if err != nil {
+		return nil, nil, err
+	}
+	return records[0].Letter, records[0].Message, nil
+}
+
+// List implements the DeadLetterStore interface
+func (s *RedisDeadLetterStore) List(ctx context.Context) ([]*DeadLetter, error) {
+	result, err := s.client.Eval(ctx, deadLetterListScript, []string{s.index, s.letters})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	values, _ := result.([]interface{})
+	records, err := decodeDeadLetters(values)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*DeadLetter, 0, len(records))
+	for _, record := range records {
+		letters = append(letters, record.Letter)
+	}
+	return letters, nil
+}
+
+// Remove implements the DeadLetterStore interface
+func (s *RedisDeadLetterStore) Remove(ctx context.Context, id string) error {
+	result, err := s.client.Eval(ctx, deadLetterRemoveScript, []string{s.index, s.letters}, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove dead letter %s: %w", id, err)
+	}
+	if removed, _ := result.(int64); removed == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// decodeDeadLetters parses dead letters stored as JSON. Entries removed
+// between reading the index and the hash are nil and skipped.
+func decodeDeadLetters(values []interface{}) ([]*deadLetterRecord, error) {
+	records := make([]*deadLetterRecord, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var record deadLetterRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/messaging"
+)
+
+// ErrDeadLetterNotFound is returned for unknown dead-letter IDs
+var ErrDeadLetterNotFound = apperror.New(apperror.CodeNotFound, "dead letter not found")
+
+// defaultDeadLetterBuffer is the number of dead letters kept when none is configured
+const defaultDeadLetterBuffer = 1000
+
+// DeadLetter is a message that exhausted all retries
+type DeadLetter struct {
+	ID             string            `json:"id"`
+	Topic          string            `json:"topic"`
+	OriginalTopic  string            `json:"original_topic"`
+	Error          string            `json:"error"`
+	Attempts       int               `json:"attempts"`
+	FirstFailureAt string            `json:"first_failure_at,omitempty"`
+	DeadLetteredAt string            `json:"dead_lettered_at,omitempty"`
+	ReceivedAt     time.Time         `json:"received_at"`
+	Headers        map[string]string `json:"headers"`
+	Payload        string            `json:"payload"`
+}
+
+// DeadLetterStore keeps the dead letters available for inspection. Stores
+// shared by all replicas, such as RedisDeadLetterStore, show every replica
+// the same letters whichever of them consumed the dead-letter topic.
+type DeadLetterStore interface {
+	// Add stores a dead letter and the message it was recorded from
+	Add(ctx context.Context, letter *DeadLetter, msg *messaging.Message) error
+	// Get returns a dead letter and its message
+	Get(ctx context.Context, id string) (*DeadLetter, *messaging.Message, error)
+	// List returns all dead letters, oldest first
+	List(ctx context.Context) ([]*DeadLetter, error)
+	// Remove deletes a dead letter, ErrDeadLetterNotFound if there is none
+	Remove(ctx context.Context, id string) error
+}
+
+// DeadLetterService keeps the most recent dead-lettered messages for
+// inspection and replays them onto their original topic. Messages stay in the
+// dead-letter topic itself; the service only holds a bounded view in its store.
+type DeadLetterService struct {
+	client messaging.Messaging
+	store  DeadLetterStore
+	logger *zap.Logger
+}
+
+// NewDeadLetterService creates a dead-letter service keeping its letters in store
+func NewDeadLetterService(client messaging.Messaging, store DeadLetterStore, logger *zap.Logger) *DeadLetterService {
+	return &DeadLetterService{
+		client: client,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Record stores a message received from a dead-letter topic. It is used as
+// the handler for dead-letter subscriptions.
+func (s *DeadLetterService) Record(ctx context.Context, msg *messaging.Message) error {
+	id := msg.Headers[messaging.HeaderDeadLetterID]
+	if id == "" {
+		s.logger.Warn("Ignoring dead letter without ID", zap.String("topic", msg.Topic))
+		return nil
+	}
+
+	attempts, _ := strconv.Atoi(msg.Headers[messaging.HeaderAttempt])
+	letter := &DeadLetter{
+		ID:             id,
+		Topic:          msg.Topic,
+		OriginalTopic:  messaging.OriginalTopic(msg),
+		Error:          msg.Headers[messaging.HeaderError],
+		Attempts:       attempts,
+		FirstFailureAt: msg.Headers[messaging.HeaderFirstFailureAt],
+		DeadLetteredAt: msg.Headers[messaging.HeaderDeadLetteredAt],
+		ReceivedAt:     time.Now().UTC(),
+		Headers:        msg.Headers,
+		Payload:        string(msg.Payload),
+	}
+
+	if err := s.store.Add(ctx, letter, msg); err != nil {
+		return fmt.Errorf("failed to store dead letter %s: %w", id, err)
+	}
+
+	s.logger.Warn("Message dead-lettered",
+		zap.String("id", id),
+		zap.String("original_topic", letter.OriginalTopic),
+		zap.Int("attempts", attempts),
+		zap.String("error", letter.Error))
+
+	return nil
+}
+
+// List returns the stored dead letters, oldest first, optionally filtered by original topic
+func (s *DeadLetterService) List(ctx context.Context, topic string) ([]*DeadLetter, error) {
+	letters, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if topic == "" {
+		return letters, nil
+	}
+
+	filtered := make([]*DeadLetter, 0, len(letters))
+	for _, letter := range letters {
+		if letter.OriginalTopic == topic {
+			filtered = append(filtered, letter)
+		}
+	}
+	return filtered, nil
+}
+
+// Get returns a single dead letter
+func (s *DeadLetterService) Get(ctx context.Context, id string) (*DeadLetter, error) {
+	letter, _, err := s.store.Get(ctx, id)
+	return letter, err
+}
+
+// Replay publishes a dead letter back onto its original topic with a fresh
+// attempt count and removes it from the store
+func (s *DeadLetterService) Replay(ctx context.Context, id string) (*DeadLetter, error) {
+	letter, msg, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.client.Publish(ctx, messaging.ReplayMessage(msg)); err != nil {
+		return nil, fmt.Errorf("failed to replay dead letter: %w", err)
+	}
+
+	// Another replica may have replayed or discarded it meanwhile
+	if err := s.store.Remove(ctx, id); err != nil && !errors.Is(err, ErrDeadLetterNotFound) {
+		return nil, fmt.Errorf("failed to remove replayed dead letter: %w", err)
+	}
+	s.logger.Info("Dead letter replayed",
+		zap.String("id", id),
+		zap.String("topic", letter.OriginalTopic))
+
+	return letter, nil
+}
+
+// Discard removes a dead letter from the store without replaying it
+func (s *DeadLetterService) Discard(ctx context.Context, id string) error {
+	return s.store.Remove(ctx, id)
+}
+
+// MemoryDeadLetterStore is an in-memory DeadLetterStore for single-instance
+// deployments and tests. It keeps the most recent capacity letters, which
+// are lost on restart.
+type MemoryDeadLetterStore struct {
+	capacity int
+
+	mu       sync.RWMutex
+	letters  map[string]*DeadLetter
+	messages map[string]*messaging.Message
+	order    []string
+}
+
+// NewMemoryDeadLetterStore creates an in-memory store keeping up to capacity letters
+func NewMemoryDeadLetterStore(capacity int) *MemoryDeadLetterStore {
+	if capacity <= 0 {
+		capacity = defaultDeadLetterBuffer
+	}
+
+	return &MemoryDeadLetterStore{
+		capacity: capacity,
+		letters:  make(map[string]*DeadLetter),
+		messages: make(map[string]*messaging.Message),
+	}
+}
+
+// Add implements the DeadLetterStore interface
+func (s *MemoryDeadLetterStore) Add(ctx context.Context, letter *DeadLetter, msg *messaging.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.letters[letter.ID]; !exists {
+		s.order = append(s.order, letter.ID)
+	}
+	s.letters[letter.ID] = letter
+	s.messages[letter.ID] = msg
+
+	// Drop the oldest entries once the buffer is full
+	for len(s.order) > s.capacity {
+		delete(s.letters, s.order[0])
+		delete(s.messages, s.order[0])
+		s.order = s.order[1:]
+	}
+	return nil
+}
+
+// Get implements the DeadLetterStore interface
+func (s *MemoryDeadLetterStore) Get(ctx context.Context, id string) (*DeadLetter, *messaging.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	letter, ok := s.letters[id]
+	if !ok {
+		return nil, nil, ErrDeadLetterNotFound
+	}
+	return letter, s.messages[id], nil
+}
+
+// List implements the DeadLetterStore interface
+func (s *MemoryDeadLetterStore) List(ctx context.Context) ([]*DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	letters := make([]*DeadLetter, 0, len(s.order))
+	for _, id := range s.order {
+		letters = append(letters, s.letters[id])
+	}
+	return letters, nil
+}
+
+// Remove implements the DeadLetterStore interface
+func (s *MemoryDeadLetterStore) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(s.letters, id)
+	delete(s.messages, id)
+	for i, existing := range s.order {
+		if existing == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-microservice/pkg/messaging"
+)
+
+// publishRecorder is a Messaging client passing on every published message
+type publishRecorder struct {
+	messaging.Messaging
+	published chan *messaging.Message
+}
+
+// Publish implements the messaging.Messaging interface
+func (r *publishRecorder) Publish(ctx context.Context, msg *messaging.Message) error {
+	r.published <- msg
+	return nil
+}
+
+// deadLetter returns a message as the retrier sends it to a dead-letter topic
+func deadLetter(id, original string) *messaging.Message {
+	return &messaging.Message{
+		Topic:   original + ".dlq",
+		Key:     "key-" + id,
+		Payload: []byte(`{"id":"` + id + `"}`),
+		Headers: map[string]string{
+			messaging.HeaderDeadLetterID:  id,
+			messaging.HeaderOriginalTopic: original,
+			messaging.HeaderAttempt:       "5",
+			messaging.HeaderError:         "handler failed",
+		},
+	}
+}
+
+func TestDeadLetterServiceSharesStoreAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	client := &publishRecorder{published: make(chan *messaging.Message, 1)}
+
+	// Two replicas of the service; only the first consumes the dead letters
+	store := NewMemoryDeadLetterStore(10)
+	first := NewDeadLetterService(client, store, zap.NewNop())
+	second := NewDeadLetterService(client, store, zap.NewNop())
+
+	for _, msg := range []*messaging.Message{deadLetter("1", "orders"), deadLetter("2", "payments")} {
+		if err := first.Record(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	letters, err := second.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 2 || letters[0].ID != "1" || letters[1].ID != "2" {
+		t.Fatalf("List() = %v, want letters 1 and 2", letters)
+	}
+	if letters, _ := second.List(ctx, "orders"); len(letters) != 1 || letters[0].ID != "1" {
+		t.Fatalf("List(orders) = %v, want letter 1", letters)
+	}
+
+	if _, err := second.Replay(ctx, "1"); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	select {
+	case msg := <-client.published:
+		if msg.Topic != "orders" || msg.Key != "key-1" || msg.Headers[messaging.HeaderDeadLetterID] != "" {
+			t.Errorf("replayed message = topic %s key %q headers %v, want orders and key-1 without retry headers", msg.Topic, msg.Key, msg.Headers)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("replayed message not published")
+	}
+	if _, err := first.Get(ctx, "1"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Get() after replay error = %v, want ErrDeadLetterNotFound", err)
+	}
+
+	if err := first.Discard(ctx, "2"); err != nil {
+		t.Fatalf("Discard() error = %v", err)
+	}
+	if err := second.Discard(ctx, "2"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("second Discard() error = %v, want ErrDeadLetterNotFound", err)
+	}
+}
+
+func TestMemoryDeadLetterStoreDropsOldest(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDeadLetterStore(2)
+	for _, id := range []string{"1", "2", "3"} {
+		if err := store.Add(ctx, &DeadLetter{ID: id}, deadLetter(id, "orders")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	letters, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 2 || letters[0].ID != "2" || letters[1].ID != "3" {
+		t.Errorf("List() = %v, want letters 2 and 3", letters)
+	}
+}
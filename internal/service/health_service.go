@@ -7,32 +7,30 @@ import (
 
 	"go-microservice/internal/config"
 	database "go-microservice/pkg/cache"
-	"go-microservice/pkg/kafka"
+	"go-microservice/pkg/messaging"
 )
 
 // HealthService handles health checks for the service
 type HealthService struct {
-	config        *config.Config
-	redisClient   *database.RedisClient
-	kafkaProducer *kafka.Producer
-	kafkaConsumer *kafka.Consumer
-	mu            sync.RWMutex
-	ready         bool
+	config      *config.Config
+	redisClient *database.RedisClient
+	messaging   messaging.Messaging
+	mu          sync.RWMutex
+	ready       bool
 }
 
-// NewHealthService creates a new health service
+// NewHealthService creates a new health service. The messaging client may be
+// nil when the broker could not be reached at startup.
 func NewHealthService(
 	cfg *config.Config,
 	redisClient *database.RedisClient,
-	kafkaProducer *kafka.Producer,
-	kafkaConsumer *kafka.Consumer,
+	messagingClient messaging.Messaging,
 ) *HealthService {
 	return &HealthService{
-		config:        cfg,
-		redisClient:   redisClient,
-		kafkaProducer: kafkaProducer,
-		kafkaConsumer: kafkaConsumer,
-		ready:         false,
+		config:      cfg,
+		redisClient: redisClient,
+		messaging:   messagingClient,
+		ready:       false,
 	}
 }
 
@@ -76,18 +74,11 @@ func (s *HealthService) CheckReadiness() map[string]string {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		if err := s.kafkaProducer.HealthCheck(ctx); err != nil {
+		if s.messaging == nil || s.messaging.HealthCheck(ctx) != nil {
 			status["status"] = "error"
-			status["kafka_producer"] = "unhealthy"
+			status["kafka"] = "unhealthy"
 		} else {
-			status["kafka_producer"] = "healthy"
-		}
-
-		if err := s.kafkaConsumer.HealthCheck(ctx); err != nil {
-			status["status"] = "error"
-			status["kafka_consumer"] = "unhealthy"
-		} else {
-			status["kafka_consumer"] = "healthy"
+			status["kafka"] = "healthy"
 		}
 	}
 
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-microservice/internal/config"
+	"go-microservice/internal/models"
+	"go-microservice/internal/repository"
+	"go-microservice/pkg/messaging"
+	"go-microservice/pkg/upload"
+	"go.uber.org/zap"
+)
+
+// Lifecycle actions applied to files
+const (
+	LifecycleActionDelete     = "delete"
+	LifecycleActionTransition = "transition"
+)
+
+// LifecycleDecision describes an action taken, or planned, for a single file
+type LifecycleDecision struct {
+	FileID  string `json:"file_id"`
+	Path    string `json:"path"`
+	Policy  string `json:"policy"`
+	Action  string `json:"action"`
+	AgeDays int    `json:"age_days"`
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// LifecycleReport summarizes a single evaluation run
+type LifecycleReport struct {
+	DryRun      bool                `json:"dry_run"`
+	EvaluatedAt time.Time           `json:"evaluated_at"`
+	Scanned     int                 `json:"scanned"`
+	Exempt      int                 `json:"exempt"`
+	Decisions   []LifecycleDecision `json:"decisions"`
+}
+
+// LifecycleAuditEvent is emitted for every lifecycle action that is applied
+type LifecycleAuditEvent struct {
+	Type      string    `json:"type"`
+	FileID    string    `json:"file_id"`
+	UserID    string    `json:"user_id"`
+	Path      string    `json:"path"`
+	Policy    string    `json:"policy"`
+	Action    string    `json:"action"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// LifecycleService evaluates retention and tiering policies against stored files
+type LifecycleService struct {
+	config    config.LifecycleConfig
+	files     repository.FileRepository
+	primary   upload.Uploader
+	cold      upload.Uploader
+	quotas    *QuotaService
+	messaging messaging.Messaging
+	logger    *zap.Logger
+}
+
+// NewLifecycleService creates a new lifecycle service. The cold uploader, quota
+// service and messaging client are optional; without them transitions, usage
+// accounting and audit publishing are skipped.
+func NewLifecycleService(
+	cfg config.LifecycleConfig,
+	files repository.FileRepository,
+	primary upload.Uploader,
+	cold upload.Uploader,
+	quotas *QuotaService,
+	messagingClient messaging.Messaging,
+	logger *zap.Logger,
+) *LifecycleService {
+	return &LifecycleService{
+		config:    cfg,
+		files:     files,
+		primary:   primary,
+		cold:      cold,
+		quotas:    quotas,
+		messaging: messagingClient,
+		logger:    logger,
+	}
+}
+
+// Start runs the lifecycle scheduler until the context is cancelled
+func (s *LifecycleService) Start(ctx context.Context) {
+	interval := s.config.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Lifecycle scheduler started",
+		zap.Duration("interval", interval),
+		zap.Int("policies", len(s.config.Policies)))
+
+	for {
+		select {
+		case <-ticker.C:
+			report, err := s.Run(ctx, false)
+			if err != nil {
+				s.logger.Error("Lifecycle run failed", zap.Error(err))
+				continue
+			}
+			s.logger.Info("Lifecycle run completed",
+				zap.Int("scanned", report.Scanned),
+				zap.Int("actions", len(report.Decisions)))
+		case <-ctx.Done():
+			s.logger.Info("Lifecycle scheduler stopped")
+			return
+		}
+	}
+}
+
+// Run evaluates all policies. With dryRun set, decisions are reported but not applied.
+func (s *LifecycleService) Run(ctx context.Context, dryRun bool) (*LifecycleReport, error) {
+	files, err := s.files.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	now := time.Now()
+	report := &LifecycleReport{
+		DryRun:      dryRun,
+		EvaluatedAt: now,
+		Scanned:     len(files),
+		Decisions:   []LifecycleDecision{},
+	}
+
+	for _, file := range files {
+		if s.isExempt(file) {
+			report.Exempt++
+			continue
+		}
+
+		decision, ok := s.evaluate(file, now)
+		if !ok {
+			continue
+		}
+
+		if !dryRun {
+			if err := s.apply(ctx, file, decision.Action); err != nil {
+				decision.Error = err.Error()
+			} else {
+				decision.Applied = true
+			}
+			s.audit(ctx, file, decision)
+		}
+
+		report.Decisions = append(report.Decisions, decision)
+	}
+
+	return report, nil
+}
+
+// isExempt reports whether a file is under legal hold
+func (s *LifecycleService) isExempt(file *models.File) bool {
+	if file.LegalHold {
+		return true
+	}
+	return hasAnyTag(file.Tags, s.config.LegalHoldTags)
+}
+
+// evaluate finds the first matching policy and decides what to do with the file
+func (s *LifecycleService) evaluate(file *models.File, now time.Time) (LifecycleDecision, bool) {
+	ageDays := int(now.Sub(file.CreatedAt).Hours() / 24)
+
+	for _, policy := range s.config.Policies {
+		if !policyMatches(policy, file) {
+			continue
+		}
+
+		decision := LifecycleDecision{
+			FileID:  file.ID,
+			Path:    file.Path,
+			Policy:  policy.Name,
+			AgeDays: ageDays,
+		}
+
+		switch {
+		case policy.DeleteAfterDays > 0 && ageDays >= policy.DeleteAfterDays:
+			decision.Action = LifecycleActionDelete
+		case policy.TransitionAfterDays > 0 && ageDays >= policy.TransitionAfterDays &&
+			file.StorageClass != models.StorageClassCold && s.cold != nil:
+			decision.Action = LifecycleActionTransition
+		default:
+			return LifecycleDecision{}, false
+		}
+		return decision, true
+	}
+
+	return LifecycleDecision{}, false
+}
+
+// apply performs a lifecycle action against storage and the file record
+func (s *LifecycleService) apply(ctx context.Context, file *models.File, action string) error {
+	switch action {
+	case LifecycleActionDelete:
+		if err := s.storageFor(file).Delete(ctx, file.Path); err != nil {
+			return err
+		}
+		if err := s.files.Delete(ctx, file.ID); err != nil {
+			return err
+		}
+		if s.quotas != nil {
+			return s.quotas.Release(ctx, file)
+		}
+		return nil
+
+	case LifecycleActionTransition:
+		if err := s.transition(ctx, file); err != nil {
+			return err
+		}
+		file.StorageClass = models.StorageClassCold
+		file.UpdatedAt = time.Now()
+		return s.files.Update(ctx, file)
+
+	default:
+		return fmt.Errorf("unknown lifecycle action %q", action)
+	}
+}
+
+// transition copies a file to the cold backend and removes it from the primary one
+func (s *LifecycleService) transition(ctx context.Context, file *models.File) error {
+	reader, err := s.primary.Download(ctx, file.Path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if _, err := s.cold.Upload(ctx, file.Path, reader, file.ContentType); err != nil {
+		return err
+	}
+
+	return s.primary.Delete(ctx, file.Path)
+}
+
+// storageFor returns the uploader that currently holds the file
+func (s *LifecycleService) storageFor(file *models.File) upload.Uploader {
+	if file.StorageClass == models.StorageClassCold && s.cold != nil {
+		return s.cold
+	}
+	return s.primary
+}
+
+// audit logs the outcome of a lifecycle action and publishes it if configured
+func (s *LifecycleService) audit(ctx context.Context, file *models.File, decision LifecycleDecision) {
+	event := LifecycleAuditEvent{
+		Type:      "storage.lifecycle." + decision.Action,
+		FileID:    file.ID,
+		UserID:    file.UserID,
+		Path:      file.Path,
+		Policy:    decision.Policy,
+		Action:    decision.Action,
+		Success:   decision.Applied,
+		Error:     decision.Error,
+		Timestamp: time.Now(),
+	}
+
+	s.logger.Info("Lifecycle action",
+		zap.String("file_id", event.FileID),
+		zap.String("path", event.Path),
+		zap.String("policy", event.Policy),
+		zap.String("action", event.Action),
+		zap.Bool("success", event.Success),
+		zap.String("error", event.Error))
+
+	if s.messaging == nil || s.config.AuditTopic == "" {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Error("Failed to marshal audit event", zap.Error(err))
+		return
+	}
+
+	if err := s.messaging.Publish(ctx, &messaging.Message{
+		Topic:   s.config.AuditTopic,
+		Payload: payload,
+	}); err != nil {
+		s.logger.Error("Failed to publish audit event",
+			zap.String("topic", s.config.AuditTopic),
+			zap.Error(err))
+	}
+}
+
+// policyMatches reports whether a policy applies to a file. A policy matches
+// when the path starts with its prefix and, if tags are set, the file carries
+// at least one of them.
+func policyMatches(policy config.LifecyclePolicy, file *models.File) bool {
+	if policy.Prefix != "" && !strings.HasPrefix(file.Path, policy.Prefix) {
+		return false
+	}
+	if len(policy.Tags) > 0 && !hasAnyTag(file.Tags, policy.Tags) {
+		return false
+	}
+	return true
+}
+
+// hasAnyTag reports whether tags contains any of the wanted tags
+func hasAnyTag(tags, wanted []string) bool {
+	for _, t := range tags {
+		for _, w := range wanted {
+			if t == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"go-microservice/pkg/messaging"
+)
+
+// TopicStatus describes a consumed topic on the broker and on this instance
+type TopicStatus struct {
+	messaging.TopicInfo
+	Logical    string `json:"logical,omitempty"`
+	Subscribed bool   `json:"subscribed"`
+	Paused     bool   `json:"paused"`
+}
+
+// DescribeTopics reports every consumed topic with its partition count and
+// whether this instance has paused it. Routed topics carry their logical name.
+func (s *MessagingService) DescribeTopics(ctx context.Context) ([]TopicStatus, error) {
+	admin, err := messaging.AdminOf(s.kafkaClient)
+	if err != nil {
+		return nil, err
+	}
+
+	topics, _ := s.consumedTopics()
+	infos, err := admin.DescribeTopics(ctx, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	logical := make(map[string]string, len(s.config.Kafka.Topics))
+	for name, topic := range s.config.Kafka.Topics {
+		logical[topic] = name
+	}
+
+	subscriptions := make(map[string]messaging.SubscriptionInfo)
+	if controller, err := messaging.SubscriptionControllerOf(s.kafkaClient); err == nil {
+		for _, sub := range controller.Subscriptions() {
+			subscriptions[sub.Topic] = sub
+		}
+	}
+
+	statuses := make([]TopicStatus, 0, len(infos))
+	for _, info := range infos {
+		sub, subscribed := subscriptions[info.Name]
+		statuses = append(statuses, TopicStatus{
+			TopicInfo:  info,
+			Logical:    logical[info.Name],
+			Subscribed: subscribed,
+			Paused:     sub.Paused,
+		})
+	}
+	return statuses, nil
+}
+
+// Lag reports how far a consumer group is behind on the consumed topics
+// that exist. An empty group means the service's own group.
+func (s *MessagingService) Lag(ctx context.Context, group string) (*messaging.GroupLag, error) {
+	admin, err := messaging.AdminOf(s.kafkaClient)
+	if err != nil {
+		return nil, err
+	}
+
+	topics, err := s.existingTopics(ctx, admin)
+	if err != nil {
+		return nil, err
+	}
+	return admin.Lag(ctx, group, topics)
+}
+
+// ResetOffsets moves a consumer group on topics for replay. Without topics
+// every consumed topic that exists is reset; an empty group means the
+// service's own group.
+func (s *MessagingService) ResetOffsets(ctx context.Context, group string, topics []string, reset messaging.OffsetReset) error {
+	admin, err := messaging.AdminOf(s.kafkaClient)
+	if err != nil {
+		return err
+	}
+
+	if len(topics) == 0 {
+		topics, err = s.existingTopics(ctx, admin)
+		if err != nil {
+			return err
+		}
+	}
+	return admin.ResetOffsets(ctx, group, topics, reset)
+}
+
+// Subscriptions lists the topics this instance consumes
+func (s *MessagingService) Subscriptions() ([]messaging.SubscriptionInfo, error) {
+	controller, err := messaging.SubscriptionControllerOf(s.kafkaClient)
+	if err != nil {
+		return nil, err
+	}
+	return controller.Subscriptions(), nil
+}
+
+// PauseTopic stops consuming a topic on this instance
+func (s *MessagingService) PauseTopic(topic string) error {
+	controller, err := messaging.SubscriptionControllerOf(s.kafkaClient)
+	if err != nil {
+		return err
+	}
+	return controller.Pause(topic)
+}
+
+// ResumeTopic continues consuming a paused topic on this instance
+func (s *MessagingService) ResumeTopic(topic string) error {
+	controller, err := messaging.SubscriptionControllerOf(s.kafkaClient)
+	if err != nil {
+		return err
+	}
+	return controller.Resume(topic)
+}
+
+// existingTopics returns the consumed topics that exist on the broker.
+// Retry and dead-letter topics are often created on first use.
+func (s *MessagingService) existingTopics(ctx context.Context, admin messaging.Admin) ([]string, error) {
+	topics, _ := s.consumedTopics()
+	infos, err := admin.DescribeTopics(ctx, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := slices.DeleteFunc(topics, func(topic string) bool {
+		return !slices.ContainsFunc(infos, func(info messaging.TopicInfo) bool {
+			return info.Name == topic && info.Exists
+		})
+	})
+	return existing, nil
+}
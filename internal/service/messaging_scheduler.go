@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/messaging"
+)
+
+// ErrSchedulingDisabled is returned when no scheduler is configured
+var ErrSchedulingDisabled = apperror.New(apperror.CodeNotImplemented, "message scheduling is not enabled")
+
+// ScheduledMessages lists pending scheduled messages with the dispatcher
+// state of this instance
+type ScheduledMessages struct {
+	Total     int                           `json:"total"`
+	Scheduled []*messaging.ScheduledMessage `json:"scheduled"`
+	Stats     messaging.SchedulerStats      `json:"stats"`
+}
+
+// UseScheduler enables scheduled publishing through scheduler
+func (s *MessagingService) UseScheduler(scheduler *messaging.Scheduler) {
+	s.scheduler = scheduler
+}
+
+// ScheduleMessage wraps a payload in an event envelope and publishes it at
+// the given time. The envelope is validated now, not at delivery.
+func (s *MessagingService) ScheduleMessage(ctx context.Context, event OutgoingMessage, at time.Time) (*messaging.ScheduledMessage, error) {
+	if s.scheduler == nil {
+		return nil, ErrSchedulingDisabled
+	}
+
+	msg, err := s.encode(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return s.scheduler.PublishAt(ctx, msg, at)
+}
+
+// ScheduledMessages returns up to limit pending scheduled messages, earliest first
+func (s *MessagingService) ScheduledMessages(ctx context.Context, limit int) (*ScheduledMessages, error) {
+	if s.scheduler == nil {
+		return nil, ErrSchedulingDisabled
+	}
+
+	scheduled, total, err := s.scheduler.Pending(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &ScheduledMessages{
+		Total:     total,
+		Scheduled: scheduled,
+		Stats:     s.scheduler.Stats(),
+	}, nil
+}
+
+// ScheduledMessage returns a pending scheduled message
+func (s *MessagingService) ScheduledMessage(ctx context.Context, id string) (*messaging.ScheduledMessage, error) {
+	if s.scheduler == nil {
+		return nil, ErrSchedulingDisabled
+	}
+	return s.scheduler.Get(ctx, id)
+}
+
+// CancelScheduled cancels a pending scheduled message by its message ID
+func (s *MessagingService) CancelScheduled(ctx context.Context, id string) error {
+	if s.scheduler == nil {
+		return ErrSchedulingDisabled
+	}
+	return s.scheduler.Cancel(ctx, id)
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 
 	"go-microservice/internal/config"
+	"go-microservice/internal/models"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/logger"
 	"go-microservice/pkg/messaging"
 	"go.uber.org/zap"
 )
@@ -19,91 +22,221 @@ type Message struct {
 // MessagingService handles Kafka operations
 type MessagingService struct {
 	kafkaClient messaging.Messaging
+	router      *messaging.Router
+	metrics     *messaging.HandlerMetrics
+	codec       *messaging.EventCodec
+	retrier     *messaging.Retrier
+	dedup       *messaging.Deduplicator
+	deadLetters *DeadLetterService
+	scheduler   *messaging.Scheduler
 	logger      *zap.Logger
 	config      *config.Config
 }
 
-// NewMessagingService creates a new messaging service
-func NewMessagingService(kafkaClient messaging.Messaging, logger *zap.Logger, cfg *config.Config) *MessagingService {
-	return &MessagingService{
+// NewMessagingService creates a new messaging service. The dedup store is
+// optional; without it handlers may see redelivered messages twice. Messages
+// read from dead-letter topics are kept in deadLetters for inspection.
+func NewMessagingService(kafkaClient messaging.Messaging, codec *messaging.EventCodec, dedup messaging.DedupStore, deadLetters DeadLetterStore, logger *zap.Logger, cfg *config.Config) *MessagingService {
+	s := &MessagingService{
 		kafkaClient: kafkaClient,
+		router:      messaging.NewRouter(cfg.Kafka.Topics),
+		metrics:     messaging.NewHandlerMetrics(),
+		codec:       codec,
+		retrier:     messaging.NewRetrier(kafkaClient, cfg.Kafka.Retry),
+		deadLetters: NewDeadLetterService(kafkaClient, deadLetters, logger),
 		logger:      logger,
 		config:      cfg,
 	}
+	if dedup != nil {
+		s.dedup = messaging.NewDeduplicator(dedup, cfg.Kafka.ConsumerGroup, cfg.Kafka.Dedup)
+	}
+
+	// Recovery sits inside logging and metrics so panics are counted as failures.
+	// Events are unwrapped last so handlers receive the event data.
+	s.router.Use(
+		messaging.Logging(),
+		s.metrics.Middleware(),
+		messaging.Recovery(),
+		messaging.Timeout(cfg.Kafka.HandlerTimeout),
+		codec.Middleware(),
+	)
+	s.registerHandlers()
+	return s
+}
+
+// registerHandlers registers a handler for every logical topic in the config
+func (s *MessagingService) registerHandlers() {
+	messaging.Register(s.router, "file_upload", s.handleFileUpload)
+	messaging.Register(s.router, "file_download", s.handleFileDownload)
+	messaging.Register(s.router, "file_delete", s.handleFileDelete)
+}
+
+// DeadLetters returns the service holding messages that exhausted their retries
+func (s *MessagingService) DeadLetters() *DeadLetterService {
+	return s.deadLetters
 }
 
-// StartConsumers starts Kafka consumers for all configured topics
+// Topics returns the configured topics keyed by logical name
+func (s *MessagingService) Topics() map[string]string {
+	return s.config.Kafka.Topics
+}
+
+// HandlerMetrics returns per-topic handler statistics
+func (s *MessagingService) HandlerMetrics() []messaging.TopicMetrics {
+	return s.metrics.Snapshot()
+}
+
+// StartConsumers starts Kafka consumers for all configured topics. It fails
+// if a configured topic has no handler. Each topic is consumed together with
+// its retry topics and its dead-letter topic in a single subscription so they
+// share one consumer group session.
 func (s *MessagingService) StartConsumers(ctx context.Context) error {
-	// Subscribe to all configured topics
-	for _, topic := range s.config.Kafka.Topics {
-		err := s.kafkaClient.Subscribe(ctx, topic, func(ctx context.Context, msg *messaging.Message) error {
-			s.logger.Info("Received message",
-				zap.String("topic", msg.Topic),
-				zap.String("payload", string(msg.Payload)))
-
-			// Process message based on topic
-			switch msg.Topic {
-			case "topic1":
-				return s.handleTopic1(msg)
-			case "topic2":
-				return s.handleTopic2(msg)
-			case "topic3":
-				return s.handleTopic3(msg)
-			default:
-				s.logger.Warn("Unknown topic",
-					zap.String("topic", msg.Topic))
-				return nil
-			}
-		})
+	if err := s.router.Validate(); err != nil {
+		return fmt.Errorf("invalid topic routes: %w", err)
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to subscribe to topic %s: %w", topic, err)
+	topics, deadLetterTopics := s.consumedTopics()
+	if len(topics) == 0 {
+		return nil
+	}
+
+	// Deduplication runs inside the retrier so a failed attempt releases its lock
+	handler := s.router.Dispatch
+	if s.dedup != nil {
+		handler = s.dedup.Wrap(handler)
+	}
+	process := s.retrier.Wrap(handler)
+
+	// Handlers log through the messaging logger, see logger.FromContext
+	ctx = logger.NewContext(ctx, s.logger)
+	err := s.kafkaClient.SubscribeMultiple(ctx, topics, func(ctx context.Context, msg *messaging.Message) error {
+		if deadLetterTopics[msg.Topic] {
+			return s.deadLetters.Record(ctx, msg)
 		}
+		return process(ctx, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to topics: %w", err)
 	}
 
 	return nil
 }
 
-// PublishMessage publishes a message to a Kafka topic
-func (s *MessagingService) PublishMessage(ctx context.Context, topic string, payload interface{}) error {
-	// Convert payload to JSON
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+// consumedTopics returns every topic the consumers subscribe to: the routed
+// topics with their retry topics, and the dead-letter topics, which are
+// also returned as a set
+func (s *MessagingService) consumedTopics() ([]string, map[string]bool) {
+	var topics []string
+	deadLetterTopics := make(map[string]bool)
+	for _, topic := range s.router.Topics() {
+		topics = append(topics, s.retrier.Topics(topic)...)
+
+		deadLetterTopic := s.retrier.DeadLetterTopic(topic)
+		topics = append(topics, deadLetterTopic)
+		deadLetterTopics[deadLetterTopic] = true
 	}
+	return topics, deadLetterTopics
+}
+
+// OutgoingMessage is an event to publish. Events with the same key are
+// consumed in order.
+type OutgoingMessage struct {
+	Topic   string
+	Key     string
+	Type    string
+	Payload interface{}
+}
 
-	// Create message
-	msg := &messaging.Message{
-		Topic:   topic,
-		Payload: jsonPayload,
+// PublishMessage wraps a payload in an event envelope of the given type and
+// publishes it to a Kafka topic. The payload is validated against the
+// event type's schema when publish validation is enabled. It returns the
+// published message with its partition and offset where the backend reports
+// them.
+func (s *MessagingService) PublishMessage(ctx context.Context, event OutgoingMessage) (*messaging.Message, error) {
+	msg, err := s.encode(ctx, event)
+	if err != nil {
+		return nil, err
 	}
 
 	// Publish message
 	if err := s.kafkaClient.Publish(ctx, msg); err != nil {
-		return fmt.Errorf("failed to publish message: %w", err)
+		return nil, apperror.Wrap(err, apperror.CodeUpstream, "failed to publish message")
 	}
 
-	return nil
+	return msg, nil
+}
+
+// PublishBatch encodes the events and publishes them in one batch. It
+// returns the published message and the error of each event; events that
+// failed to encode have no message.
+func (s *MessagingService) PublishBatch(ctx context.Context, events []OutgoingMessage) ([]*messaging.Message, []error) {
+	msgs := make([]*messaging.Message, len(events))
+	results := make([]error, len(events))
+	batch := make([]*messaging.Message, 0, len(events))
+	indexes := make([]int, 0, len(events))
+
+	// Encode every event; events that fail are not published
+	for i, event := range events {
+		msg, err := s.encode(ctx, event)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+		msgs[i] = msg
+		batch = append(batch, msg)
+		indexes = append(indexes, i)
+	}
+	if len(batch) == 0 {
+		return msgs, results
+	}
+
+	// Map the batch results back to the events
+	for j, err := range messaging.BatchResults(s.kafkaClient.PublishBatch(ctx, batch), len(batch)) {
+		if err != nil {
+			results[indexes[j]] = apperror.Wrap(err, apperror.CodeUpstream, "failed to publish message")
+		}
+	}
+	return msgs, results
+}
+
+// encode wraps an event's payload in an event envelope
+func (s *MessagingService) encode(ctx context.Context, event OutgoingMessage) (*messaging.Message, error) {
+	// Convert payload to JSON
+	jsonPayload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	// Wrap payload in an event envelope
+	msg, err := s.codec.Encode(ctx, event.Topic, s.codec.NewEvent(event.Type, jsonPayload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	msg.Key = event.Key
+	return msg, nil
 }
 
 // Topic-specific handlers
-func (s *MessagingService) handleTopic1(msg *messaging.Message) error {
-	s.logger.Info("Processing topic1 message",
-		zap.String("payload", string(msg.Payload)))
-	// Add your topic1 specific logic here
+func (s *MessagingService) handleFileUpload(ctx context.Context, file models.File) error {
+	logger.FromContext(ctx).Info("Processing file upload message",
+		zap.String("file_id", file.ID),
+		zap.String("user_id", file.UserID))
+	// Add your file upload specific logic here
 	return nil
 }
 
-func (s *MessagingService) handleTopic2(msg *messaging.Message) error {
-	s.logger.Info("Processing topic2 message",
-		zap.String("payload", string(msg.Payload)))
-	// Add your topic2 specific logic here
+func (s *MessagingService) handleFileDownload(ctx context.Context, file models.File) error {
+	logger.FromContext(ctx).Info("Processing file download message",
+		zap.String("file_id", file.ID),
+		zap.String("user_id", file.UserID))
+	// Add your file download specific logic here
 	return nil
 }
 
-func (s *MessagingService) handleTopic3(msg *messaging.Message) error {
-	s.logger.Info("Processing topic3 message",
-		zap.String("payload", string(msg.Payload)))
-	// Add your topic3 specific logic here
+func (s *MessagingService) handleFileDelete(ctx context.Context, file models.File) error {
+	logger.FromContext(ctx).Info("Processing file delete message",
+		zap.String("file_id", file.ID),
+		zap.String("user_id", file.UserID))
+	// Add your file delete specific logic here
 	return nil
-} 
\ No newline at end of file
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-microservice/internal/config"
+	"go-microservice/internal/models"
+	"go-microservice/internal/repository"
+	"go-microservice/pkg/messaging"
+)
+
+// Headers added to messages published from the outbox
+const (
+	HeaderOutboxID      = "x-outbox-id"
+	HeaderEventType     = "x-event-type"
+	HeaderAggregateType = "x-aggregate-type"
+	HeaderAggregateID   = "x-aggregate-id"
+)
+
+// Default relay settings used when the config leaves them empty
+const (
+	defaultOutboxPollInterval    = time.Second
+	defaultOutboxBatchSize       = 100
+	defaultOutboxRetention       = 24 * time.Hour
+	defaultOutboxCleanupInterval = 10 * time.Minute
+)
+
+// OutboxRelayStats reports the progress of the outbox relay
+type OutboxRelayStats struct {
+	models.OutboxStats
+	LagSeconds       float64    `json:"lag_seconds"`
+	LastLagSeconds   float64    `json:"last_lag_seconds"`
+	Published        uint64     `json:"published"`
+	Failed           uint64     `json:"failed"`
+	Cleaned          uint64     `json:"cleaned"`
+	LastRelayAt      *time.Time `json:"last_relay_at,omitempty"`
+	LastPublishError string     `json:"last_publish_error,omitempty"`
+}
+
+// OutboxRelay publishes outbox events that were committed together with data
+// changes. Delivery is at least once: an event is marked delivered only after
+// Publish succeeds, so a crash in between publishes it again. Events of the
+// same aggregate are published in the order they were recorded; when one
+// fails, later events of that aggregate wait for the next run.
+type OutboxRelay struct {
+	config config.OutboxConfig
+	outbox repository.OutboxRepository
+	client messaging.Messaging
+	logger *zap.Logger
+
+	mu    sync.Mutex
+	stats OutboxRelayStats
+}
+
+// NewOutboxRelay creates a relay publishing events from outbox through client
+func NewOutboxRelay(cfg config.OutboxConfig, outbox repository.OutboxRepository, client messaging.Messaging, logger *zap.Logger) *OutboxRelay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultOutboxPollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultOutboxBatchSize
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = defaultOutboxRetention
+	}
+	if cfg.CleanupInterval <= 0 {
+		cfg.CleanupInterval = defaultOutboxCleanupInterval
+	}
+
+	return &OutboxRelay{
+		config: cfg,
+		outbox: outbox,
+		client: client,
+		logger: logger,
+	}
+}
+
+// Start relays events until the context is cancelled
+func (r *OutboxRelay) Start(ctx context.Context) {
+	poll := time.NewTicker(r.config.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(r.config.CleanupInterval)
+	defer cleanup.Stop()
+
+	r.logger.Info("Outbox relay started",
+		zap.Duration("poll_interval", r.config.PollInterval),
+		zap.Int("batch_size", r.config.BatchSize))
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay stopped")
+			return
+		case <-poll.C:
+			if _, err := r.Relay(ctx); err != nil {
+				r.logger.Error("Outbox relay failed", zap.Error(err))
+			}
+		case <-cleanup.C:
+			if _, err := r.Cleanup(ctx); err != nil {
+				r.logger.Error("Outbox cleanup failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Relay publishes one batch of pending events and returns how many were delivered
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	pending, err := r.outbox.Pending(ctx, r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	blocked := make(map[string]bool)
+	for _, event := range pending {
+		// Keep per-aggregate order: skip events queued behind a failed one
+		key := event.AggregateType + ":" + event.AggregateID
+		if blocked[key] {
+			continue
+		}
+
+		if err := r.client.Publish(ctx, r.message(event)); err != nil {
+			blocked[key] = true
+			r.recordFailure(ctx, event, err)
+			continue
+		}
+
+		now := time.Now()
+		if err := r.outbox.MarkDelivered(ctx, event.ID, now); err != nil {
+			// The event is published again on the next run
+			return delivered, err
+		}
+		delivered++
+		r.recordDelivery(now.Sub(event.CreatedAt))
+	}
+
+	r.mu.Lock()
+	now := time.Now().UTC()
+	r.stats.LastRelayAt = &now
+	r.mu.Unlock()
+
+	return delivered, nil
+}
+
+// Cleanup removes events delivered longer ago than the retention period
+func (r *OutboxRelay) Cleanup(ctx context.Context) (int, error) {
+	removed, err := r.outbox.DeleteDelivered(ctx, time.Now().Add(-r.config.Retention))
+	if err != nil {
+		return 0, err
+	}
+
+	if removed > 0 {
+		r.mu.Lock()
+		r.stats.Cleaned += uint64(removed)
+		r.mu.Unlock()
+
+		r.logger.Debug("Delivered outbox events removed", zap.Int("count", removed))
+	}
+	return removed, nil
+}
+
+// Stats returns relay counters together with the current outbox backlog. Lag
+// is the age of the oldest event that has not been delivered yet.
+func (r *OutboxRelay) Stats(ctx context.Context) (OutboxRelayStats, error) {
+	backlog, err := r.outbox.Stats(ctx)
+	if err != nil {
+		return OutboxRelayStats{}, err
+	}
+
+	r.mu.Lock()
+	stats := r.stats
+	r.mu.Unlock()
+
+	stats.OutboxStats = backlog
+	if backlog.OldestPending != nil {
+		stats.LagSeconds = time.Since(*backlog.OldestPending).Seconds()
+	}
+	return stats, nil
+}
+
+// message converts an outbox event into a message for its aggregate's topic
+func (r *OutboxRelay) message(event *models.OutboxMessage) *messaging.Message {
+	// The outbox ID doubles as message ID so consumers can drop republished events
+	headers := make(map[string]string, len(event.Headers)+6)
+	for k, v := range event.Headers {
+		headers[k] = v
+	}
+	headers[HeaderOutboxID] = event.ID
+	headers[messaging.HeaderMessageID] = event.ID
+	headers[HeaderEventType] = event.EventType
+	headers[HeaderAggregateType] = event.AggregateType
+	headers[HeaderAggregateID] = event.AggregateID
+
+	topic, ok := r.config.Topics[event.AggregateType]
+	if !ok {
+		topic = event.AggregateType + "-events"
+	}
+
+	// Consumers keep the events of one aggregate in order
+	return &messaging.Message{
+		Topic:   topic,
+		Key:     event.AggregateType + ":" + event.AggregateID,
+		Payload: event.Payload,
+		Headers: headers,
+	}
+}
+
+// recordDelivery updates the relay counters after a successful publish
+func (r *OutboxRelay) recordDelivery(lag time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Published++
+	r.stats.LastLagSeconds = lag.Seconds()
+}
+
+// recordFailure stores a failed attempt on the event and in the relay counters
+func (r *OutboxRelay) recordFailure(ctx context.Context, event *models.OutboxMessage, cause error) {
+	r.logger.Warn("Failed to publish outbox event",
+		zap.String("id", event.ID),
+		zap.String("event_type", event.EventType),
+		zap.String("aggregate_id", event.AggregateID),
+		zap.Int("attempts", event.Attempts+1),
+		zap.Error(cause))
+
+	if err := r.outbox.MarkFailed(ctx, event.ID, cause); err != nil {
+		r.logger.Error("Failed to record outbox publish failure", zap.Error(err))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.Failed++
+	r.stats.LastPublishError = cause.Error()
+}
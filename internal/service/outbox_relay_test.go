@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-microservice/internal/config"
+	"go-microservice/internal/models"
+	"go-microservice/internal/repository"
+	"go-microservice/pkg/messaging"
+)
+
+// flakyPublisher records published messages and fails the publishes of an
+// outbox event as many times as failures holds for its ID. Only Publish is
+// implemented.
+type flakyPublisher struct {
+	messaging.Messaging
+
+	mu        sync.Mutex
+	failures  map[string]int
+	published []*messaging.Message
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, msg *messaging.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := msg.Headers[HeaderOutboxID]
+	if p.failures[id] > 0 {
+		p.failures[id]--
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, msg)
+	return nil
+}
+
+// ids returns the outbox IDs of the published messages in publishing order
+func (p *flakyPublisher) ids() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ids := make([]string, len(p.published))
+	for i, msg := range p.published {
+		ids[i] = msg.Headers[HeaderOutboxID]
+	}
+	return ids
+}
+
+// flakyOutbox is an in-memory outbox failing to mark the events in
+// markFailures as delivered once
+type flakyOutbox struct {
+	*repository.MemoryOutboxRepository
+	markFailures map[string]bool
+}
+
+func (o *flakyOutbox) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	if o.markFailures[id] {
+		delete(o.markFailures, id)
+		return errors.New("connection reset")
+	}
+	return o.MemoryOutboxRepository.MarkDelivered(ctx, id, at)
+}
+
+// newFlakyOutbox returns an empty outbox that marks every event delivered
+func newFlakyOutbox() *flakyOutbox {
+	return &flakyOutbox{MemoryOutboxRepository: repository.NewMemoryOutboxRepository(), markFailures: map[string]bool{}}
+}
+
+// newRelay returns a relay over an outbox holding one event per ID, in that
+// order, for the "type:id" aggregates in aggregateOf
+func newRelay(t *testing.T, publisher *flakyPublisher, outbox *flakyOutbox, ids []string, aggregateOf map[string]string) *OutboxRelay {
+	t.Helper()
+
+	for _, id := range ids {
+		aggregateType, aggregateID, _ := strings.Cut(aggregateOf[id], ":")
+		if err := outbox.Add(t.Context(), &models.OutboxMessage{
+			ID:            id,
+			AggregateType: aggregateType,
+			AggregateID:   aggregateID,
+			EventType:     aggregateType + ".changed",
+			Payload:       []byte(`{"id":"` + id + `"}`),
+			CreatedAt:     time.Now(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewOutboxRelay(config.OutboxConfig{Topics: map[string]string{"order": "orders"}}, outbox, publisher, zap.NewNop())
+}
+
+func TestOutboxRelayPublishesEventsInOrder(t *testing.T) {
+	publisher := &flakyPublisher{}
+	outbox := newFlakyOutbox()
+	relay := newRelay(t, publisher, outbox, []string{"o1", "c1", "o2", "o3"}, map[string]string{
+		"o1": "order:1", "o2": "order:1", "o3": "order:2", "c1": "customer:1",
+	})
+
+	delivered, err := relay.Relay(t.Context())
+	if err != nil || delivered != 4 {
+		t.Fatalf("Relay() = %d, %v, want 4 delivered", delivered, err)
+	}
+	if got := publisher.ids(); !slices.Equal(got, []string{"o1", "c1", "o2", "o3"}) {
+		t.Errorf("published %v, want the recorded order", got)
+	}
+
+	tests := []struct {
+		i         int
+		wantTopic string
+		wantKey   string
+	}{
+		{0, "orders", "order:1"},
+		{1, "customer-events", "customer:1"},
+	}
+	for _, tt := range tests {
+		msg := publisher.published[tt.i]
+		if msg.Topic != tt.wantTopic || msg.Key != tt.wantKey {
+			t.Errorf("event %d published on %s with key %s, want %s and %s", tt.i, msg.Topic, msg.Key, tt.wantTopic, tt.wantKey)
+		}
+		if id := msg.Headers[HeaderOutboxID]; msg.Headers[messaging.HeaderMessageID] != id {
+			t.Errorf("event %s published with message ID %q, want the outbox ID", id, msg.Headers[messaging.HeaderMessageID])
+		}
+	}
+
+	if delivered, _ := relay.Relay(t.Context()); delivered != 0 {
+		t.Errorf("second Relay() delivered %d events, want none left", delivered)
+	}
+}
+
+func TestOutboxRelaySkipsEventsBehindFailure(t *testing.T) {
+	publisher := &flakyPublisher{failures: map[string]int{"o1": 1}}
+	outbox := newFlakyOutbox()
+	relay := newRelay(t, publisher, outbox, []string{"o1", "o2", "c1", "o3"}, map[string]string{
+		"o1": "order:1", "o2": "order:1", "o3": "order:1", "c1": "customer:1",
+	})
+
+	// Later events of the failed aggregate wait, others go out
+	delivered, err := relay.Relay(t.Context())
+	if err != nil || delivered != 1 {
+		t.Fatalf("Relay() = %d, %v, want 1 delivered", delivered, err)
+	}
+	if got := publisher.ids(); !slices.Equal(got, []string{"c1"}) {
+		t.Errorf("published %v, want only the other aggregate's event", got)
+	}
+	pending, _ := outbox.Pending(t.Context(), 0)
+	if len(pending) != 3 {
+		t.Fatalf("%d events pending after the failure, want 3", len(pending))
+	}
+	if pending[0].ID != "o1" || pending[0].Attempts != 1 || pending[0].LastError != "broker unavailable" {
+		t.Errorf("pending after the failure = %+v, want o1 with one failed attempt first", pending[0])
+	}
+	stats, err := relay.Stats(t.Context())
+	if err != nil || stats.Failed != 1 || stats.Published != 1 || stats.Pending != 3 || stats.LastPublishError != "broker unavailable" {
+		t.Errorf("Stats() = %+v, %v", stats, err)
+	}
+
+	// The next run publishes the aggregate's events in order
+	if delivered, err := relay.Relay(t.Context()); err != nil || delivered != 3 {
+		t.Fatalf("second Relay() = %d, %v, want 3 delivered", delivered, err)
+	}
+	if got := publisher.ids(); !slices.Equal(got, []string{"c1", "o1", "o2", "o3"}) {
+		t.Errorf("published %v, want the order events in order after the retry", got)
+	}
+}
+
+func TestOutboxRelayRepublishesUnmarkedEvents(t *testing.T) {
+	publisher := &flakyPublisher{}
+	outbox := newFlakyOutbox()
+	outbox.markFailures["o1"] = true
+	relay := newRelay(t, publisher, outbox, []string{"o1"}, map[string]string{"o1": "order:1"})
+
+	// Published, but not recorded as delivered
+	if delivered, err := relay.Relay(t.Context()); err == nil || delivered != 0 {
+		t.Fatalf("Relay() = %d, %v, want the MarkDelivered error", delivered, err)
+	}
+	if delivered, err := relay.Relay(t.Context()); err != nil || delivered != 1 {
+		t.Fatalf("second Relay() = %d, %v, want 1 delivered", delivered, err)
+	}
+
+	// At least once, with the same message ID for consumers to deduplicate
+	if got := publisher.ids(); !slices.Equal(got, []string{"o1", "o1"}) {
+		t.Fatalf("published %v, want o1 twice", got)
+	}
+	if first, second := publisher.published[0], publisher.published[1]; first.Headers[messaging.HeaderMessageID] != second.Headers[messaging.HeaderMessageID] {
+		t.Errorf("republished with message ID %q, want %q", second.Headers[messaging.HeaderMessageID], first.Headers[messaging.HeaderMessageID])
+	}
+}
+
+func TestOutboxRelayCleanup(t *testing.T) {
+	outbox := newFlakyOutbox()
+	relay := newRelay(t, &flakyPublisher{}, outbox, []string{"old", "recent", "pending"}, map[string]string{
+		"old": "order:1", "recent": "order:2", "pending": "order:3",
+	})
+	if err := outbox.MarkDelivered(t.Context(), "old", time.Now().Add(-25*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.MarkDelivered(t.Context(), "recent", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only events delivered before the default 24h retention are removed
+	if removed, err := relay.Cleanup(t.Context()); err != nil || removed != 1 {
+		t.Fatalf("Cleanup() = %d, %v, want 1 removed", removed, err)
+	}
+	stats, err := relay.Stats(t.Context())
+	if err != nil || stats.Cleaned != 1 || stats.Delivered != 1 || stats.Pending != 1 {
+		t.Errorf("Stats() = %+v, %v, want 1 cleaned, 1 delivered and 1 pending", stats, err)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go-microservice/internal/config"
+	"go-microservice/internal/models"
+	"go-microservice/internal/repository"
+	"go-microservice/pkg/apperror"
+)
+
+// ErrQuotaExceeded is returned when an upload would exceed a storage quota
+var ErrQuotaExceeded = apperror.New(apperror.CodeQuotaExceeded, "storage quota exceeded")
+
+// StorageReport describes the usage and limits of a user
+type StorageReport struct {
+	UserID string              `json:"user_id"`
+	Usage  models.StorageUsage `json:"usage"`
+	Quota  models.StorageQuota `json:"quota"`
+}
+
+// QuotaService enforces per-user and per-tenant storage quotas
+type QuotaService struct {
+	config  config.QuotaConfig
+	usage   repository.UsageRepository
+	mu      sync.RWMutex
+	users   map[string]models.StorageQuota
+	tenants map[string]models.StorageQuota
+}
+
+// NewQuotaService creates a new quota service. Limits from the config can be
+// overridden at runtime through SetUserQuota and SetTenantQuota.
+func NewQuotaService(cfg config.QuotaConfig, usage repository.UsageRepository) *QuotaService {
+	s := &QuotaService{
+		config:  cfg,
+		usage:   usage,
+		users:   make(map[string]models.StorageQuota),
+		tenants: make(map[string]models.StorageQuota),
+	}
+
+	for id, limit := range cfg.Users {
+		s.users[id] = toStorageQuota(limit)
+	}
+	for id, limit := range cfg.Tenants {
+		s.tenants[id] = toStorageQuota(limit)
+	}
+
+	return s
+}
+
+// Reserve accounts for a new file, failing with ErrQuotaExceeded if the user
+// or tenant would go over their limit
+func (s *QuotaService) Reserve(ctx context.Context, file *models.File) error {
+	scopes := []repository.UsageScope{
+		{Key: userUsageKey(file.UserID), Limit: s.UserQuota(file.UserID)},
+	}
+	if file.TenantID != "" {
+		scopes = append(scopes, repository.UsageScope{
+			Key:   tenantUsageKey(file.TenantID),
+			Limit: s.TenantQuota(file.TenantID),
+		})
+	}
+
+	err := s.usage.Reserve(ctx, fileUsage(file), scopes...)
+	if errors.Is(err, repository.ErrLimitExceeded) {
+		return fmt.Errorf("%w: %v", ErrQuotaExceeded, err)
+	}
+	return err
+}
+
+// Release removes a file from the usage counters
+func (s *QuotaService) Release(ctx context.Context, file *models.File) error {
+	keys := []string{userUsageKey(file.UserID)}
+	if file.TenantID != "" {
+		keys = append(keys, tenantUsageKey(file.TenantID))
+	}
+	return s.usage.Release(ctx, fileUsage(file), keys...)
+}
+
+// GetUserReport returns the usage and quota of a user
+func (s *QuotaService) GetUserReport(ctx context.Context, userID string) (*StorageReport, error) {
+	usage, err := s.usage.Get(ctx, userUsageKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &StorageReport{
+		UserID: userID,
+		Usage:  usage,
+		Quota:  s.UserQuota(userID),
+	}, nil
+}
+
+// UserQuota returns the effective quota of a user
+func (s *QuotaService) UserQuota(userID string) models.StorageQuota {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if quota, ok := s.users[userID]; ok {
+		return quota
+	}
+	return toStorageQuota(s.config.DefaultUser)
+}
+
+// TenantQuota returns the effective quota of a tenant
+func (s *QuotaService) TenantQuota(tenantID string) models.StorageQuota {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if quota, ok := s.tenants[tenantID]; ok {
+		return quota
+	}
+	return toStorageQuota(s.config.DefaultTenant)
+}
+
+// SetUserQuota overrides the quota of a user
+func (s *QuotaService) SetUserQuota(userID string, quota models.StorageQuota) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID] = quota
+}
+
+// SetTenantQuota overrides the quota of a tenant
+func (s *QuotaService) SetTenantQuota(tenantID string, quota models.StorageQuota) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants[tenantID] = quota
+}
+
+func userUsageKey(userID string) string {
+	return "user:" + userID
+}
+
+func tenantUsageKey(tenantID string) string {
+	return "tenant:" + tenantID
+}
+
+func fileUsage(file *models.File) models.StorageUsage {
+	return models.StorageUsage{Bytes: file.Size, Files: 1}
+}
+
+func toStorageQuota(limit config.QuotaLimit) models.StorageQuota {
+	return models.StorageQuota{MaxBytes: limit.MaxBytes, MaxFiles: limit.MaxFiles}
+}
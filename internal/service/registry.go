@@ -0,0 +1,24 @@
+package service
+
+// Registry holds the service instances built during boot so that route
+// registration can hand them to controllers
+type Registry struct {
+	Service     Service
+	Quota       *QuotaService
+	Lifecycle   *LifecycleService
+	Messaging   *MessagingService
+	DeadLetters *DeadLetterService
+	Outbox      *OutboxRelay
+}
+
+var registry = &Registry{}
+
+// SetRegistry replaces the shared service registry
+func SetRegistry(r *Registry) {
+	registry = r
+}
+
+// GetRegistry returns the shared service registry
+func GetRegistry() *Registry {
+	return registry
+}
@@ -2,7 +2,18 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
 	"go-microservice/internal/models"
+	"go-microservice/internal/repository"
+	"go-microservice/pkg/apperror"
+	"go-microservice/pkg/upload"
 )
 
 // Service defines the interface for business logic operations
@@ -14,53 +25,193 @@ type Service interface {
 	DeleteUser(ctx context.Context, id string) error
 
 	// File operations
-	UploadFile(ctx context.Context, file *models.File) error
+	UploadFile(ctx context.Context, file *models.File, content io.Reader) error
 	DownloadFile(ctx context.Context, id string) (*models.File, error)
 	DeleteFile(ctx context.Context, id string) error
 }
 
+// Aggregate types and events recorded in the outbox
+const (
+	AggregateUser = "user"
+	AggregateFile = "file"
+
+	EventUserCreated  = "user.created"
+	EventUserUpdated  = "user.updated"
+	EventUserDeleted  = "user.deleted"
+	EventFileUploaded = "file.uploaded"
+	EventFileDeleted  = "file.deleted"
+)
+
 // service implements the Service interface
 type service struct {
-	// Add any dependencies here (e.g., repositories, clients)
+	store    repository.Store
+	uploader upload.Uploader
+	keys     *upload.KeyGenerator
+	quotas   *QuotaService
 }
 
-// NewService creates a new service instance
-func NewService() Service {
-	return &service{}
+// NewService creates a new service instance. The quota service is optional;
+// without it uploads are not limited. Every data change records an outbox
+// event in the same transaction.
+func NewService(store repository.Store, uploader upload.Uploader, keys *upload.KeyGenerator, quotas *QuotaService) Service {
+	return &service{
+		store:    store,
+		uploader: uploader,
+		keys:     keys,
+		quotas:   quotas,
+	}
 }
 
 // Implement the Service interface methods
 func (s *service) CreateUser(ctx context.Context, user *models.User) error {
-	// TODO: Implement user creation logic
-	return nil
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	now := time.Now().UTC()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	return s.store.WithinTx(ctx, func(tx repository.Tx) error {
+		if err := tx.Users().Create(ctx, user); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, AggregateUser, user.ID, EventUserCreated, user)
+	})
 }
 
 func (s *service) GetUser(ctx context.Context, id string) (*models.User, error) {
-	// TODO: Implement user retrieval logic
-	return nil, nil
+	user, err := s.store.Users().Get(ctx, id)
+	return user, notFound(err, "user")
 }
 
 func (s *service) UpdateUser(ctx context.Context, user *models.User) error {
-	// TODO: Implement user update logic
-	return nil
+	err := s.store.WithinTx(ctx, func(tx repository.Tx) error {
+		existing, err := tx.Users().Get(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		user.CreatedAt = existing.CreatedAt
+		user.UpdatedAt = time.Now().UTC()
+
+		if err := tx.Users().Update(ctx, user); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, AggregateUser, user.ID, EventUserUpdated, user)
+	})
+	return notFound(err, "user")
 }
 
 func (s *service) DeleteUser(ctx context.Context, id string) error {
-	// TODO: Implement user deletion logic
-	return nil
+	err := s.store.WithinTx(ctx, func(tx repository.Tx) error {
+		if err := tx.Users().Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, AggregateUser, id, EventUserDeleted, map[string]string{"id": id})
+	})
+	return notFound(err, "user")
 }
 
-func (s *service) UploadFile(ctx context.Context, file *models.File) error {
-	// TODO: Implement file upload logic
+func (s *service) UploadFile(ctx context.Context, file *models.File, content io.Reader) error {
+	if file.ID == "" {
+		file.ID = uuid.New().String()
+	}
+
+	// Storage keys are always generated server-side; the client filename only contributes its extension
+	key, err := s.keys.Generate(upload.KeyParams{
+		ID:       file.ID,
+		UserID:   file.UserID,
+		TenantID: file.TenantID,
+		Filename: file.Name,
+	})
+	if err != nil {
+		return err
+	}
+	file.Path = key
+
+	// Account for the file before storing it so concurrent uploads cannot overshoot the quota
+	if s.quotas != nil {
+		if err := s.quotas.Reserve(ctx, file); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.uploader.Upload(ctx, file.Path, content, file.ContentType); err != nil {
+		s.releaseQuota(ctx, file)
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	err = s.store.WithinTx(ctx, func(tx repository.Tx) error {
+		if err := tx.Files().Create(ctx, file); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, AggregateFile, file.ID, EventFileUploaded, file)
+	})
+	if err != nil {
+		s.uploader.Delete(ctx, file.Path)
+		s.releaseQuota(ctx, file)
+		return fmt.Errorf("failed to save file record: %w", err)
+	}
+
 	return nil
 }
 
 func (s *service) DownloadFile(ctx context.Context, id string) (*models.File, error) {
-	// TODO: Implement file download logic
-	return nil, nil
+	file, err := s.store.Files().Get(ctx, id)
+	return file, notFound(err, "file")
 }
 
 func (s *service) DeleteFile(ctx context.Context, id string) error {
-	// TODO: Implement file deletion logic
+	file, err := s.store.Files().Get(ctx, id)
+	if err != nil {
+		return notFound(err, "file")
+	}
+
+	if err := s.uploader.Delete(ctx, file.Path); err != nil {
+		return fmt.Errorf("failed to delete stored file: %w", err)
+	}
+
+	err = s.store.WithinTx(ctx, func(tx repository.Tx) error {
+		if err := tx.Files().Delete(ctx, id); err != nil {
+			return err
+		}
+		return recordEvent(ctx, tx, AggregateFile, id, EventFileDeleted, file)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file record: %w", err)
+	}
+
+	s.releaseQuota(ctx, file)
 	return nil
-} 
\ No newline at end of file
+}
+
+// releaseQuota gives back the usage accounted for a file
+func (s *service) releaseQuota(ctx context.Context, file *models.File) {
+	if s.quotas != nil {
+		s.quotas.Release(ctx, file)
+	}
+}
+
+// notFound names the missing record of errors wrapping repository.ErrNotFound
+func notFound(err error, record string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.Wrap(err, apperror.CodeNotFound, record+" not found")
+	}
+	return err
+}
+
+// recordEvent adds an event describing a data change to the transaction's outbox
+func recordEvent(ctx context.Context, tx repository.Tx, aggregateType, aggregateID, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+
+	return tx.Outbox().Add(ctx, &models.OutboxMessage{
+		ID:            uuid.New().String(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       payload,
+		CreatedAt:     time.Now().UTC(),
+	})
+}
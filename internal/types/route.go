@@ -7,4 +7,7 @@ type Route struct {
 	Method  string
 	Path    string
 	Handler gin.HandlerFunc
+
+	// Middleware runs before Handler, for example to authenticate
+	Middleware []gin.HandlerFunc
 } 
\ No newline at end of file
@@ -0,0 +1,120 @@
+// Package apperror defines the errors returned to API clients. An Error
+// carries a Code, which decides the HTTP status, a message safe to show to
+// clients and the underlying cause, which is only logged.
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code classifies an error
+type Code string
+
+// Error codes with the HTTP status they map to
+const (
+	CodeInternal       Code = "internal"        // 500
+	CodeValidation     Code = "validation"      // 400
+	CodeUnauthorized   Code = "unauthorized"    // 401
+	CodeForbidden      Code = "forbidden"       // 403
+	CodeNotFound       Code = "not_found"       // 404
+	CodeConflict       Code = "conflict"        // 409
+	CodeQuotaExceeded  Code = "quota_exceeded"  // 413
+	CodeRateLimited    Code = "rate_limited"    // 429
+	CodeNotImplemented Code = "not_implemented" // 501
+	CodeUpstream       Code = "upstream"        // 502
+	CodeUnavailable    Code = "unavailable"     // 503
+)
+
+var statuses = map[Code]int{
+	CodeInternal:       http.StatusInternalServerError,
+	CodeValidation:     http.StatusBadRequest,
+	CodeUnauthorized:   http.StatusUnauthorized,
+	CodeForbidden:      http.StatusForbidden,
+	CodeNotFound:       http.StatusNotFound,
+	CodeConflict:       http.StatusConflict,
+	CodeQuotaExceeded:  http.StatusRequestEntityTooLarge,
+	CodeRateLimited:    http.StatusTooManyRequests,
+	CodeNotImplemented: http.StatusNotImplemented,
+	CodeUpstream:       http.StatusBadGateway,
+	CodeUnavailable:    http.StatusServiceUnavailable,
+}
+
+// Status returns the HTTP status of the code, 500 for unknown codes
+func (c Code) Status() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error with a code and a client-facing message
+type Error struct {
+	Code    Code
+	Message string            // shown to clients
+	Fields  map[string]string // problems of single input fields, for validation errors
+	Err     error             // cause, never shown to clients
+}
+
+// New creates an error without a cause, usually a sentinel such as
+// ErrNotFound that is wrapped with fmt.Errorf and %w
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap creates an error with err as its cause
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// registered maps errors of packages that do not use this one to codes
+var registered []registration
+
+type registration struct {
+	target error
+	code   Code
+}
+
+// Register classifies errors matching target, as reported by errors.Is,
+// with code. Errors of client requests (4xx codes) show their full message
+// to clients, others only show target's.
+func Register(target error, code Code) {
+	registered = append(registered, registration{target: target, code: code})
+}
+
+// From returns the Error in err's chain. Errors without one are classified
+// by the registered targets, and as internal errors with a generic message
+// when none matches. From returns nil for a nil error.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	for _, r := range registered {
+		if !errors.Is(err, r.target) {
+			continue
+		}
+		message := r.target.Error()
+		if r.code.Status() < http.StatusInternalServerError {
+			message = err.Error()
+		}
+		return Wrap(err, r.code, message)
+	}
+	return Wrap(err, CodeInternal, "internal server error")
+}
@@ -0,0 +1,108 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCodeStatus(t *testing.T) {
+	tests := []struct {
+		code Code
+		want int
+	}{
+		{CodeInternal, http.StatusInternalServerError},
+		{CodeValidation, http.StatusBadRequest},
+		{CodeUnauthorized, http.StatusUnauthorized},
+		{CodeForbidden, http.StatusForbidden},
+		{CodeNotFound, http.StatusNotFound},
+		{CodeConflict, http.StatusConflict},
+		{CodeQuotaExceeded, http.StatusRequestEntityTooLarge},
+		{CodeRateLimited, http.StatusTooManyRequests},
+		{CodeNotImplemented, http.StatusNotImplemented},
+		{CodeUpstream, http.StatusBadGateway},
+		{CodeUnavailable, http.StatusServiceUnavailable},
+		{Code("teapot"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			if got := tt.code.Status(); got != tt.want {
+				t.Errorf("Status() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrom(t *testing.T) {
+	// Errors of a package that does not use apperror
+	errMissing := errors.New("order missing")
+	errBrokerDown := errors.New("broker down")
+	saved := registered
+	t.Cleanup(func() { registered = saved })
+	Register(errMissing, CodeNotFound)
+	Register(errBrokerDown, CodeUnavailable)
+
+	errNotFound := New(CodeNotFound, "file not found")
+	cause := errors.New("dial tcp: connection refused")
+
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    Code
+		wantMessage string
+		wantCause   error
+	}{
+		{
+			name:        "wrapped Error",
+			err:         fmt.Errorf("loading report: %w", errNotFound),
+			wantCode:    CodeNotFound,
+			wantMessage: "file not found",
+		},
+		{
+			name:        "Error with a cause",
+			err:         Wrap(cause, CodeUpstream, "storage unavailable"),
+			wantCode:    CodeUpstream,
+			wantMessage: "storage unavailable",
+			wantCause:   cause,
+		},
+		{
+			name:        "registered client error shows the full message",
+			err:         fmt.Errorf("order o1: %w", errMissing),
+			wantCode:    CodeNotFound,
+			wantMessage: "order o1: order missing",
+			wantCause:   errMissing,
+		},
+		{
+			name:        "registered server error shows only the target",
+			err:         fmt.Errorf("publish to 10.0.0.1:4222: %w", errBrokerDown),
+			wantCode:    CodeUnavailable,
+			wantMessage: "broker down",
+			wantCause:   errBrokerDown,
+		},
+		{
+			name:        "unknown error",
+			err:         fmt.Errorf("query users: %w", cause),
+			wantCode:    CodeInternal,
+			wantMessage: "internal server error",
+			wantCause:   cause,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if got.Code != tt.wantCode || got.Message != tt.wantMessage {
+				t.Errorf("From() = %s %q, want %s %q", got.Code, got.Message, tt.wantCode, tt.wantMessage)
+			}
+			if tt.wantCause != nil && !errors.Is(got, tt.wantCause) {
+				t.Errorf("From() = %v, want it to wrap %v", got, tt.wantCause)
+			}
+		})
+	}
+
+	if got := From(nil); got != nil {
+		t.Errorf("From(nil) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,66 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Invalid creates a validation error for a request body that failed to
+// bind. Failed validation rules are reported per field.
+func Invalid(err error) *Error {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		// Malformed JSON and mismatched types explain themselves
+		return New(CodeValidation, "invalid request body: "+err.Error())
+	}
+
+	appErr := Wrap(err, CodeValidation, "invalid request body")
+	appErr.Fields = make(map[string]string, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		appErr.Fields[fieldName(fieldErr)] = describeFieldError(fieldErr)
+	}
+	return appErr
+}
+
+// fieldName returns the path of a field, such as messages[0].topic,
+// without the name of the request type. Anonymous request types have no
+// name in the path; named ones are told apart from fields by their initial
+// capital, which assumes the validator reports JSON field names.
+func fieldName(fieldErr validator.FieldError) string {
+	root, name, ok := strings.Cut(fieldErr.Namespace(), ".")
+	if ok && root != "" && unicode.IsUpper(rune(root[0])) {
+		return name
+	}
+	return fieldErr.Namespace()
+}
+
+// JSONFieldName names struct fields by their JSON key in validation
+// errors. Register it with the validator's RegisterTagNameFunc.
+func JSONFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// describeFieldError explains a failed validation rule
+func describeFieldError(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		return "must be at most " + fieldErr.Param()
+	default:
+		return fmt.Sprintf("fails the %s rule", fieldErr.Tag())
+	}
+}
@@ -70,6 +70,20 @@ func (r *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
 	return result > 0, err
 }
 
+// HGetAll retrieves all fields of a hash
+func (r *RedisClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return r.client.HGetAll(ctx, key).Result()
+}
+
+// Eval runs a Lua script atomically on the Redis server
+func (r *RedisClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	result, err := r.client.Eval(ctx, script, keys, args...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return result, err
+}
+
 // Close closes the Redis connection
 func (r *RedisClient) Close() error {
 	return r.client.Close()
@@ -6,22 +6,29 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 
 	"go-microservice/internal/config"
 	"go-microservice/internal/middleware"
 	"go-microservice/internal/routes"
+	"go-microservice/pkg/apperror"
 	"go-microservice/pkg/logger"
 )
 
 type Server struct {
-	engine *gin.Engine
+	engine  *gin.Engine
+	cors    *middleware.CORS
+	limiter *middleware.RateLimiter
 }
 
 func NewServer() *Server {
 	cfg := config.GetConfig().Server
 
 	setGinMode(cfg.Environment)
+	useJSONFieldNames()
 
 	engine := gin.New()
 	server := &Server{
@@ -46,24 +53,74 @@ func setGinMode(env string) {
 	logger.Info("Gin mode set", zap.String("mode", gin.Mode()))
 }
 
+// useJSONFieldNames makes request validation errors name fields as
+// clients send them
+func useJSONFieldNames() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(apperror.JSONFieldName)
+	}
+}
+
 func (s *Server) registerMiddlewares() {
 	cfg := config.GetConfig().Server
 
 	s.engine.Use(gin.Recovery())
 	s.engine.Use(middleware.LoggingMiddleware())
 	s.engine.Use(middleware.GzipMiddleware())
-	logger.Info("Default middlewares initialized: Recovery, Logging, Gzip")
-
+	// Errors are rendered inside gzip, which cannot be written after it finished
+	s.engine.Use(middleware.ErrorHandler())
+	logger.Info("Default middlewares initialized: Recovery, Logging, Gzip, ErrorHandler")
+
+	// CORS and rate limiting are always installed so that reloads can turn
+	// them on and off
+	s.cors = middleware.NewCORS(cfg.AllowOrigins)
+	s.engine.Use(s.cors.Middleware())
 	if cfg.AllowOrigins != "" {
-		s.engine.Use(middleware.CORSMiddleware(cfg.AllowOrigins))
 		logger.Info("CORS middleware applied", zap.String("allowed_origins", cfg.AllowOrigins))
 	} else {
 		logger.Info("CORS middleware skipped, no allowed origins configured")
 	}
+
+	limit, burst := rateLimit(cfg.RateLimit)
+	s.limiter = middleware.NewRateLimiter(limit, burst, func(c *gin.Context) string {
+		return c.ClientIP()
+	})
+	s.engine.Use(s.limiter.Middleware())
+
+	config.OnChange("server", s.applyServerConfig)
+}
+
+// applyServerConfig applies reloaded CORS and rate limit settings
+func (s *Server) applyServerConfig(old, new *config.Config) {
+	if new.Server.AllowOrigins != old.Server.AllowOrigins {
+		s.cors.SetAllowOrigins(new.Server.AllowOrigins)
+		logger.Info("CORS origins updated", zap.String("allowed_origins", new.Server.AllowOrigins))
+	}
+	if new.Server.RateLimit != old.Server.RateLimit {
+		s.limiter.SetLimit(rateLimit(new.Server.RateLimit))
+		logger.Info("Rate limit updated",
+			zap.Bool("enabled", new.Server.RateLimit.Enabled),
+			zap.Float64("requests_per_second", new.Server.RateLimit.RequestsPerSecond),
+			zap.Int("burst", new.Server.RateLimit.Burst))
+	}
+}
+
+// rateLimit converts rate limit settings for the limiter. Disabled limits
+// let every request through.
+func rateLimit(cfg config.RateLimitConfig) (rate.Limit, int) {
+	if !cfg.Enabled {
+		return rate.Inf, 0
+	}
+	return rate.Limit(cfg.RequestsPerSecond), cfg.Burst
 }
 
 func (s *Server) RegisterRoutes() error {
 	routes.RegisterRoutes(s.engine)
+
+	// Unknown routes get a problem details response like every other error
+	s.engine.NoRoute(func(c *gin.Context) {
+		c.Error(apperror.New(apperror.CodeNotFound, "route not found"))
+	})
 	return nil
 }
 
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// contextKey stores the logger of a context
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, which FromContext returns
+// and WithContext adds fields to
+func NewContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// WithContext returns a copy of ctx whose logger has fields added, so that
+// every line logged while handling a request or message carries them
+func WithContext(ctx context.Context, fields ...zap.Field) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(fields...))
+}
+
+// FromContext returns the logger of ctx with the fields added by
+// middleware, such as the request ID, route and trace IDs of HTTP requests
+// or the topic and message ID of messages. Without one it returns the
+// global logger.
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return Get()
+}
@@ -0,0 +1,239 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	// level is the global level, used by loggers without a component level
+	level = zap.NewAtomicLevel()
+
+	// components holds the component levels. It is replaced on every change
+	// so that logging reads it without locking.
+	components atomic.Pointer[componentLevels]
+
+	// levelMu serializes level changes and guards reverts
+	levelMu sync.Mutex
+	reverts = make(map[string]*levelRevert)
+)
+
+// componentLevels maps logger names to levels
+type componentLevels struct {
+	levels map[string]zapcore.Level
+	min    zapcore.Level // lowest level in levels
+}
+
+// levelRevert restores a level when its timer fires
+type levelRevert struct {
+	timer   *time.Timer
+	at      time.Time
+	restore zapcore.Level
+	had     bool // false removes the component level instead
+}
+
+// LevelStatus is the level of the global logger or a component and when a
+// temporary level reverts
+type LevelStatus struct {
+	Level    string     `json:"level"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// Levels lists the global level and the component levels
+type Levels struct {
+	Global     LevelStatus            `json:"global"`
+	Components map[string]LevelStatus `json:"components"`
+}
+
+// SetLevel changes the global level while the logger runs
+func SetLevel(logLevel string) error {
+	return SetComponentLevel("", logLevel, 0)
+}
+
+// SetComponentLevel sets the level of the loggers named component, see
+// Named, and of their children such as component.child. An empty component
+// sets the global level. A positive ttl reverts the change after ttl to the
+// level before the first temporary change.
+func SetComponentLevel(component, logLevel string, ttl time.Duration) error {
+	zapLevel, err := zapcore.ParseLevel(logLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", logLevel, err)
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	// A pending revert keeps restoring the level from before it
+	restore, had := currentLevel(component)
+	if pending, ok := reverts[component]; ok {
+		restore, had = pending.restore, pending.had
+		pending.timer.Stop()
+		delete(reverts, component)
+	}
+
+	setLevel(component, zapLevel)
+
+	if ttl > 0 {
+		revert := &levelRevert{at: time.Now().Add(ttl), restore: restore, had: had}
+		revert.timer = time.AfterFunc(ttl, func() {
+			levelMu.Lock()
+			defer levelMu.Unlock()
+
+			// Skip reverts replaced by a later change
+			if reverts[component] != revert {
+				return
+			}
+			delete(reverts, component)
+			if revert.had {
+				setLevel(component, revert.restore)
+			} else {
+				removeLevel(component)
+			}
+		})
+		reverts[component] = revert
+	}
+	return nil
+}
+
+// ResetComponentLevel removes the level of a component, which then logs at
+// the global level
+func ResetComponentLevel(component string) error {
+	if component == "" {
+		return errors.New("the global level cannot be reset")
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	if pending, ok := reverts[component]; ok {
+		pending.timer.Stop()
+		delete(reverts, component)
+	}
+	removeLevel(component)
+	return nil
+}
+
+// GetLevels returns the global level and the component levels
+func GetLevels() Levels {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	status := func(component string, lvl zapcore.Level) LevelStatus {
+		s := LevelStatus{Level: lvl.String()}
+		if revert, ok := reverts[component]; ok {
+			at := revert.at
+			s.RevertAt = &at
+		}
+		return s
+	}
+
+	levels := Levels{
+		Global:     status("", level.Level()),
+		Components: make(map[string]LevelStatus),
+	}
+	if comps := components.Load(); comps != nil {
+		for component, lvl := range comps.levels {
+			levels.Components[component] = status(component, lvl)
+		}
+	}
+	return levels
+}
+
+// currentLevel returns the level set for component and whether one is set.
+// Callers hold levelMu.
+func currentLevel(component string) (zapcore.Level, bool) {
+	if component == "" {
+		return level.Level(), true
+	}
+	if comps := components.Load(); comps != nil {
+		lvl, ok := comps.levels[component]
+		return lvl, ok
+	}
+	return 0, false
+}
+
+// setLevel sets the level of component or the global level. Callers hold
+// levelMu.
+func setLevel(component string, lvl zapcore.Level) {
+	if component == "" {
+		level.SetLevel(lvl)
+		return
+	}
+	updateComponents(func(levels map[string]zapcore.Level) {
+		levels[component] = lvl
+	})
+}
+
+// removeLevel removes the level of component. Callers hold levelMu.
+func removeLevel(component string) {
+	updateComponents(func(levels map[string]zapcore.Level) {
+		delete(levels, component)
+	})
+}
+
+// updateComponents replaces the component levels with a modified copy
+func updateComponents(update func(map[string]zapcore.Level)) {
+	next := &componentLevels{levels: make(map[string]zapcore.Level), min: zapcore.FatalLevel}
+	if comps := components.Load(); comps != nil {
+		for component, lvl := range comps.levels {
+			next.levels[component] = lvl
+		}
+	}
+	update(next.levels)
+	for _, lvl := range next.levels {
+		next.min = min(next.min, lvl)
+	}
+	components.Store(next)
+}
+
+// levelFor returns the level of a logger name: the level of the longest
+// component prefix of the name, or the global level
+func levelFor(name string) zapcore.LevelEnabler {
+	comps := components.Load()
+	if comps == nil || len(comps.levels) == 0 {
+		return level
+	}
+	for name != "" {
+		if lvl, ok := comps.levels[name]; ok {
+			return lvl
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return level
+}
+
+// levelCore filters entries by the level of their logger name
+type levelCore struct {
+	zapcore.Core
+}
+
+// Enabled reports whether any logger logs at lvl
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	if level.Enabled(lvl) {
+		return true
+	}
+	comps := components.Load()
+	return comps != nil && len(comps.levels) > 0 && lvl >= comps.min
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields)}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !levelFor(entry.LoggerName).Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
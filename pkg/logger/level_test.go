@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// resetLevels restores the global level and drops the component levels and
+// pending reverts when the test ends
+func resetLevels(t *testing.T) {
+	t.Helper()
+
+	saved := level.Level()
+	t.Cleanup(func() {
+		levelMu.Lock()
+		defer levelMu.Unlock()
+		for component, revert := range reverts {
+			revert.timer.Stop()
+			delete(reverts, component)
+		}
+		components.Store(nil)
+		level.SetLevel(saved)
+	})
+}
+
+// waitForLevel waits until component logs at want, or is unset for an
+// empty want, and fails the test after a second
+func waitForLevel(t *testing.T, component, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		got := levelOf(component)
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("level of %q = %q, want %q", component, got, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// levelOf returns the level of component from GetLevels, empty when unset
+func levelOf(component string) string {
+	levels := GetLevels()
+	if component == "" {
+		return levels.Global.Level
+	}
+	return levels.Components[component].Level
+}
+
+func TestSetComponentLevelRevertsAfterTTL(t *testing.T) {
+	tests := []struct {
+		name       string
+		component  string
+		before     string // set without a TTL first, empty for none
+		wantRevert string
+	}{
+		{"global level", "", "warn", "warn"},
+		{"component with a level", "upload", "error", "error"},
+		{"component without a level", "upload", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetLevels(t)
+			if tt.before != "" {
+				if err := SetComponentLevel(tt.component, tt.before, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := SetComponentLevel(tt.component, "debug", 30*time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+			if got := levelOf(tt.component); got != "debug" {
+				t.Fatalf("level = %q, want debug", got)
+			}
+			status := GetLevels().Global
+			if tt.component != "" {
+				status = GetLevels().Components[tt.component]
+			}
+			if status.RevertAt == nil {
+				t.Error("temporary level reported without a revert time")
+			}
+
+			waitForLevel(t, tt.component, tt.wantRevert)
+			if tt.component != "" && tt.wantRevert == "" {
+				if _, ok := GetLevels().Components[tt.component]; ok {
+					t.Error("reverted component level still listed")
+				}
+			}
+		})
+	}
+}
+
+func TestSetComponentLevelReplacesPendingRevert(t *testing.T) {
+	tests := []struct {
+		name      string
+		next      string
+		nextTTL   time.Duration
+		wantFinal string
+	}{
+		// The new revert restores the level from before the first change
+		{"temporary change", "error", 60 * time.Millisecond, "warn"},
+		{"permanent change", "error", 0, "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetLevels(t)
+			if err := SetComponentLevel("upload", "warn", 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := SetComponentLevel("upload", "debug", 30*time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+			if err := SetComponentLevel("upload", tt.next, tt.nextTTL); err != nil {
+				t.Fatal(err)
+			}
+
+			// The first revert does not fire
+			time.Sleep(45 * time.Millisecond)
+			if got := levelOf("upload"); got != tt.next {
+				t.Fatalf("level after the first TTL = %q, want %q", got, tt.next)
+			}
+			waitForLevel(t, "upload", tt.wantFinal)
+			time.Sleep(50 * time.Millisecond)
+			if got := levelOf("upload"); got != tt.wantFinal {
+				t.Errorf("level = %q, want %q", got, tt.wantFinal)
+			}
+		})
+	}
+}
+
+func TestResetComponentLevel(t *testing.T) {
+	resetLevels(t)
+	if err := SetComponentLevel("upload", "debug", 30*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetComponentLevel("upload.ftp", "error", 0); err != nil {
+		t.Fatal(err)
+	}
+	if !levelFor("upload.sftp").Enabled(zapcore.DebugLevel) || levelFor("upload.ftp").Enabled(zapcore.WarnLevel) {
+		t.Error("child loggers do not use the longest matching component level")
+	}
+
+	if err := ResetComponentLevel("upload"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetLevels().Components["upload"]; ok {
+		t.Error("reset component level still listed")
+	}
+	if levelFor("upload.sftp") != level {
+		t.Error("child logger of a reset component does not use the global level")
+	}
+
+	// The cancelled revert does not bring the level back
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := GetLevels().Components["upload"]; ok {
+		t.Error("cancelled revert restored the component level")
+	}
+
+	if err := ResetComponentLevel(""); err == nil {
+		t.Error("ResetComponentLevel(\"\") succeeded, want an error")
+	}
+}
@@ -1,11 +1,15 @@
 package logger
 
 import (
-	"context"
+	"fmt"
+	"os"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"go-microservice/internal/config"
 )
 
 var (
@@ -13,43 +17,50 @@ var (
 	once     sync.Once
 )
 
-// Init initializes the global logger only once with level and file path
-func Init(level, filePath string) error {
+// Init initializes the global logger only once with level and the sinks
+// entries are written to
+func Init(logLevel string, sinks []config.LogSinkConfig) error {
 	var initErr error
 	once.Do(func() {
-		config := zap.NewProductionConfig()
-
-		var zapLevel zapcore.Level
-		if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
-			zapLevel = zapcore.InfoLevel
+		if err := SetLevel(logLevel); err != nil {
+			level.SetLevel(zapcore.InfoLevel)
 		}
-		config.Level = zap.NewAtomicLevelAt(zapLevel)
 
-		if filePath != "" {
-			config.OutputPaths = []string{filePath, "stdout"}
-		} else {
-			config.OutputPaths = []string{"stdout"}
+		cores := make([]zapcore.Core, 0, len(sinks))
+		for i, sink := range sinks {
+			core, err := newSinkCore(sink)
+			if err != nil {
+				initErr = fmt.Errorf("failed to create %s log sink %d: %w", sink.Type, i, err)
+				return
+			}
+			cores = append(cores, core)
 		}
 
-		config.EncoderConfig.TimeKey = "timestamp"
-		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-
-		instance, initErr = config.Build()
+		// Same sampling and options as zap's production config. levelCore
+		// applies the global and component levels on top of the sink levels.
+		core := zapcore.NewSamplerWithOptions(zapcore.NewTee(cores...), time.Second, 100, 100)
+		instance = zap.New(&levelCore{Core: core},
+			zap.AddCaller(),
+			zap.AddStacktrace(zapcore.ErrorLevel),
+			zap.ErrorOutput(zapcore.Lock(os.Stderr)))
 	})
 	return initErr
 }
 
-// FromContext returns a logger with context fields
-func FromContext(ctx context.Context) *zap.Logger {
+// Get returns the global logger, or a no-op logger before Init is called
+func Get() *zap.Logger {
 	if instance == nil {
 		return zap.NewNop()
 	}
-	if reqID, ok := ctx.Value("request_id").(string); ok {
-		return instance.With(zap.String("request_id", reqID))
-	}
 	return instance
 }
 
+// Named returns the global logger of a component. Components can log at
+// their own level, see SetComponentLevel.
+func Named(component string) *zap.Logger {
+	return Get().Named(component)
+}
+
 // Info logs an info level message
 func Info(msg string, fields ...zap.Field) {
 	if instance != nil {
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"go-microservice/internal/config"
+)
+
+// DefaultMaxSizeMB is the size at which file sinks rotate by default
+const DefaultMaxSizeMB = 100
+
+// newSinkCore creates the core writing entries to a sink
+func newSinkCore(cfg config.LogSinkConfig) (zapcore.Core, error) {
+	minLevel := zapcore.DebugLevel
+	if cfg.Level != "" {
+		var err error
+		if minLevel, err = zapcore.ParseLevel(cfg.Level); err != nil {
+			return nil, fmt.Errorf("invalid level %q: %w", cfg.Level, err)
+		}
+	}
+
+	// Colors only make sense on a terminal
+	terminal := cfg.Type == "stdout" || cfg.Type == "stderr"
+	encoder, err := newEncoder(cfg.Encoding, terminal)
+	if err != nil {
+		return nil, err
+	}
+
+	var out zapcore.WriteSyncer
+	switch cfg.Type {
+	case "stdout":
+		out = zapcore.Lock(os.Stdout)
+	case "stderr":
+		out = zapcore.Lock(os.Stderr)
+	case "file":
+		out = newFileWriter(cfg.File)
+	case "syslog":
+		return newSyslogCore(cfg.Syslog, encoder, minLevel)
+	case "http", "tcp":
+		out, err = newRemoteWriter(cfg.Type, cfg.Remote)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported log sink %q", cfg.Type)
+	}
+	return zapcore.NewCore(encoder, out, minLevel), nil
+}
+
+// newEncoder creates a JSON encoder or, for "console", a human-readable one
+func newEncoder(encoding string, terminal bool) (zapcore.Encoder, error) {
+	switch encoding {
+	case "", "json":
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.TimeKey = "timestamp"
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case "console":
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		if terminal {
+			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	default:
+		return nil, fmt.Errorf("unsupported log encoding %q", encoding)
+	}
+}
+
+// newFileWriter creates a file writer rotated by size and, with
+// RotateEvery, by time
+func newFileWriter(cfg config.LogFileConfig) zapcore.WriteSyncer {
+	maxSize := cfg.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSizeMB
+	}
+
+	file := &fileWriter{Logger: &lumberjack.Logger{
+		Filename:   cfg.Path,
+		MaxSize:    maxSize,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAgeDays,
+		Compress:   cfg.Compress,
+	}}
+
+	// The logger lives as long as the process, so the ticker is never stopped
+	if cfg.RotateEvery > 0 {
+		go func() {
+			for range time.Tick(cfg.RotateEvery) {
+				// Files without new entries are kept
+				if !file.written.Swap(false) {
+					continue
+				}
+				if err := file.Rotate(); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to rotate log file %s: %v\n", cfg.Path, err)
+				}
+			}
+		}()
+	}
+	return zapcore.AddSync(file)
+}
+
+// fileWriter is a rotating file that tracks whether it was written to
+type fileWriter struct {
+	*lumberjack.Logger
+	written atomic.Bool
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.written.Store(true)
+	return w.Logger.Write(p)
+}
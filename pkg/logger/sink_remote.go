@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go-microservice/internal/config"
+)
+
+// Defaults for http and tcp sinks
+const (
+	DefaultRemoteBufferSize    = 10000
+	DefaultRemoteBatchSize     = 100
+	DefaultRemoteFlushInterval = time.Second
+	DefaultRemoteTimeout       = 5 * time.Second
+)
+
+// remoteWriter queues encoded entries and ships them in batches from a
+// background goroutine, so logging never waits for the network
+type remoteWriter struct {
+	cfg     config.LogRemoteConfig
+	send    func(batch [][]byte) error
+	entries chan []byte
+	flush   chan chan struct{}
+	dropped atomic.Int64
+}
+
+// newRemoteWriter creates the writer of an http or tcp sink
+func newRemoteWriter(kind string, cfg config.LogRemoteConfig) (*remoteWriter, error) {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultRemoteBufferSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultRemoteBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultRemoteFlushInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultRemoteTimeout
+	}
+
+	w := &remoteWriter{
+		cfg:     cfg,
+		entries: make(chan []byte, cfg.BufferSize),
+		flush:   make(chan chan struct{}),
+	}
+	switch kind {
+	case "http":
+		w.send = httpSender(cfg)
+	case "tcp":
+		w.send = (&tcpSender{cfg: cfg}).send
+	default:
+		return nil, fmt.Errorf("unsupported remote log sink %q", kind)
+	}
+
+	// The logger lives as long as the process, so the goroutine never stops
+	go w.run()
+	return w, nil
+}
+
+// Write queues an entry, dropping it when the buffer is full
+func (w *remoteWriter) Write(p []byte) (int, error) {
+	// zap reuses p once Write returns
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	select {
+	case w.entries <- entry:
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Sync sends the queued entries, waiting at most the sink timeout
+func (w *remoteWriter) Sync() error {
+	timeout := time.NewTimer(w.cfg.Timeout)
+	defer timeout.Stop()
+
+	done := make(chan struct{})
+	select {
+	case w.flush <- done:
+	case <-timeout.C:
+		return errors.New("timed out flushing log entries")
+	}
+	select {
+	case <-done:
+		return nil
+	case <-timeout.C:
+		return errors.New("timed out flushing log entries")
+	}
+}
+
+// run sends a batch when it is full, every flush interval and on Sync
+func (w *remoteWriter) run() {
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, w.cfg.BatchSize)
+	send := func() {
+		// Failures cannot be logged without looping back here
+		if dropped := w.dropped.Swap(0); dropped > 0 {
+			fmt.Fprintf(os.Stderr, "dropped %d log entries, the log sink buffer was full\n", dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.send(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to ship %d log entries: %v\n", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	add := func(entry []byte) {
+		batch = append(batch, entry)
+		if len(batch) >= w.cfg.BatchSize {
+			send()
+		}
+	}
+
+	for {
+		select {
+		case entry := <-w.entries:
+			add(entry)
+		case <-ticker.C:
+			send()
+		case done := <-w.flush:
+			// Send the entries queued before Sync was called
+			for n := len(w.entries); n > 0; n-- {
+				add(<-w.entries)
+			}
+			send()
+			close(done)
+		}
+	}
+}
+
+// httpSender POSTs batches as newline-delimited entries
+func httpSender(cfg config.LogRemoteConfig) func([][]byte) error {
+	client := &http.Client{Timeout: cfg.Timeout}
+	return func(batch [][]byte) error {
+		req, err := http.NewRequest(http.MethodPost, cfg.URL, bytes.NewReader(bytes.Join(batch, nil)))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		for key, value := range cfg.Headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("log endpoint returned %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// tcpSender writes batches to a TCP connection, reconnecting once when a
+// write fails. It is only used by the remoteWriter goroutine.
+type tcpSender struct {
+	cfg  config.LogRemoteConfig
+	conn net.Conn
+}
+
+func (s *tcpSender) send(batch [][]byte) error {
+	data := bytes.Join(batch, nil)
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			conn, err := net.DialTimeout("tcp", s.cfg.Address, s.cfg.Timeout)
+			if err != nil {
+				return err
+			}
+			s.conn = conn
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(s.cfg.Timeout))
+		_, err := s.conn.Write(data)
+		if err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+		if attempt > 0 {
+			return err
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-microservice/internal/config"
+)
+
+// logEndpoint is an HTTP log endpoint passing on each request it receives
+type logEndpoint struct {
+	requests chan *http.Request
+	bodies   chan string
+	release  chan struct{} // closed to answer requests, nil answers at once
+}
+
+func newLogEndpoint(t *testing.T, release chan struct{}) (*logEndpoint, string) {
+	t.Helper()
+
+	e := &logEndpoint{
+		requests: make(chan *http.Request, 10),
+		bodies:   make(chan string, 10),
+		release:  release,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		e.requests <- r
+		e.bodies <- string(body)
+		if e.release != nil {
+			<-e.release
+		}
+	}))
+	t.Cleanup(server.Close)
+	return e, server.URL
+}
+
+// next returns the body of the next request
+func (e *logEndpoint) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case body := <-e.bodies:
+		return body
+	case <-time.After(2 * time.Second):
+		t.Fatal("no batch arrived")
+		return ""
+	}
+}
+
+// write writes entries, each followed by a newline
+func write(t *testing.T, w *remoteWriter, entries ...string) {
+	t.Helper()
+	for _, entry := range entries {
+		if n, err := w.Write([]byte(entry + "\n")); err != nil || n != len(entry)+1 {
+			t.Fatalf("Write() = %d, %v", n, err)
+		}
+	}
+}
+
+func TestRemoteWriterSendsHTTPBatches(t *testing.T) {
+	endpoint, url := newLogEndpoint(t, nil)
+	w, err := newRemoteWriter("http", config.LogRemoteConfig{
+		URL:           url,
+		Headers:       map[string]string{"Authorization": "Bearer log-token"},
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Full batches go out on their own, the rest on Sync
+	write(t, w, "e1", "e2", "e3", "e4", "e5", "e6", "e7")
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	for _, want := range []string{"e1\ne2\ne3\n", "e4\ne5\ne6\n", "e7\n"} {
+		if got := endpoint.next(t); got != want {
+			t.Errorf("batch = %q, want %q", got, want)
+		}
+	}
+
+	req := <-endpoint.requests
+	if req.Method != http.MethodPost || req.Header.Get("Content-Type") != "application/x-ndjson" || req.Header.Get("Authorization") != "Bearer log-token" {
+		t.Errorf("request %s with headers %v", req.Method, req.Header)
+	}
+}
+
+func TestRemoteWriterFlushesEveryInterval(t *testing.T) {
+	endpoint, url := newLogEndpoint(t, nil)
+	w, err := newRemoteWriter("http", config.LogRemoteConfig{URL: url, FlushInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, w, "e1")
+	if got := endpoint.next(t); got != "e1\n" {
+		t.Errorf("batch = %q, want e1", got)
+	}
+}
+
+func TestRemoteWriterDropsEntriesWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	endpoint, url := newLogEndpoint(t, release)
+	w, err := newRemoteWriter("http", config.LogRemoteConfig{
+		URL:           url,
+		BufferSize:    2,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The endpoint holds the first batch while the buffer fills up
+	write(t, w, "e1")
+	if got := endpoint.next(t); got != "e1\n" {
+		t.Fatalf("batch = %q, want e1", got)
+	}
+	write(t, w, "e2", "e3", "e4", "e5")
+	if dropped := w.dropped.Load(); dropped != 2 {
+		t.Errorf("dropped %d entries, want 2", dropped)
+	}
+
+	close(release)
+	if err := w.Sync(); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	for _, want := range []string{"e2\n", "e3\n"} {
+		if got := endpoint.next(t); got != want {
+			t.Errorf("batch = %q, want %q", got, want)
+		}
+	}
+	if dropped := w.dropped.Load(); dropped != 0 {
+		t.Errorf("drop counter = %d after it was reported, want 0", dropped)
+	}
+}
+
+func TestRemoteWriterReconnectsTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	// Every accepted connection is passed on with a channel of its lines
+	type connection struct {
+		conn  *net.TCPConn
+		lines chan string
+	}
+	connections := make(chan connection, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c := connection{conn: conn.(*net.TCPConn), lines: make(chan string, 10)}
+			connections <- c
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					c.lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	accept := func() connection {
+		t.Helper()
+		select {
+		case c := <-connections:
+			return c
+		case <-time.After(2 * time.Second):
+			t.Fatal("no connection arrived")
+			return connection{}
+		}
+	}
+	readLine := func(c connection) string {
+		t.Helper()
+		select {
+		case line := <-c.lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("no entry arrived")
+			return ""
+		}
+	}
+
+	w, err := newRemoteWriter("tcp", config.LogRemoteConfig{Address: listener.Addr().String(), FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, w, "e1")
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	first := accept()
+	if got := readLine(first); got != "e1" {
+		t.Errorf("entry = %q, want e1", got)
+	}
+
+	// The endpoint resets the connection; the next batch fails on it and
+	// is sent again over a new one
+	first.conn.SetLinger(0)
+	first.conn.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	write(t, w, "e2", "e3")
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	second := accept()
+	var got []string
+	for range 2 {
+		got = append(got, readLine(second))
+	}
+	if strings.Join(got, ",") != "e2,e3" {
+		t.Errorf("entries after reconnecting = %v, want e2 and e3", got)
+	}
+}
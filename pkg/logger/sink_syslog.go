@@ -0,0 +1,89 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"go.uber.org/zap/zapcore"
+
+	"go-microservice/internal/config"
+)
+
+// syslogFacilities maps facility names to syslog priorities
+var syslogFacilities = map[string]syslog.Priority{
+	"":       syslog.LOG_USER,
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// syslogCore writes entries to syslog with the severity of their level
+type syslogCore struct {
+	zapcore.LevelEnabler
+	encoder zapcore.Encoder
+	writer  *syslog.Writer
+}
+
+// newSyslogCore connects to syslog
+func newSyslogCore(cfg config.LogSyslogConfig, encoder zapcore.Encoder, minLevel zapcore.Level) (zapcore.Core, error) {
+	facility, ok := syslogFacilities[cfg.Facility]
+	if !ok {
+		return nil, fmt.Errorf("unsupported syslog facility %q", cfg.Facility)
+	}
+
+	writer, err := syslog.Dial(cfg.Network, cfg.Address, facility|syslog.LOG_INFO, cfg.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &syslogCore{LevelEnabler: minLevel, encoder: encoder, writer: writer}, nil
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, encoder: encoder, writer: c.writer}
+}
+
+func (c *syslogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *syslogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	msg := buf.String()
+	switch entry.Level {
+	case zapcore.DebugLevel:
+		return c.writer.Debug(msg)
+	case zapcore.InfoLevel:
+		return c.writer.Info(msg)
+	case zapcore.WarnLevel:
+		return c.writer.Warning(msg)
+	case zapcore.ErrorLevel:
+		return c.writer.Err(msg)
+	default:
+		return c.writer.Crit(msg)
+	}
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+
+	"go-microservice/internal/config"
+)
+
+// newSyslogCore fails because syslog is not available on this platform
+func newSyslogCore(config.LogSyslogConfig, zapcore.Encoder, zapcore.Level) (zapcore.Core, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,174 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Administration errors
+var (
+	ErrAdminNotSupported    = errors.New("backend does not support administration")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	ErrTopicNotFound        = errors.New("topic not found")
+	ErrGroupActive          = errors.New("consumer group has active members")
+	ErrInvalidOffsetReset   = errors.New("invalid offset reset")
+)
+
+// Offset reset targets
+const (
+	ResetEarliest  = "earliest"
+	ResetLatest    = "latest"
+	ResetTimestamp = "timestamp"
+)
+
+// TopicInfo describes a topic on the broker
+type TopicInfo struct {
+	Name       string `json:"name"`
+	Partitions int    `json:"partitions"`
+	Exists     bool   `json:"exists"`
+}
+
+// PartitionLag is how far a consumer group is behind on one partition
+type PartitionLag struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Committed int64  `json:"committed"`
+	Latest    int64  `json:"latest"`
+	Lag       int64  `json:"lag"`
+}
+
+// GroupLag is the lag of a consumer group on a set of topics
+type GroupLag struct {
+	Group      string         `json:"group"`
+	Partitions []PartitionLag `json:"partitions"`
+	Total      int64          `json:"total"`
+}
+
+// OffsetReset moves a consumer group to the earliest or latest message, or
+// to the first message published at or after Timestamp
+type OffsetReset struct {
+	To        string    `json:"to"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Validate checks the reset target
+func (r OffsetReset) Validate() error {
+	switch r.To {
+	case ResetEarliest, ResetLatest:
+		return nil
+	case ResetTimestamp:
+		if r.Timestamp.IsZero() {
+			return fmt.Errorf("%w: timestamp is required", ErrInvalidOffsetReset)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown target %q", ErrInvalidOffsetReset, r.To)
+	}
+}
+
+// SubscriptionInfo describes a topic this client consumes
+type SubscriptionInfo struct {
+	Topic  string `json:"topic"`
+	Paused bool   `json:"paused"`
+}
+
+// Admin is implemented by backends that can inspect topics and consumer
+// groups and move a group's position for replay. An empty group means the
+// group the client consumes with.
+type Admin interface {
+	// DescribeTopics returns partition counts of the given topics
+	DescribeTopics(ctx context.Context, topics []string) ([]TopicInfo, error)
+
+	// Lag reports how far group is behind on each partition of topics
+	Lag(ctx context.Context, group string, topics []string) (*GroupLag, error)
+
+	// ResetOffsets moves group on topics. It fails with ErrGroupActive
+	// while members of the group are consuming.
+	ResetOffsets(ctx context.Context, group string, topics []string, reset OffsetReset) error
+}
+
+// SubscriptionController is implemented by backends that can pause and
+// resume the subscriptions of this client. Pausing only affects this
+// instance; replicas keep consuming.
+type SubscriptionController interface {
+	// Subscriptions lists the subscribed topics
+	Subscriptions() []SubscriptionInfo
+
+	// Pause stops consuming topic until it is resumed
+	Pause(topic string) error
+
+	// Resume continues consuming a paused topic
+	Resume(topic string) error
+}
+
+// AdminOf returns the admin interface of client
+func AdminOf(client Messaging) (Admin, error) {
+	admin, ok := client.(Admin)
+	if !ok {
+		return nil, ErrAdminNotSupported
+	}
+	return admin, nil
+}
+
+// SubscriptionControllerOf returns the subscription controller of client
+func SubscriptionControllerOf(client Messaging) (SubscriptionController, error) {
+	controller, ok := client.(SubscriptionController)
+	if !ok {
+		return nil, ErrAdminNotSupported
+	}
+	return controller, nil
+}
+
+// pauseSet tracks paused topics and wakes consumers when it changes
+type pauseSet struct {
+	mu      sync.Mutex
+	paused  map[string]bool
+	changed chan struct{}
+}
+
+func newPauseSet() *pauseSet {
+	return &pauseSet{
+		paused:  make(map[string]bool),
+		changed: make(chan struct{}),
+	}
+}
+
+// set pauses or resumes topic
+func (p *pauseSet) set(topic string, paused bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.paused[topic] == paused {
+		return
+	}
+	if paused {
+		p.paused[topic] = true
+	} else {
+		delete(p.paused, topic)
+	}
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// isPaused reports whether topic is paused
+func (p *pauseSet) isPaused(topic string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused[topic]
+}
+
+// active returns the topics that are not paused and a channel closed on the
+// next change
+func (p *pauseSet) active(topics []string) ([]string, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	active := slices.DeleteFunc(slices.Clone(topics), func(topic string) bool {
+		return p.paused[topic]
+	})
+	return active, p.changed
+}
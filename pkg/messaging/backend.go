@@ -0,0 +1,38 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-microservice/internal/config"
+)
+
+// ErrUnsupportedBackend is returned for a backend name that was never registered
+var ErrUnsupportedBackend = errors.New("unsupported messaging backend")
+
+// BackendFactory creates a messaging backend from the service config
+type BackendFactory func(ctx context.Context, cfg *config.Config) (Messaging, error)
+
+// Registry of available messaging backends
+var backendFactories = make(map[string]BackendFactory)
+
+// RegisterBackend registers a new messaging backend implementation
+func RegisterBackend(name string, factory BackendFactory) {
+	backendFactories[name] = factory
+}
+
+// NewBackend creates the messaging backend registered under name
+func NewBackend(ctx context.Context, name string, cfg *config.Config) (Messaging, error) {
+	factory, exists := backendFactories[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedBackend, name)
+	}
+
+	// Factories may return a typed nil on error, which is not a nil interface
+	client, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
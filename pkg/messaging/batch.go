@@ -0,0 +1,151 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBatchNotSupported is returned by SubscribeBatch for backends that
+// cannot consume in batches
+var ErrBatchNotSupported = errors.New("backend does not support batch consumption")
+
+// Defaults for batch options left empty
+const (
+	DefaultBatchSize = 100
+	DefaultBatchWait = time.Second
+)
+
+// BatchError reports which messages of a published batch failed
+type BatchError struct {
+	// Errors has one entry per message, nil for messages that were published
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d messages failed: %v", failed, len(e.Errors), first)
+}
+
+// Unwrap returns the errors of the failed messages
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// newBatchError returns a BatchError if any message failed
+func newBatchError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &BatchError{Errors: errs}
+		}
+	}
+	return nil
+}
+
+// BatchResults splits the error returned by PublishBatch for a batch of n
+// messages into one error per message. An error that is not a BatchError
+// applies to every message.
+func BatchResults(err error, n int) []error {
+	results := make([]error, n)
+	if err == nil {
+		return results
+	}
+
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) && len(batchErr.Errors) == n {
+		copy(results, batchErr.Errors)
+		return results
+	}
+	for i := range results {
+		results[i] = err
+	}
+	return results
+}
+
+// BatchHandler handles a batch of messages from one topic. An error fails
+// the whole batch, which is then redelivered like a single message.
+type BatchHandler func(ctx context.Context, msgs []*Message) error
+
+// BatchOptions bound a batch: the handler runs once Size messages arrived or
+// Wait passed since the first one
+type BatchOptions struct {
+	Size int
+	Wait time.Duration
+}
+
+// withDefaults fills in options left empty
+func (o BatchOptions) withDefaults() BatchOptions {
+	if o.Size <= 0 {
+		o.Size = DefaultBatchSize
+	}
+	if o.Wait <= 0 {
+		o.Wait = DefaultBatchWait
+	}
+	return o
+}
+
+// BatchSubscriber is implemented by backends that consume in batches
+type BatchSubscriber interface {
+	// SubscribeBatch consumes topics and hands messages to handler in
+	// batches until ctx is done
+	SubscribeBatch(ctx context.Context, topics []string, opts BatchOptions, handler BatchHandler) error
+}
+
+// SubscribeBatch consumes topics through client in batches
+func SubscribeBatch(ctx context.Context, client Messaging, topics []string, opts BatchOptions, handler BatchHandler) error {
+	subscriber, ok := client.(BatchSubscriber)
+	if !ok {
+		return ErrBatchNotSupported
+	}
+	return subscriber.SubscribeBatch(ctx, topics, opts, handler)
+}
+
+// collectBatch reads up to size items from ch. It waits for the first item
+// as long as needed and for the rest at most wait. It returns false once ch
+// is closed or ctx is done.
+func collectBatch[T any](ctx context.Context, ch <-chan T, size int, wait time.Duration) ([]T, bool) {
+	var batch []T
+	select {
+	case item, ok := <-ch:
+		if !ok {
+			return nil, false
+		}
+		batch = append(batch, item)
+	case <-ctx.Done():
+		return nil, false
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for len(batch) < size {
+		select {
+		case item, ok := <-ch:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, item)
+		case <-timer.C:
+			return batch, true
+		case <-ctx.Done():
+			return batch, false
+		}
+	}
+	return batch, true
+}
@@ -0,0 +1,357 @@
+package messaging
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/hamba/avro/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Payload encodings supported by the event codec
+const (
+	EncodingJSON     = "json"
+	EncodingAvro     = "avro"
+	EncodingProtobuf = "protobuf"
+)
+
+// protobufFirstMessageIndex is the encoded message index list [0], which
+// selects the first message declared in a Protobuf schema
+var protobufFirstMessageIndex = []byte{0}
+
+// binaryCodec encodes event data with a registry-backed schema format
+type binaryCodec interface {
+	// format returns the local schema file format the codec reads
+	format() string
+	// registryType returns the registry schema type the codec registers
+	registryType() string
+	// contentType returns the content type of encoded payloads
+	contentType() string
+	// encode converts JSON data to the binary format, framed with schema ID
+	encode(id int, schema string, data []byte) ([]byte, error)
+	// decode converts a framed payload without its magic byte and ID back to JSON
+	decode(schema string, payload []byte) ([]byte, error)
+}
+
+// avroCodec encodes data as Avro
+type avroCodec struct {
+	mu      sync.Mutex
+	schemas map[string]avro.Schema
+}
+
+func (c *avroCodec) format() string       { return SchemaFormatAvro }
+func (c *avroCodec) registryType() string { return RegistrySchemaAvro }
+func (c *avroCodec) contentType() string  { return ContentTypeAvro }
+
+func (c *avroCodec) encode(id int, source string, data []byte) ([]byte, error) {
+	schema, err := c.parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	value, err = avroValue(schema, value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := avro.Marshal(schema, value)
+	if err != nil {
+		return nil, err
+	}
+	return frameWire(id, nil, encoded), nil
+}
+
+func (c *avroCodec) decode(source string, payload []byte) ([]byte, error) {
+	schema, err := c.parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := avro.Unmarshal(schema, payload, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// parse parses an Avro schema, caching the result
+func (c *avroCodec) parse(source string) (avro.Schema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if schema, ok := c.schemas[source]; ok {
+		return schema, nil
+	}
+	schema, err := avro.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Avro schema: %w", err)
+	}
+	c.schemas[source] = schema
+	return schema, nil
+}
+
+// avroValue converts a decoded JSON value to the Go types the Avro encoder
+// expects for schema, such as int64 for longs instead of float64
+func avroValue(schema avro.Schema, value interface{}) (interface{}, error) {
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return avroValue(s.Schema(), value)
+	case *avro.RecordSchema:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object for record %s", s.Name())
+		}
+		record := make(map[string]interface{}, len(s.Fields()))
+		for _, field := range s.Fields() {
+			v, ok := fields[field.Name()]
+			if !ok {
+				if !field.HasDefault() {
+					return nil, fmt.Errorf("missing field %s.%s", s.Name(), field.Name())
+				}
+				v = field.Default()
+			}
+			converted, err := avroValue(field.Type(), v)
+			if err != nil {
+				return nil, err
+			}
+			record[field.Name()] = converted
+		}
+		return record, nil
+	case *avro.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array")
+		}
+		converted := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := avroValue(s.Items(), item)
+			if err != nil {
+				return nil, err
+			}
+			converted[i] = v
+		}
+		return converted, nil
+	case *avro.MapSchema:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object for map")
+		}
+		converted := make(map[string]interface{}, len(entries))
+		for k, entry := range entries {
+			v, err := avroValue(s.Values(), entry)
+			if err != nil {
+				return nil, err
+			}
+			converted[k] = v
+		}
+		return converted, nil
+	case *avro.UnionSchema:
+		if value == nil && s.Nullable() {
+			return nil, nil
+		}
+		for _, option := range s.Types() {
+			if option.Type() == avro.Null {
+				continue
+			}
+			if v, err := avroValue(option, value); err == nil {
+				return v, nil
+			}
+		}
+		return nil, fmt.Errorf("value matches no union branch")
+	case *avro.EnumSchema:
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("expected string for enum %s", s.Name())
+		}
+		return value, nil
+	case *avro.PrimitiveSchema:
+		return avroPrimitive(s.Type(), value)
+	default:
+		return nil, fmt.Errorf("unsupported Avro schema type %s", schema.Type())
+	}
+}
+
+// avroPrimitive converts a decoded JSON scalar to the Go type of an Avro primitive
+func avroPrimitive(typ avro.Type, value interface{}) (interface{}, error) {
+	switch typ {
+	case avro.Null:
+		if value != nil {
+			return nil, fmt.Errorf("expected null")
+		}
+		return nil, nil
+	case avro.Boolean:
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("expected boolean")
+		}
+		return value, nil
+	case avro.String:
+		if _, ok := value.(string); !ok {
+			return nil, fmt.Errorf("expected string")
+		}
+		return value, nil
+	case avro.Bytes:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected base64 string for bytes")
+		}
+		return base64.StdEncoding.DecodeString(s)
+	}
+
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("expected number for %s", typ)
+	}
+	switch typ {
+	case avro.Int:
+		if n != math.Trunc(n) || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("%v is not an int", n)
+		}
+		return int(n), nil
+	case avro.Long:
+		if n != math.Trunc(n) {
+			return nil, fmt.Errorf("%v is not a long", n)
+		}
+		return int64(n), nil
+	case avro.Float:
+		return float32(n), nil
+	case avro.Double:
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unsupported Avro type %s", typ)
+	}
+}
+
+// protobufCodec encodes data as Protobuf using the first message of a schema
+type protobufCodec struct {
+	mu          sync.Mutex
+	descriptors map[string]protoreflect.MessageDescriptor
+}
+
+func (c *protobufCodec) format() string       { return SchemaFormatProtobuf }
+func (c *protobufCodec) registryType() string { return RegistrySchemaProtobuf }
+func (c *protobufCodec) contentType() string  { return ContentTypeProtobuf }
+
+func (c *protobufCodec) encode(id int, source string, data []byte) ([]byte, error) {
+	descriptor, err := c.compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := dynamicpb.NewMessage(descriptor)
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	encoded, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return frameWire(id, protobufFirstMessageIndex, encoded), nil
+}
+
+func (c *protobufCodec) decode(source string, payload []byte) ([]byte, error) {
+	descriptor, err := c.compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only schemas whose first message is used are supported
+	if len(payload) == 0 || payload[0] != protobufFirstMessageIndex[0] {
+		return nil, fmt.Errorf("%w: unsupported Protobuf message index", ErrInvalidWireFormat)
+	}
+
+	msg := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(payload[1:], msg); err != nil {
+		return nil, err
+	}
+	return json.Marshal(protoMessageValue(msg))
+}
+
+// protoMessageValue converts a message to JSON-ready Go values keyed by field
+// name. Unlike protojson, 64-bit integers stay numbers so decoded data
+// validates against the same JSON Schema as JSON-encoded events.
+func protoMessageValue(msg protoreflect.Message) map[string]interface{} {
+	fields := msg.Descriptor().Fields()
+	value := make(map[string]interface{}, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.HasPresence() && !msg.Has(field) {
+			continue
+		}
+		v := msg.Get(field)
+		switch {
+		case field.IsList():
+			list := v.List()
+			items := make([]interface{}, list.Len())
+			for j := range items {
+				items[j] = protoFieldValue(field, list.Get(j))
+			}
+			value[string(field.Name())] = items
+		case field.IsMap():
+			entries := make(map[string]interface{}, v.Map().Len())
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				entries[k.String()] = protoFieldValue(field.MapValue(), mv)
+				return true
+			})
+			value[string(field.Name())] = entries
+		default:
+			value[string(field.Name())] = protoFieldValue(field, v)
+		}
+	}
+	return value
+}
+
+// protoFieldValue converts a single (non-repeated) field value
+func protoFieldValue(field protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoMessageValue(v.Message())
+	case protoreflect.EnumKind:
+		if enum := field.Enum().Values().ByNumber(v.Enum()); enum != nil {
+			return string(enum.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	default:
+		return v.Interface()
+	}
+}
+
+// compile compiles a Protobuf schema and returns its first message, caching the result
+func (c *protobufCodec) compile(source string) (protoreflect.MessageDescriptor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if descriptor, ok := c.descriptors[source]; ok {
+		return descriptor, nil
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{"schema.proto": source}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), "schema.proto")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile Protobuf schema: %w", err)
+	}
+	messages := files[0].Messages()
+	if messages.Len() == 0 {
+		return nil, fmt.Errorf("Protobuf schema declares no messages")
+	}
+
+	descriptor := messages.Get(0)
+	c.descriptors[source] = descriptor
+	return descriptor, nil
+}
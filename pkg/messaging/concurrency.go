@@ -0,0 +1,122 @@
+package messaging
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"go-microservice/internal/config"
+)
+
+// concurrencyLimits returns the worker count and in-flight bound for a
+// topic. Topic settings override the defaults; without any settings
+// messages are handled one at a time.
+func concurrencyLimits(cfg config.ConcurrencyConfig, topic string) (workers, maxInFlight int) {
+	workers, maxInFlight = cfg.Workers, cfg.MaxInFlight
+	if override, ok := cfg.Topics[topic]; ok {
+		if override.Workers > 0 {
+			workers = override.Workers
+		}
+		if override.MaxInFlight > 0 {
+			maxInFlight = override.MaxInFlight
+		}
+	}
+
+	workers = max(workers, 1)
+	if maxInFlight <= 0 {
+		maxInFlight = workers
+	}
+	return workers, maxInFlight
+}
+
+// workerPool runs tasks on a fixed set of workers. Tasks with the same key
+// always run on the same worker, in the order they were submitted; tasks
+// without a key are spread round robin. Callers take a slot before
+// submitting and release it once the message is done, which bounds the
+// messages in flight and pushes back on the consumer when all are taken.
+type workerPool struct {
+	queues []chan func()
+	slots  chan struct{}
+	next   atomic.Uint64
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// newWorkerPool starts workers that share at most maxInFlight slots
+func newWorkerPool(workers, maxInFlight int) *workerPool {
+	p := &workerPool{
+		queues: make([]chan func(), workers),
+		slots:  make(chan struct{}, maxInFlight),
+	}
+
+	for i := range p.queues {
+		// A queue never holds more tasks than there are slots, so
+		// submitting does not block
+		queue := make(chan func(), maxInFlight)
+		p.queues[i] = queue
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for task := range queue {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// acquire takes a slot, waiting while all are in use. It returns false if
+// ctx is done first.
+func (p *workerPool) acquire(ctx context.Context) bool {
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release gives back a slot taken with acquire
+func (p *workerPool) release() {
+	<-p.slots
+}
+
+// submit queues a task on the worker for key. It returns false if the pool
+// was stopped, in which case the task does not run.
+func (p *workerPool) submit(key string, task func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return false
+	}
+
+	var i uint64
+	if key == "" {
+		i = p.next.Add(1)
+	} else {
+		hash := fnv.New64a()
+		hash.Write([]byte(key))
+		i = hash.Sum64()
+	}
+	p.queues[i%uint64(len(p.queues))] <- task
+	return true
+}
+
+// stop waits for the queued tasks to finish and stops the workers
+func (p *workerPool) stop() {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
@@ -0,0 +1,281 @@
+package messaging
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go-microservice/internal/config"
+	"go-microservice/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErrDuplicateInProgress is returned while another consumer is processing the
+// same message. The Retrier waits for that consumer without counting failed
+// attempts and never forwards the message to retry or dead-letter topics.
+var ErrDuplicateInProgress = errors.New("duplicate message is being processed")
+
+// ErrDedupLockLost is returned when extending a processing lock that expired
+// or was taken over by another consumer
+var ErrDedupLockLost = errors.New("message processing lock lost")
+
+// Default deduplication settings used when the config leaves them empty
+const (
+	defaultDedupTTL     = 24 * time.Hour
+	defaultDedupLockTTL = 30 * time.Second
+)
+
+// DedupStatus is the outcome of claiming a message ID
+type DedupStatus int
+
+const (
+	// DedupClaimed means the caller holds the processing lock
+	DedupClaimed DedupStatus = iota
+	// DedupProcessed means the message was already processed
+	DedupProcessed
+	// DedupInProgress means another consumer holds the processing lock
+	DedupInProgress
+)
+
+// DedupStore records which message IDs have been processed
+type DedupStore interface {
+	// Claim takes the processing lock for id for lockTTL. The returned token
+	// identifies the lock holder and is only valid with status DedupClaimed.
+	Claim(ctx context.Context, id string, lockTTL time.Duration) (DedupStatus, string, error)
+
+	// Complete marks id as processed and keeps the record for ttl
+	Complete(ctx context.Context, id string, ttl time.Duration) error
+
+	// Extend renews the processing lock for another lockTTL if it is still
+	// held with token, and returns ErrDedupLockLost otherwise
+	Extend(ctx context.Context, id, token string, lockTTL time.Duration) error
+
+	// Abandon releases the processing lock if it is still held with token,
+	// so the message can be processed again
+	Abandon(ctx context.Context, id, token string) error
+}
+
+// Deduplicator skips messages whose ID has already been processed
+type Deduplicator struct {
+	store     DedupStore
+	namespace string
+	ttl       time.Duration
+	lockTTL   time.Duration
+}
+
+// NewDeduplicator creates a deduplicator. IDs are recorded under namespace,
+// typically the consumer group, so different consumers track them separately.
+func NewDeduplicator(store DedupStore, namespace string, cfg config.DedupConfig) *Deduplicator {
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultDedupTTL
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = defaultDedupLockTTL
+	}
+
+	return &Deduplicator{
+		store:     store,
+		namespace: namespace,
+		ttl:       cfg.TTL,
+		lockTTL:   cfg.LockTTL,
+	}
+}
+
+// Wrap returns a handler that runs handler at most once per message ID.
+// Messages without an ID are always handled. The processing lock is renewed
+// while the handler runs, and a failed handler releases it so the message
+// can be retried.
+func (d *Deduplicator) Wrap(handler Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		id := msg.Headers[HeaderMessageID]
+		if id == "" {
+			return handler(ctx, msg)
+		}
+		key := d.namespace + ":" + id
+
+		status, token, err := d.store.Claim(ctx, key, d.lockTTL)
+		if err != nil {
+			return err
+		}
+
+		switch status {
+		case DedupProcessed:
+			logger.FromContext(ctx).Debug("Skipping duplicate message",
+				zap.String("topic", msg.Topic),
+				zap.String("message_id", id))
+			return nil
+		case DedupInProgress:
+			return ErrDuplicateInProgress
+		}
+
+		stop := d.keepLock(ctx, key, token)
+		err = handler(ctx, msg)
+		stop()
+
+		if err != nil {
+			// Use a fresh context so the lock is released during shutdown too
+			if abandonErr := d.store.Abandon(context.WithoutCancel(ctx), key, token); abandonErr != nil {
+				logger.FromContext(ctx).Warn("Failed to release message lock",
+					zap.String("message_id", id),
+					zap.Error(abandonErr))
+			}
+			return err
+		}
+
+		// The message has been handled; failing to record it only risks a duplicate later
+		if err := d.store.Complete(context.WithoutCancel(ctx), key, d.ttl); err != nil {
+			logger.FromContext(ctx).Warn("Failed to record processed message",
+				zap.String("message_id", id),
+				zap.Error(err))
+		}
+		return nil
+	}
+}
+
+// keepLock extends the processing lock of key every third of the lock TTL
+// until the returned function is called
+func (d *Deduplicator) keepLock(ctx context.Context, key, token string) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(d.lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := d.store.Extend(context.WithoutCancel(ctx), key, token, d.lockTTL); err != nil {
+					logger.FromContext(ctx).Warn("Failed to extend message lock", zap.String("key", key), zap.Error(err))
+					if errors.Is(err, ErrDedupLockLost) {
+						return
+					}
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// MemoryDedupStore is an in-memory DedupStore for single-instance deployments
+// and tests. Entries expire in deadline order, so each call only does work
+// for the entries that expired.
+type MemoryDedupStore struct {
+	mu      sync.Mutex
+	entries map[string]dedupEntry
+	expiry  dedupExpiry
+}
+
+// dedupEntry is a processing lock, or a processed record when token is empty
+type dedupEntry struct {
+	token   string
+	expires time.Time
+}
+
+// NewMemoryDedupStore creates an empty in-memory deduplication store
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{
+		entries: make(map[string]dedupEntry),
+	}
+}
+
+// Claim implements the DedupStore interface
+func (s *MemoryDedupStore) Claim(ctx context.Context, id string, lockTTL time.Duration) (DedupStatus, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	if entry, ok := s.entries[id]; ok {
+		if entry.token == "" {
+			return DedupProcessed, "", nil
+		}
+		return DedupInProgress, "", nil
+	}
+
+	token := uuid.New().String()
+	s.set(id, dedupEntry{token: token, expires: now.Add(lockTTL)})
+	return DedupClaimed, token, nil
+}
+
+// Complete implements the DedupStore interface
+func (s *MemoryDedupStore) Complete(ctx context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(id, dedupEntry{expires: time.Now().Add(ttl)})
+	return nil
+}
+
+// Extend implements the DedupStore interface
+func (s *MemoryDedupStore) Extend(ctx context.Context, id, token string, lockTTL time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	if entry, ok := s.entries[id]; !ok || entry.token != token {
+		return ErrDedupLockLost
+	}
+	s.set(id, dedupEntry{token: token, expires: now.Add(lockTTL)})
+	return nil
+}
+
+// Abandon implements the DedupStore interface
+func (s *MemoryDedupStore) Abandon(ctx context.Context, id, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[id]; ok && entry.token == token {
+		delete(s.entries, id)
+	}
+	return nil
+}
+
+// set stores an entry and schedules its expiry. Callers hold s.mu.
+func (s *MemoryDedupStore) set(id string, entry dedupEntry) {
+	s.entries[id] = entry
+	heap.Push(&s.expiry, dedupDeadline{id: id, expires: entry.expires})
+}
+
+// expire removes entries whose TTL has passed. Deadlines of entries that
+// were replaced or removed since are dropped as they come up. Callers hold
+// s.mu.
+func (s *MemoryDedupStore) expire(now time.Time) {
+	for len(s.expiry) > 0 && now.After(s.expiry[0].expires) {
+		deadline := heap.Pop(&s.expiry).(dedupDeadline)
+		if entry, ok := s.entries[deadline.id]; ok && entry.expires.Equal(deadline.expires) {
+			delete(s.entries, deadline.id)
+		}
+	}
+}
+
+// dedupDeadline is when an entry expires
+type dedupDeadline struct {
+	id      string
+	expires time.Time
+}
+
+// dedupExpiry is a min-heap of deadlines, see container/heap
+type dedupExpiry []dedupDeadline
+
+func (h dedupExpiry) Len() int           { return len(h) }
+func (h dedupExpiry) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+func (h dedupExpiry) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *dedupExpiry) Push(x any)        { *h = append(*h, x.(dedupDeadline)) }
+func (h *dedupExpiry) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
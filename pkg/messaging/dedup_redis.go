@@ -0,0 +1,100 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	database "go-microservice/pkg/cache"
+)
+
+// processedMarker is stored for message IDs that have been processed
+const processedMarker = "processed"
+
+// claimScript takes the processing lock unless the ID is processed or locked.
+// KEYS: dedup key. ARGV: lock token, lock TTL in milliseconds, processed marker.
+// Returns 0 when claimed, 1 when processed, 2 when locked by someone else.
+const claimScript = `
+local current = redis.call('GET', KEYS[1])
+if current == ARGV[3] then
+	return 1
+end
+if current then
+	return 2
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 0
+`
+
+// abandonScript releases the lock only if it is still held with the given token
+const abandonScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// extendScript renews the lock only if it is still held with the given token.
+// KEYS: dedup key. ARGV: lock token, lock TTL in milliseconds.
+const extendScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`
+
+// RedisDedupStore keeps processed message IDs in Redis so they are shared by all replicas
+type RedisDedupStore struct {
+	client *database.RedisClient
+	prefix string
+}
+
+// NewRedisDedupStore creates a Redis-backed deduplication store
+func NewRedisDedupStore(client *database.RedisClient) *RedisDedupStore {
+	return &RedisDedupStore{
+		client: client,
+		prefix: "messaging:dedup:",
+	}
+}
+
+// Claim implements the DedupStore interface
+func (s *RedisDedupStore) Claim(ctx context.Context, id string, lockTTL time.Duration) (DedupStatus, string, error) {
+	token := uuid.New().String()
+	result, err := s.client.Eval(ctx, claimScript, []string{s.prefix + id}, token, lockTTL.Milliseconds(), processedMarker)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to claim message %s: %w", id, err)
+	}
+
+	status, ok := result.(int64)
+	if !ok {
+		return 0, "", fmt.Errorf("unexpected claim result %v", result)
+	}
+	if DedupStatus(status) != DedupClaimed {
+		return DedupStatus(status), "", nil
+	}
+	return DedupClaimed, token, nil
+}
+
+// Complete implements the DedupStore interface
+func (s *RedisDedupStore) Complete(ctx context.Context, id string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+id, processedMarker, ttl)
+}
+
+// Extend implements the DedupStore interface
+func (s *RedisDedupStore) Extend(ctx context.Context, id, token string, lockTTL time.Duration) error {
+	result, err := s.client.Eval(ctx, extendScript, []string{s.prefix + id}, token, lockTTL.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to extend lock of message %s: %w", id, err)
+	}
+	if extended, _ := result.(int64); extended != 1 {
+		return ErrDedupLockLost
+	}
+	return nil
+}
+
+// Abandon implements the DedupStore interface
+func (s *RedisDedupStore) Abandon(ctx context.Context, id, token string) error {
+	_, err := s.client.Eval(ctx, abandonScript, []string{s.prefix + id}, token)
+	return err
+}
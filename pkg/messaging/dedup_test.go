@@ -0,0 +1,112 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-microservice/internal/config"
+)
+
+func TestMemoryDedupStoreExpiresEntries(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemoryDedupStore()
+
+	// A lock replaced by a processed record outlives the lock's deadline
+	if status, _, _ := store.Claim(ctx, "kept", 10*time.Millisecond); status != DedupClaimed {
+		t.Fatalf("Claim(kept) = %v, want claimed", status)
+	}
+	if err := store.Complete(ctx, "kept", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// A lock that is never completed expires
+	if status, _, _ := store.Claim(ctx, "abandoned", 10*time.Millisecond); status != DedupClaimed {
+		t.Fatalf("Claim(abandoned) = %v, want claimed", status)
+	}
+	// A processed record expires after its TTL
+	if err := store.Complete(ctx, "short", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	tests := []struct {
+		id   string
+		want DedupStatus
+	}{
+		{"kept", DedupProcessed},
+		{"abandoned", DedupClaimed},
+		{"short", DedupClaimed},
+	}
+	for _, tt := range tests {
+		if status, _, err := store.Claim(ctx, tt.id, time.Hour); err != nil || status != tt.want {
+			t.Errorf("Claim(%s) = %v, %v, want %v", tt.id, status, err, tt.want)
+		}
+	}
+
+	// Only the live entries are left: kept and the two new locks
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.entries) != 3 || len(store.expiry) != 3 {
+		t.Errorf("%d entries and %d deadlines left, want 3 of each", len(store.entries), len(store.expiry))
+	}
+}
+
+func TestMemoryDedupStoreExtend(t *testing.T) {
+	ctx := t.Context()
+	store := NewMemoryDedupStore()
+
+	_, token, _ := store.Claim(ctx, "m1", 20*time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if err := store.Extend(ctx, "m1", token, 50*time.Millisecond); err != nil {
+		t.Fatalf("Extend() error = %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if status, _, _ := store.Claim(ctx, "m1", time.Hour); status != DedupInProgress {
+		t.Errorf("Claim() after Extend = %v, want in progress", status)
+	}
+
+	if err := store.Extend(ctx, "m1", "other-token", time.Hour); !errors.Is(err, ErrDedupLockLost) {
+		t.Errorf("Extend() with another token error = %v, want ErrDedupLockLost", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := store.Extend(ctx, "m1", token, time.Hour); !errors.Is(err, ErrDedupLockLost) {
+		t.Errorf("Extend() of an expired lock error = %v, want ErrDedupLockLost", err)
+	}
+}
+
+func TestDeduplicatorKeepsLockWhileHandlerRuns(t *testing.T) {
+	store := NewMemoryDedupStore()
+	dedup := NewDeduplicator(store, "group", config.DedupConfig{LockTTL: 30 * time.Millisecond})
+
+	// The handler runs for several lock TTLs while a second consumer
+	// receives the same message
+	running := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- dedup.Wrap(func(ctx context.Context, msg *Message) error {
+			close(running)
+			time.Sleep(150 * time.Millisecond)
+			return nil
+		})(t.Context(), &Message{Headers: map[string]string{HeaderMessageID: "m1"}})
+	}()
+
+	<-running
+	time.Sleep(100 * time.Millisecond)
+	var calls int
+	err := dedup.Wrap(func(ctx context.Context, msg *Message) error {
+		calls++
+		return nil
+	})(t.Context(), &Message{Headers: map[string]string{HeaderMessageID: "m1"}})
+	if !errors.Is(err, ErrDuplicateInProgress) || calls != 0 {
+		t.Errorf("second consumer got %v after %d calls, want ErrDuplicateInProgress", err, calls)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if status, _, _ := store.Claim(context.Background(), "group:m1", time.Hour); status != DedupProcessed {
+		t.Errorf("Claim() after the handler finished = %v, want processed", status)
+	}
+}
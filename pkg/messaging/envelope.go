@@ -0,0 +1,236 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+	"go-microservice/internal/config"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// defaultEventSource is used when no event source is configured
+const defaultEventSource = "go-microservice"
+
+// eventContextKey stores the decoded event in a handler's context
+type eventContextKey struct{}
+
+// EventFromContext returns the event envelope of the message being handled
+func EventFromContext(ctx context.Context) (*Event, bool) {
+	event, ok := ctx.Value(eventContextKey{}).(*Event)
+	return event, ok
+}
+
+// EventCodec wraps payloads in CloudEvents envelopes and unwraps them again.
+// Event data is validated against JSON Schemas from a local directory and,
+// with Avro or Protobuf encoding, encoded with schemas registered in a
+// Confluent-compatible schema registry. JSON events use the structured mode
+// of the CloudEvents Kafka binding; Avro and Protobuf events use binary mode
+// with the attributes in ce_ headers.
+type EventCodec struct {
+	source          string
+	schemas         *SchemaSet
+	registry        *SchemaRegistryClient
+	encoding        binaryCodec
+	decoders        map[string]binaryCodec
+	validatePublish bool
+	validateConsume bool
+}
+
+// NewEventCodec creates an event codec from the events config
+func NewEventCodec(cfg config.EventsConfig) (*EventCodec, error) {
+	c := &EventCodec{
+		source:          cfg.Source,
+		validatePublish: cfg.ValidateOnPublish,
+		validateConsume: cfg.ValidateOnConsume,
+		decoders: map[string]binaryCodec{
+			ContentTypeAvro:     &avroCodec{schemas: make(map[string]avro.Schema)},
+			ContentTypeProtobuf: &protobufCodec{descriptors: make(map[string]protoreflect.MessageDescriptor)},
+		},
+	}
+	if c.source == "" {
+		c.source = defaultEventSource
+	}
+
+	if cfg.SchemaDir != "" {
+		schemas, err := LoadSchemas(cfg.SchemaDir)
+		if err != nil {
+			return nil, err
+		}
+		c.schemas = schemas
+	}
+
+	if cfg.Registry.URL != "" {
+		c.registry = NewSchemaRegistryClient(cfg.Registry.URL, cfg.Registry.Username, cfg.Registry.Password, cfg.Registry.Timeout)
+	}
+
+	switch cfg.Encoding {
+	case "", EncodingJSON:
+	case EncodingAvro, EncodingProtobuf:
+		if c.schemas == nil || c.registry == nil {
+			return nil, fmt.Errorf("%s encoding requires a schema directory and a schema registry", cfg.Encoding)
+		}
+		if cfg.Encoding == EncodingAvro {
+			c.encoding = c.decoders[ContentTypeAvro]
+		} else {
+			c.encoding = c.decoders[ContentTypeProtobuf]
+		}
+	default:
+		return nil, fmt.Errorf("unsupported event encoding %q", cfg.Encoding)
+	}
+
+	return c, nil
+}
+
+// NewEvent creates an event with the codec's source
+func (c *EventCodec) NewEvent(eventType string, data json.RawMessage) *Event {
+	return NewEvent(eventType, c.source, data)
+}
+
+// Encode validates an event's data and converts the event into a message for topic
+func (c *EventCodec) Encode(ctx context.Context, topic string, event *Event) (*Message, error) {
+	version := 0
+	if c.schemas != nil && event.DataSchema == "" {
+		if latest, ok := c.schemas.Latest(event.Type); ok {
+			version = latest
+			event.DataSchema = SchemaRef(event.Type, version)
+		}
+	}
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.validatePublish {
+		if err := c.validate(event); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.encoding == nil {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal event: %w", err)
+		}
+		return &Message{
+			Topic:   topic,
+			Payload: payload,
+			Headers: map[string]string{HeaderContentType: ContentTypeCloudEvent},
+		}, nil
+	}
+
+	// Binary encodings need the schema of the exact version being written
+	if version == 0 {
+		return nil, fmt.Errorf("%w: no %s schema for event type %s", ErrSchemaNotFound, c.encoding.format(), event.Type)
+	}
+	source, err := c.schemas.Source(event.Type, version, c.encoding.format())
+	if err != nil {
+		return nil, err
+	}
+	id, err := c.registry.Register(ctx, topic+"-value", c.encoding.registryType(), source)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := c.encoding.encode(id, source, event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event data: %w", c.encoding.format(), err)
+	}
+
+	return &Message{
+		Topic:   topic,
+		Payload: payload,
+		Headers: event.binaryHeaders(c.encoding.contentType()),
+	}, nil
+}
+
+// Decode extracts the event from a message. It returns nil for messages that
+// do not carry an event envelope.
+func (c *EventCodec) Decode(ctx context.Context, msg *Message) (*Event, error) {
+	event, err := eventFromStructured(msg)
+	if err != nil || event != nil {
+		return c.checked(event, err)
+	}
+
+	event, err = eventFromHeaders(msg.Headers)
+	if err != nil || event == nil {
+		return event, err
+	}
+
+	decoder, ok := c.decoders[event.DataContentType]
+	if !ok {
+		event.Data = msg.Payload
+		return c.checked(event, nil)
+	}
+	if c.registry == nil {
+		return nil, fmt.Errorf("%w: %s event without a configured registry", ErrSchemaRegistry, event.DataContentType)
+	}
+
+	id, payload, err := unframeWire(msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := c.registry.Schema(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event.Data, err = decoder.decode(schema.Schema, payload); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode data: %v", ErrInvalidEvent, err)
+	}
+	return c.checked(event, nil)
+}
+
+// Middleware unwraps events before they reach a handler. The handler receives
+// the event data as payload and can read the envelope with EventFromContext.
+// Messages without an envelope are passed on unchanged. Malformed or invalid
+// events fail permanently; registry errors are retried.
+func (c *EventCodec) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			event, err := c.Decode(ctx, msg)
+			if err != nil {
+				if errors.Is(err, ErrSchemaRegistry) {
+					return err
+				}
+				return Permanent(err)
+			}
+			if event == nil {
+				return next(ctx, msg)
+			}
+
+			unwrapped := *msg
+			unwrapped.Payload = event.Data
+			return next(context.WithValue(ctx, eventContextKey{}, event), &unwrapped)
+		}
+	}
+}
+
+// checked validates a decoded event's data if consumer validation is enabled
+func (c *EventCodec) checked(event *Event, err error) (*Event, error) {
+	if err != nil {
+		return nil, err
+	}
+	if c.validateConsume {
+		if err := c.validate(event); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// validate checks event data against the JSON Schema named in its dataschema
+// attribute, or the latest schema of its type
+func (c *EventCodec) validate(event *Event) error {
+	if c.schemas == nil {
+		return nil
+	}
+
+	eventType, version, ok := parseSchemaRef(event.DataSchema)
+	if !ok {
+		eventType = event.Type
+		if version, ok = c.schemas.Latest(eventType); !ok {
+			return nil
+		}
+	}
+	return c.schemas.Validate(eventType, version, event.Data)
+}
@@ -0,0 +1,127 @@
+package messaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CloudEventsSpecVersion is the CloudEvents version written by this package
+const CloudEventsSpecVersion = "1.0"
+
+// Content types of event payloads
+const (
+	ContentTypeJSON       = "application/json"
+	ContentTypeCloudEvent = "application/cloudevents+json"
+	ContentTypeAvro       = "application/avro"
+	ContentTypeProtobuf   = "application/protobuf"
+)
+
+// Headers used by binary-mode events, following the CloudEvents Kafka binding
+const (
+	HeaderContentType       = "content-type"
+	headerCloudEventsPrefix = "ce_"
+)
+
+// ErrInvalidEvent is returned for messages that carry a malformed event envelope
+var ErrInvalidEvent = errors.New("invalid event envelope")
+
+// Event is a CloudEvents envelope around a message payload
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewEvent creates an event with a new ID and the current time
+func NewEvent(eventType, source string, data json.RawMessage) *Event {
+	return &Event{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              uuid.New().String(),
+		Type:            eventType,
+		Source:          source,
+		Time:            time.Now().UTC(),
+		DataContentType: ContentTypeJSON,
+		Data:            data,
+	}
+}
+
+// Validate checks that the required CloudEvents attributes are set
+func (e *Event) Validate() error {
+	switch {
+	case e.SpecVersion != CloudEventsSpecVersion:
+		return fmt.Errorf("%w: unsupported specversion %q", ErrInvalidEvent, e.SpecVersion)
+	case e.ID == "":
+		return fmt.Errorf("%w: missing id", ErrInvalidEvent)
+	case e.Type == "":
+		return fmt.Errorf("%w: missing type", ErrInvalidEvent)
+	case e.Source == "":
+		return fmt.Errorf("%w: missing source", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// binaryHeaders returns the event attributes as binary-mode headers
+func (e *Event) binaryHeaders(contentType string) map[string]string {
+	headers := map[string]string{
+		headerCloudEventsPrefix + "specversion": e.SpecVersion,
+		headerCloudEventsPrefix + "id":          e.ID,
+		headerCloudEventsPrefix + "type":        e.Type,
+		headerCloudEventsPrefix + "source":      e.Source,
+		headerCloudEventsPrefix + "time":        e.Time.Format(time.RFC3339Nano),
+		HeaderContentType:                       contentType,
+	}
+	if e.DataSchema != "" {
+		headers[headerCloudEventsPrefix+"dataschema"] = e.DataSchema
+	}
+	return headers
+}
+
+// eventFromHeaders reads the attributes of a binary-mode event. It returns
+// nil if the message does not carry a binary-mode event.
+func eventFromHeaders(headers map[string]string) (*Event, error) {
+	specVersion := headers[headerCloudEventsPrefix+"specversion"]
+	if specVersion == "" {
+		return nil, nil
+	}
+
+	event := &Event{
+		SpecVersion:     specVersion,
+		ID:              headers[headerCloudEventsPrefix+"id"],
+		Type:            headers[headerCloudEventsPrefix+"type"],
+		Source:          headers[headerCloudEventsPrefix+"source"],
+		DataContentType: headers[HeaderContentType],
+		DataSchema:      headers[headerCloudEventsPrefix+"dataschema"],
+	}
+	if t := headers[headerCloudEventsPrefix+"time"]; t != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid time %q", ErrInvalidEvent, t)
+		}
+		event.Time = parsed
+	}
+	return event, event.Validate()
+}
+
+// eventFromStructured parses a structured-mode event. It returns nil if the
+// payload is not a CloudEvents envelope.
+func eventFromStructured(msg *Message) (*Event, error) {
+	if !strings.HasPrefix(msg.Headers[HeaderContentType], ContentTypeCloudEvent) {
+		return nil, nil
+	}
+
+	var event Event
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	return &event, event.Validate()
+}
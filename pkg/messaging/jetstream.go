@@ -0,0 +1,596 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go-microservice/internal/config"
+	"go-microservice/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Defaults for JetStream settings left empty in the config
+const (
+	defaultJetStreamDurable    = "go-microservice"
+	defaultJetStreamFetchBatch = 100
+	defaultJetStreamNakDelay   = time.Second
+	defaultJetStreamAckWait    = 30 * time.Second
+)
+
+// JetStream implements the Messaging interface on NATS JetStream. Messages
+// are stored in a single stream and consumed through durable pull consumers,
+// one per subject. Every replica of the service binds to the same durable
+// consumers, so the messages of a subject are load-balanced between them
+// like a queue group. Messages are acked after the handler succeeds and
+// nak'ed with a delay from the configured backoff when it fails; while the
+// handler runs they are reported in progress. Handlers run on a worker pool
+// per subject.
+type JetStream struct {
+	conn        *nats.Conn
+	js          jetstream.JetStream
+	config      config.NATSConfig
+	concurrency config.ConcurrencyConfig
+
+	mu            sync.Mutex
+	subscriptions []*jetStreamSubscription
+	paused        map[string]bool
+
+	// Batch consumers stop once done is closed
+	done    chan struct{}
+	batches sync.WaitGroup
+}
+
+func init() {
+	RegisterBackend("nats", func(ctx context.Context, cfg *config.Config) (Messaging, error) {
+		return NewJetStream(ctx, cfg.NATS, cfg.Messaging.Concurrency)
+	})
+}
+
+// NewJetStream connects to NATS and creates or updates the configured stream.
+// Subscriptions handle messages with the given concurrency.
+func NewJetStream(ctx context.Context, cfg config.NATSConfig, concurrency config.ConcurrencyConfig) (*JetStream, error) {
+	if cfg.Stream.Name == "" || len(cfg.Stream.Subjects) == 0 {
+		return nil, errors.New("nats stream name and subjects are required")
+	}
+	if cfg.Consumer.Durable == "" {
+		cfg.Consumer.Durable = defaultJetStreamDurable
+	}
+	if cfg.Consumer.FetchBatch <= 0 {
+		cfg.Consumer.FetchBatch = defaultJetStreamFetchBatch
+	}
+
+	conn, err := nats.Connect(cfg.URL, natsOptions(cfg)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	// Provision the stream
+	storage := jetstream.FileStorage
+	if strings.EqualFold(cfg.Stream.Storage, "memory") {
+		storage = jetstream.MemoryStorage
+	}
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     cfg.Stream.Name,
+		Subjects: cfg.Stream.Subjects,
+		Storage:  storage,
+		Replicas: max(cfg.Stream.Replicas, 1),
+		MaxAge:   cfg.Stream.MaxAge,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to provision stream %s: %w", cfg.Stream.Name, err)
+	}
+
+	return &JetStream{
+		conn:        conn,
+		js:          js,
+		config:      cfg,
+		concurrency: concurrency,
+		paused:      make(map[string]bool),
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// natsOptions returns connection options with authentication and reconnect handling
+func natsOptions(cfg config.NATSConfig) []nats.Option {
+	opts := []nats.Option{
+		nats.MaxReconnects(cfg.MaxReconnects),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn("Disconnected from NATS", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("Reconnected to NATS", zap.String("url", conn.ConnectedUrl()))
+		}),
+		nats.ClosedHandler(func(_ *nats.Conn) {
+			logger.Info("NATS connection closed")
+		}),
+	}
+	if cfg.Name != "" {
+		opts = append(opts, nats.Name(cfg.Name))
+	}
+	if cfg.ReconnectWait > 0 {
+		opts = append(opts, nats.ReconnectWait(cfg.ReconnectWait))
+	}
+	if cfg.Username != "" {
+		opts = append(opts, nats.UserInfo(cfg.Username, cfg.Password))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, nats.Token(cfg.Token))
+	}
+	return opts
+}
+
+// Publish stores a message in the stream. The message ID doubles as the
+// JetStream deduplication ID, so a retried publish is stored only once.
+func (j *JetStream) Publish(ctx context.Context, msg *Message) error {
+	ensureMessageID(msg)
+
+	ack, err := j.js.PublishMsg(ctx, j.newMsg(msg), jetstream.WithMsgID(msg.Headers[HeaderMessageID]))
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to publish message", zap.Error(err))
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	j.checkDuplicate(ctx, msg, ack)
+	msg.Offset = int64(ack.Sequence)
+	return nil
+}
+
+// checkDuplicate logs publishes the stream dropped because a message with
+// the same ID was stored within its duplicate window. That is expected when
+// a publish is retried, but points at reused message IDs otherwise.
+func (j *JetStream) checkDuplicate(ctx context.Context, msg *Message, ack *jetstream.PubAck) {
+	if ack.Duplicate {
+		messageLogger(ctx, msg).Warn("Message dropped as a duplicate by the stream",
+			zap.Uint64("stored_sequence", ack.Sequence))
+	}
+}
+
+// PublishBatch publishes all messages asynchronously and waits for the
+// stream to acknowledge each of them
+func (j *JetStream) PublishBatch(ctx context.Context, msgs []*Message) error {
+	errs := make([]error, len(msgs))
+	futures := make([]jetstream.PubAckFuture, len(msgs))
+	for i, msg := range msgs {
+		ensureMessageID(msg)
+		future, err := j.js.PublishMsgAsync(j.newMsg(msg), jetstream.WithMsgID(msg.Headers[HeaderMessageID]))
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to publish message: %w", err)
+			continue
+		}
+		futures[i] = future
+	}
+
+	for i, future := range futures {
+		if future == nil {
+			continue
+		}
+		select {
+		case ack := <-future.Ok():
+			j.checkDuplicate(ctx, msgs[i], ack)
+			msgs[i].Offset = int64(ack.Sequence)
+		case err := <-future.Err():
+			errs[i] = fmt.Errorf("failed to publish message: %w", err)
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+		}
+	}
+
+	if err := newBatchError(errs); err != nil {
+		logger.FromContext(ctx).Error("Failed to publish part of message batch", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// newMsg converts a message to be stored, moving keyed messages to their
+// key subject
+func (j *JetStream) newMsg(msg *Message) *nats.Msg {
+	natsMsg := newNATSMsg(msg)
+	natsMsg.Subject = keySubject(msg.Topic, msg.Key, j.config.KeySubjects)
+	return natsMsg
+}
+
+// Subscribe subscribes to a single subject
+func (j *JetStream) Subscribe(ctx context.Context, topic string, handler Handler) error {
+	return j.SubscribeMultiple(ctx, []string{topic}, handler)
+}
+
+// SubscribeMultiple binds a durable consumer to each subject and consumes
+// them until ctx is done
+func (j *JetStream) SubscribeMultiple(ctx context.Context, topics []string, handler Handler) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var subs []*jetStreamSubscription
+	for _, topic := range topics {
+		consumer, err := j.js.CreateOrUpdateConsumer(ctx, j.config.Stream.Name, j.consumerConfig(topic))
+		if err != nil {
+			j.stopSubscriptions(subs)
+			return fmt.Errorf("failed to provision consumer for %s: %w", topic, err)
+		}
+
+		sub := &jetStreamSubscription{
+			ctx:      ctx,
+			topic:    topic,
+			consumer: consumer,
+			pool:     newWorkerPool(concurrencyLimits(j.concurrency, topic)),
+			handler:  handler,
+		}
+		subs = append(subs, sub)
+
+		// Topics paused earlier stay paused
+		if !j.paused[topic] {
+			if err := j.startConsuming(sub); err != nil {
+				j.stopSubscriptions(subs)
+				return err
+			}
+		}
+	}
+	j.subscriptions = append(j.subscriptions, subs...)
+
+	// Stop consuming when the subscription context ends
+	go func() {
+		<-ctx.Done()
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.subscriptions = slices.DeleteFunc(j.subscriptions, func(sub *jetStreamSubscription) bool {
+			return slices.Contains(subs, sub)
+		})
+		j.stopSubscriptions(subs)
+	}()
+
+	return nil
+}
+
+// jetStreamSubscription is a durable consumer bound to one subject. The
+// consume context is nil while the subscription is paused.
+type jetStreamSubscription struct {
+	ctx      context.Context
+	topic    string
+	consumer jetstream.Consumer
+	pool     *workerPool
+	handler  Handler
+	cc       jetstream.ConsumeContext
+}
+
+// startConsuming starts pulling messages for a subscription. Callers hold j.mu.
+func (j *JetStream) startConsuming(sub *jetStreamSubscription) error {
+	cc, err := sub.consumer.Consume(
+		func(msg jetstream.Msg) { j.dispatch(sub.ctx, sub.pool, msg, sub.handler) },
+		jetstream.PullMaxMessages(j.config.Consumer.FetchBatch),
+		jetstream.ConsumeErrHandler(func(_ jetstream.ConsumeContext, err error) {
+			logger.FromContext(sub.ctx).Warn("JetStream consumer error", zap.String("topic", sub.topic), zap.Error(err))
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to consume %s: %w", sub.topic, err)
+	}
+	sub.cc = cc
+	return nil
+}
+
+// stopConsuming stops pulling messages for a subscription. Messages already
+// handed to workers still finish. Callers hold j.mu.
+func (j *JetStream) stopConsuming(sub *jetStreamSubscription) {
+	if sub.cc != nil {
+		sub.cc.Stop()
+		sub.cc = nil
+	}
+}
+
+// stopSubscriptions stops consuming and waits for the workers of subs.
+// Callers hold j.mu.
+func (j *JetStream) stopSubscriptions(subs []*jetStreamSubscription) {
+	for _, sub := range subs {
+		j.stopConsuming(sub)
+	}
+	for _, sub := range subs {
+		sub.pool.stop()
+	}
+}
+
+// Request sends a request with core NATS request/reply. Requests bypass the
+// stream, so request subjects must not be captured by it; otherwise
+// JetStream stores the request and answers with a publish ack.
+func (j *JetStream) Request(ctx context.Context, topic string, msg *Message) (*Message, error) {
+	return natsRequest(ctx, j.conn, topic, msg)
+}
+
+// Respond serves requests on a subject through a core NATS queue
+// subscription named after the durable consumer
+func (j *JetStream) Respond(ctx context.Context, topic string, responder Responder) error {
+	return natsRespond(ctx, j.conn, topic, j.config.Consumer.Durable, responder)
+}
+
+// consumerConfig returns the durable consumer config for a subject. With
+// key subjects the consumer also filters the subject's key subjects.
+func (j *JetStream) consumerConfig(topic string) jetstream.ConsumerConfig {
+	cfg := j.config.Consumer
+	consumer := jetstream.ConsumerConfig{
+		Durable:       durableName(cfg.Durable, topic),
+		DeliverPolicy: jetstream.DeliverAllPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       cfg.AckWait,
+		MaxDeliver:    cfg.MaxDeliver,
+		MaxAckPending: cfg.MaxAckPending,
+		BackOff:       cfg.Backoff,
+	}
+	if subjects := topicSubjects(topic, j.config.KeySubjects); len(subjects) > 1 {
+		consumer.FilterSubjects = subjects
+	} else {
+		consumer.FilterSubject = topic
+	}
+	return consumer
+}
+
+// dispatch hands a delivery to the subject's worker pool, waiting while all
+// slots are taken. Time spent queued for a worker counts against AckWait,
+// so MaxInFlight should leave every queued message time to finish.
+// Deliveries dropped because the subscription ended are redelivered by the
+// server after AckWait.
+func (j *JetStream) dispatch(ctx context.Context, pool *workerPool, msg jetstream.Msg, handler Handler) {
+	if !pool.acquire(ctx) {
+		return
+	}
+
+	key := msg.Headers().Get(HeaderPartitionKey)
+	submitted := pool.submit(key, func() {
+		defer pool.release()
+		j.handle(ctx, msg, handler)
+	})
+	if !submitted {
+		pool.release()
+	}
+}
+
+// handle runs the handler for one delivery and acknowledges the outcome.
+// The delivery is reported in progress while the handler runs, which may
+// take longer than AckWait when it waits out retry delays.
+func (j *JetStream) handle(ctx context.Context, msg jetstream.Msg, handler Handler) {
+	message := j.messageFrom(msg)
+	log := messageLogger(ctx, message)
+
+	stop := j.inProgress(log, msg)
+	err := handler(ctx, message)
+	stop()
+	j.settle(log, msg, err)
+}
+
+// inProgress reports deliveries as in progress, resetting their ack timer,
+// until the returned function is called
+func (j *JetStream) inProgress(log *zap.Logger, msgs ...jetstream.Msg) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(j.progressInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				for _, msg := range msgs {
+					if err := msg.InProgress(); err != nil {
+						log.Warn("Failed to report message in progress", zap.Error(err))
+					}
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// progressInterval returns how often deliveries in progress are reported:
+// twice within AckWait and within the shortest backoff, which replaces
+// AckWait as the redelivery timeout when set
+func (j *JetStream) progressInterval() time.Duration {
+	timeout := j.config.Consumer.AckWait
+	if timeout <= 0 {
+		timeout = defaultJetStreamAckWait
+	}
+	for _, backoff := range j.config.Consumer.Backoff {
+		if backoff > 0 {
+			timeout = min(timeout, backoff)
+		}
+	}
+	return max(timeout/2, time.Millisecond)
+}
+
+// messageFrom converts a JetStream delivery. Messages on a key subject are
+// reported on their topic; the offset is the stream sequence.
+func (j *JetStream) messageFrom(msg jetstream.Msg) *Message {
+	message := &Message{
+		Topic:   msg.Subject(),
+		Payload: msg.Data(),
+		Headers: make(map[string]string),
+	}
+	for key, values := range msg.Headers() {
+		if len(values) > 0 {
+			message.Headers[key] = values[0]
+		}
+	}
+
+	message.Key = message.Headers[HeaderPartitionKey]
+	if j.config.KeySubjects && message.Key != "" {
+		message.Topic = strings.TrimSuffix(message.Topic, "."+subjectTokenReplacer.Replace(message.Key))
+	}
+	if meta, err := msg.Metadata(); err == nil {
+		message.Timestamp = meta.Timestamp
+		message.Offset = int64(meta.Sequence.Stream)
+	}
+	return message
+}
+
+// settle acks a delivery whose handler succeeded. Failed deliveries are
+// nak'ed with the backoff delay, or terminated once they failed permanently
+// or reached MaxDeliver.
+func (j *JetStream) settle(log *zap.Logger, msg jetstream.Msg, err error) {
+	if err == nil {
+		if err := msg.Ack(); err != nil {
+			log.Error("Failed to ack message", zap.Error(err))
+		}
+		return
+	}
+
+	delivered := uint64(1)
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		delivered = meta.NumDelivered
+	}
+
+	// Permanent failures and the last allowed delivery are not redelivered
+	maxDeliver := j.config.Consumer.MaxDeliver
+	if IsPermanent(err) || (maxDeliver > 0 && delivered >= uint64(maxDeliver)) {
+		log.Error("Failed to handle message, giving up",
+			zap.Uint64("delivered", delivered),
+			zap.Error(err))
+		if err := msg.Term(); err != nil {
+			log.Error("Failed to terminate message", zap.Error(err))
+		}
+		return
+	}
+
+	delay := j.nakDelay(delivered)
+	log.Error("Failed to handle message, redelivering",
+		zap.Uint64("delivered", delivered),
+		zap.Duration("delay", delay),
+		zap.Error(err))
+	if err := msg.NakWithDelay(delay); err != nil {
+		log.Error("Failed to nak message", zap.Error(err))
+	}
+}
+
+// SubscribeBatch fetches batches from the durable consumer of each subject
+// until ctx is done or the client is closed. Batch subscriptions share the
+// consumers of SubscribeMultiple but are not affected by Pause.
+func (j *JetStream) SubscribeBatch(ctx context.Context, topics []string, opts BatchOptions, handler BatchHandler) error {
+	opts = opts.withDefaults()
+
+	consumers := make([]jetstream.Consumer, 0, len(topics))
+	for _, topic := range topics {
+		consumer, err := j.js.CreateOrUpdateConsumer(ctx, j.config.Stream.Name, j.consumerConfig(topic))
+		if err != nil {
+			return fmt.Errorf("failed to provision consumer for %s: %w", topic, err)
+		}
+		consumers = append(consumers, consumer)
+	}
+
+	for i, consumer := range consumers {
+		j.batches.Add(1)
+		go func() {
+			defer j.batches.Done()
+			j.consumeBatches(ctx, topics[i], consumer, opts, handler)
+		}()
+	}
+	return nil
+}
+
+// consumeBatches fetches up to opts.Size messages at a time, waiting at most
+// opts.Wait for them, and settles every message of a batch with its outcome
+func (j *JetStream) consumeBatches(ctx context.Context, topic string, consumer jetstream.Consumer, opts BatchOptions, handler BatchHandler) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-j.done:
+			return
+		default:
+		}
+
+		batch, err := consumer.Fetch(opts.Size, jetstream.FetchMaxWait(opts.Wait))
+		if err != nil {
+			logger.FromContext(ctx).Warn("Failed to fetch batch", zap.String("topic", topic), zap.Error(err))
+			select {
+			case <-time.After(opts.Wait):
+			case <-ctx.Done():
+			case <-j.done:
+			}
+			continue
+		}
+
+		var deliveries []jetstream.Msg
+		var messages []*Message
+		for msg := range batch.Messages() {
+			deliveries = append(deliveries, msg)
+			messages = append(messages, j.messageFrom(msg))
+		}
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			logger.FromContext(ctx).Warn("Batch fetch ended early", zap.String("topic", topic), zap.Error(err))
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		stop := j.inProgress(logger.FromContext(ctx).With(zap.String("topic", topic)), deliveries...)
+		err = handler(ctx, messages)
+		stop()
+		for i, msg := range deliveries {
+			j.settle(messageLogger(ctx, messages[i]), msg, err)
+		}
+	}
+}
+
+// nakDelay returns the redelivery delay after the given number of deliveries
+func (j *JetStream) nakDelay(delivered uint64) time.Duration {
+	backoff := j.config.Consumer.Backoff
+	if len(backoff) == 0 {
+		return defaultJetStreamNakDelay
+	}
+	i := min(int(delivered)-1, len(backoff)-1)
+	return backoff[max(i, 0)]
+}
+
+// HealthCheck checks the connection and that JetStream is available
+func (j *JetStream) HealthCheck(ctx context.Context) error {
+	if !j.conn.IsConnected() {
+		return ErrNotConnected
+	}
+	if _, err := j.js.AccountInfo(ctx); err != nil {
+		return fmt.Errorf("jetstream unavailable: %w", err)
+	}
+	return nil
+}
+
+// Close stops all consumers and drains the connection
+func (j *JetStream) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Let running handlers finish before draining
+	j.stopSubscriptions(j.subscriptions)
+	j.subscriptions = nil
+	select {
+	case <-j.done:
+	default:
+		close(j.done)
+	}
+	j.batches.Wait()
+
+	if err := j.conn.Drain(); err != nil {
+		return fmt.Errorf("failed to drain NATS connection: %w", err)
+	}
+	return nil
+}
+
+// durableName builds a valid consumer name from the durable prefix and a
+// subject, since names may not contain dots or wildcards
+func durableName(prefix, subject string) string {
+	return prefix + "_" + subjectTokenReplacer.Replace(subject)
+}
@@ -0,0 +1,209 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DescribeTopics reports which subjects the stream captures. A subject is
+// a single partition.
+func (j *JetStream) DescribeTopics(ctx context.Context, topics []string) ([]TopicInfo, error) {
+	infos := make([]TopicInfo, 0, len(topics))
+	for _, topic := range topics {
+		info := TopicInfo{Name: topic}
+
+		stream, err := j.js.StreamNameBySubject(ctx, topic)
+		switch {
+		case err == nil:
+			if stream == j.config.Stream.Name {
+				info.Exists = true
+				info.Partitions = 1
+			}
+		case !errors.Is(err, jetstream.ErrStreamNotFound):
+			return nil, fmt.Errorf("failed to look up stream of %s: %w", topic, err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Lag reports the durable consumers of group on topics. Committed is the
+// stream sequence below which all messages are acked, Latest the sequence of
+// the last message on the subject and Lag the messages not delivered or
+// not acked yet.
+func (j *JetStream) Lag(ctx context.Context, group string, topics []string) (*GroupLag, error) {
+	stream, err := j.js.Stream(ctx, j.config.Stream.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up stream %s: %w", j.config.Stream.Name, err)
+	}
+
+	if group == "" {
+		group = j.config.Consumer.Durable
+	}
+	lag := &GroupLag{Group: group, Partitions: []PartitionLag{}}
+	for _, topic := range topics {
+		info, err := j.consumerInfo(ctx, group, topic)
+		if err != nil {
+			return nil, err
+		}
+
+		latest := int64(0)
+		last, err := stream.GetLastMsgForSubject(ctx, topic)
+		switch {
+		case err == nil:
+			latest = int64(last.Sequence)
+		case !errors.Is(err, jetstream.ErrMsgNotFound):
+			return nil, fmt.Errorf("failed to get last message of %s: %w", topic, err)
+		}
+
+		p := PartitionLag{
+			Topic:     topic,
+			Committed: int64(info.AckFloor.Stream),
+			Latest:    latest,
+			Lag:       int64(info.NumPending) + int64(info.NumAckPending),
+		}
+		lag.Partitions = append(lag.Partitions, p)
+		lag.Total += p.Lag
+	}
+	return lag, nil
+}
+
+// ResetOffsets recreates the durable consumers of group on topics with a
+// new start position, since JetStream cannot move an existing consumer.
+// It fails while any instance is still pulling from a consumer.
+func (j *JetStream) ResetOffsets(ctx context.Context, group string, topics []string, reset OffsetReset) error {
+	if err := reset.Validate(); err != nil {
+		return err
+	}
+
+	// Check every consumer before changing any
+	infos := make([]*jetstream.ConsumerInfo, 0, len(topics))
+	for _, topic := range topics {
+		info, err := j.consumerInfo(ctx, group, topic)
+		if err != nil {
+			return err
+		}
+		if info.NumWaiting > 0 {
+			return fmt.Errorf("%w: %s has %d pull requests", ErrGroupActive, info.Name, info.NumWaiting)
+		}
+		infos = append(infos, info)
+	}
+
+	for _, info := range infos {
+		cfg := info.Config
+		cfg.OptStartSeq = 0
+		cfg.OptStartTime = nil
+		switch reset.To {
+		case ResetEarliest:
+			cfg.DeliverPolicy = jetstream.DeliverAllPolicy
+		case ResetLatest:
+			cfg.DeliverPolicy = jetstream.DeliverNewPolicy
+		case ResetTimestamp:
+			start := reset.Timestamp
+			cfg.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+			cfg.OptStartTime = &start
+		}
+
+		if err := j.js.DeleteConsumer(ctx, j.config.Stream.Name, info.Name); err != nil {
+			return fmt.Errorf("failed to delete consumer %s: %w", info.Name, err)
+		}
+		if _, err := j.js.CreateConsumer(ctx, j.config.Stream.Name, cfg); err != nil {
+			return fmt.Errorf("failed to recreate consumer %s: %w", info.Name, err)
+		}
+	}
+	return nil
+}
+
+// consumerInfo returns the durable consumer of group on topic
+func (j *JetStream) consumerInfo(ctx context.Context, group, topic string) (*jetstream.ConsumerInfo, error) {
+	if group == "" {
+		group = j.config.Consumer.Durable
+	}
+	name := durableName(group, topic)
+	consumer, err := j.js.Consumer(ctx, j.config.Stream.Name, name)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil, fmt.Errorf("%w: no consumer %s", ErrSubscriptionNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up consumer %s: %w", name, err)
+	}
+
+	info, err := consumer.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of consumer %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// Subscriptions lists the subjects subscribed on this client
+func (j *JetStream) Subscriptions() []SubscriptionInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var infos []SubscriptionInfo
+	for _, sub := range j.subscriptions {
+		if !slices.ContainsFunc(infos, func(info SubscriptionInfo) bool { return info.Topic == sub.topic }) {
+			infos = append(infos, SubscriptionInfo{Topic: sub.topic, Paused: j.paused[sub.topic]})
+		}
+	}
+	slices.SortFunc(infos, func(a, b SubscriptionInfo) int {
+		return strings.Compare(a.Topic, b.Topic)
+	})
+	return infos
+}
+
+// Pause stops pulling messages for topic on this instance. Messages
+// already received are still handled; replicas keep consuming.
+func (j *JetStream) Pause(topic string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	subs := j.subscriptionsOf(topic)
+	if len(subs) == 0 {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, topic)
+	}
+
+	j.paused[topic] = true
+	for _, sub := range subs {
+		j.stopConsuming(sub)
+	}
+	return nil
+}
+
+// Resume continues pulling messages for a paused topic
+func (j *JetStream) Resume(topic string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	subs := j.subscriptionsOf(topic)
+	if len(subs) == 0 {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, topic)
+	}
+
+	delete(j.paused, topic)
+	for _, sub := range subs {
+		if sub.cc != nil {
+			continue
+		}
+		if err := j.startConsuming(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscriptionsOf returns the subscriptions of topic. Callers hold j.mu.
+func (j *JetStream) subscriptionsOf(topic string) []*jetStreamSubscription {
+	var subs []*jetStreamSubscription
+	for _, sub := range j.subscriptions {
+		if sub.topic == topic {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
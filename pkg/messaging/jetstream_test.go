@@ -0,0 +1,226 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-microservice/internal/config"
+	"go-microservice/pkg/messaging/natstest"
+)
+
+// delivery is one call of a handler
+type delivery struct {
+	at  time.Time
+	msg *Message
+}
+
+// newJetStream connects to an embedded JetStream server capturing orders.>,
+// with the server's config changed by configure when it is not nil
+func newJetStream(t *testing.T, configure func(*config.NATSConfig)) *JetStream {
+	t.Helper()
+
+	cfg := natstest.NewJetStreamServer(t, "orders.>")
+	if configure != nil {
+		configure(&cfg)
+	}
+	js, err := NewJetStream(t.Context(), cfg, config.ConcurrencyConfig{})
+	if err != nil {
+		t.Fatalf("NewJetStream() error = %v", err)
+	}
+	t.Cleanup(func() { js.Close() })
+	return js
+}
+
+// consume subscribes handler to topic and returns a function waiting until
+// the handler was called n times, returning the deliveries so far
+func consume(t *testing.T, m Messaging, topic string, handler func(n int) error) func(n int) []delivery {
+	t.Helper()
+
+	var (
+		mu         sync.Mutex
+		deliveries []delivery
+		delivered  = make(chan struct{}, 10)
+	)
+	err := m.Subscribe(t.Context(), topic, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		deliveries = append(deliveries, delivery{time.Now(), msg})
+		n := len(deliveries)
+		mu.Unlock()
+
+		delivered <- struct{}{}
+		return handler(n)
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	return func(n int) []delivery {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			select {
+			case <-delivered:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("got %d deliveries, want %d", i, n)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]delivery(nil), deliveries...)
+	}
+}
+
+// assertSettled checks that the consumer of topic has no delivery waiting
+// for an ack and that no further delivery arrives
+func assertSettled(t *testing.T, js *JetStream, topic string, wait func(n int) []delivery, total int) {
+	t.Helper()
+
+	// Longer than the backoff and the ack wait of the test server
+	time.Sleep(1500 * time.Millisecond)
+	if got := len(wait(0)); got != total {
+		t.Errorf("got %d deliveries, want %d", got, total)
+	}
+
+	consumer, err := js.js.Consumer(t.Context(), js.config.Stream.Name, durableName(js.config.Consumer.Durable, topic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := consumer.Info(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.NumAckPending != 0 || info.NumPending != 0 {
+		t.Errorf("consumer has %d deliveries awaiting ack and %d pending, want none", info.NumAckPending, info.NumPending)
+	}
+}
+
+func publishOrder(t *testing.T, js *JetStream) {
+	t.Helper()
+	if err := js.Publish(t.Context(), &Message{Topic: "orders.created", Payload: []byte(`{"id":"o1"}`)}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+}
+
+func TestJetStreamAcksHandledMessages(t *testing.T) {
+	js := newJetStream(t, nil)
+	wait := consume(t, js, "orders.created", func(int) error { return nil })
+
+	publishOrder(t, js)
+	deliveries := wait(1)
+	if msg := deliveries[0].msg; string(msg.Payload) != `{"id":"o1"}` || msg.Topic != "orders.created" || msg.Offset != 1 {
+		t.Errorf("delivered %s on %s at offset %d", msg.Payload, msg.Topic, msg.Offset)
+	}
+	assertSettled(t, js, "orders.created", wait, 1)
+}
+
+func TestJetStreamRedeliversWithBackoff(t *testing.T) {
+	js := newJetStream(t, nil)
+	// Fails until the last delivery MaxDeliver allows
+	wait := consume(t, js, "orders.created", func(n int) error {
+		if n < 3 {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	publishOrder(t, js)
+	deliveries := wait(3)
+	for i, backoff := range js.config.Consumer.Backoff {
+		if gap := deliveries[i+1].at.Sub(deliveries[i].at); gap < backoff {
+			t.Errorf("delivery %d came %v after the one before, want at least the %v backoff", i+2, gap, backoff)
+		}
+	}
+	assertSettled(t, js, "orders.created", wait, 3)
+}
+
+func TestJetStreamTerminatesFailedMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantTotal int
+	}{
+		{"permanent failure", Permanent(errors.New("malformed order")), 1},
+		{"max deliveries reached", errors.New("handler failed"), 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			js := newJetStream(t, nil)
+			wait := consume(t, js, "orders.created", func(int) error { return tt.err })
+
+			publishOrder(t, js)
+			wait(tt.wantTotal)
+			assertSettled(t, js, "orders.created", wait, tt.wantTotal)
+		})
+	}
+}
+
+func TestJetStreamKeepsSlowHandlersInProgress(t *testing.T) {
+	js := newJetStream(t, func(cfg *config.NATSConfig) {
+		cfg.Consumer.AckWait = 200 * time.Millisecond
+		cfg.Consumer.Backoff = nil
+	})
+	// Runs for several ack waits, as when the retrier waits out a delay
+	wait := consume(t, js, "orders.created", func(int) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	publishOrder(t, js)
+	wait(1)
+	assertSettled(t, js, "orders.created", wait, 1)
+}
+
+func TestJetStreamDeadLettersThroughRetrier(t *testing.T) {
+	js := newJetStream(t, nil)
+	retrier := NewRetrier(js, config.RetryConfig{
+		MaxAttempts:    1,
+		InitialBackoff: time.Millisecond,
+		Delays:         []time.Duration{10 * time.Millisecond},
+	})
+
+	deadLetters := make(chan *Message, 1)
+	if err := js.Subscribe(t.Context(), retrier.DeadLetterTopic("orders.created"), func(ctx context.Context, msg *Message) error {
+		deadLetters <- msg
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var handled []*Message
+	if err := js.SubscribeMultiple(t.Context(), retrier.Topics("orders.created"), retrier.Wrap(func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		handled = append(handled, msg)
+		mu.Unlock()
+		return errors.New("handler failed")
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	original := &Message{Topic: "orders.created", Payload: []byte(`{"id":"o1"}`)}
+	if err := js.Publish(t.Context(), original); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-deadLetters:
+		originalID := original.Headers[HeaderMessageID]
+		if msg.Headers[HeaderOriginalMessageID] != originalID || msg.Headers[HeaderMessageID] == originalID {
+			t.Errorf("dead letter has ID %q and original ID %q, want its own ID and original %q",
+				msg.Headers[HeaderMessageID], msg.Headers[HeaderOriginalMessageID], originalID)
+		}
+		if string(msg.Payload) != `{"id":"o1"}` {
+			t.Errorf("dead letter payload = %s", msg.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead-lettered message never arrived on orders.created.dlq")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 2 || handled[1].Topic != "orders.created.retry.1" {
+		t.Errorf("handled %d messages, want the original and its retry", len(handled))
+	}
+}
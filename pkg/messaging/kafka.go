@@ -2,63 +2,179 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/google/uuid"
 	"go-microservice/internal/config"
 	"go-microservice/pkg/logger"
 	"go.uber.org/zap"
 )
 
-// Kafka implements the Messaging interface
+// replyGroupReadyTimeout bounds the wait for the reply consumer group to
+// join before the first request is sent
+const replyGroupReadyTimeout = 30 * time.Second
+
+// Kafka implements the Messaging interface. Requests are answered through a
+// shared reply topic that every instance reads with its own consumer group,
+// matching replies to requests by correlation ID.
 type Kafka struct {
+	client   sarama.Client
 	producer sarama.SyncProducer
 	consumer sarama.ConsumerGroup
+	groupID  string
+	inbox    *replyInbox
 	mu       sync.Mutex
+
+	concurrency config.ConcurrencyConfig
+
+	// Administration and topics paused on this instance
+	admin      sarama.ClusterAdmin
+	subscribed map[string]bool
+	paused     *pauseSet
+
+	// Consumer groups for replies and responders, closed with the client
+	ctx    context.Context
+	cancel context.CancelFunc
+	groups []sarama.ConsumerGroup
+}
+
+func init() {
+	RegisterBackend("kafka", func(_ context.Context, cfg *config.Config) (Messaging, error) {
+		return NewKafka(cfg)
+	})
 }
 
-// NewKafka creates a new Kafka client
+// NewKafka creates a new Kafka client. The producer and the consumer group
+// share one connection to the cluster.
 func NewKafka(cfg *config.Config) (*Kafka, error) {
-	config := sarama.NewConfig()
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 5
-	config.Producer.Return.Successes = true
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	saramaConfig, err := newSaramaConfig(&cfg.Kafka)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sarama.NewClient(cfg.Kafka.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
 
 	// Create producer
-	producer, err := sarama.NewSyncProducer(cfg.Kafka.Brokers, config)
+	producer, err := sarama.NewSyncProducerFromClient(client)
 	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create producer: %w", err)
 	}
 
 	// Create consumer group
-	group, err := sarama.NewConsumerGroup(cfg.Kafka.Brokers, cfg.Kafka.ConsumerGroup, config)
+	group, err := sarama.NewConsumerGroupFromClient(cfg.Kafka.ConsumerGroup, client)
 	if err != nil {
 		producer.Close()
+		client.Close()
 		return nil, fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
-	return &Kafka{
+	replyTopic := cfg.Kafka.ReplyTopic
+	if replyTopic == "" {
+		replyTopic = cfg.Kafka.ConsumerGroup + ".replies"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	k := &Kafka{
+		client:   client,
 		producer: producer,
 		consumer: group,
-	}, nil
+		groupID:  cfg.Kafka.ConsumerGroup,
+		ctx:      ctx,
+		cancel:   cancel,
+
+		concurrency: cfg.Messaging.Concurrency,
+		subscribed:  make(map[string]bool),
+		paused:      newPauseSet(),
+	}
+	k.inbox = newReplyInbox(replyTopic, k.subscribeReplies)
+	return k, nil
 }
 
-// Publish publishes a message to a topic
+// Publish publishes a message to a topic. The producer is safe for
+// concurrent use, so publishes are not serialized.
 func (k *Kafka) Publish(ctx context.Context, msg *Message) error {
-	k.mu.Lock()
-	defer k.mu.Unlock()
+	if k.producer == nil || k.ctx.Err() != nil {
+		return ErrNotConnected
+	}
+
+	ensureMessageID(msg)
+
+	// Send message
+	partition, offset, err := k.producer.SendMessage(newProducerMessage(msg))
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to send message", zap.Error(err))
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	msg.Partition, msg.Offset = partition, offset
+
+	logger.Debug("Message published successfully",
+		zap.String("topic", msg.Topic),
+		zap.Int32("partition", partition),
+		zap.Int64("offset", offset))
+
+	return nil
+}
 
-	if k.producer == nil {
+// PublishBatch sends all messages in one round of produce requests
+func (k *Kafka) PublishBatch(ctx context.Context, msgs []*Message) error {
+	if k.producer == nil || k.ctx.Err() != nil {
 		return ErrNotConnected
 	}
 
-	// Create Kafka message
+	// The metadata of each producer message is its index in the batch
+	kafkaMsgs := make([]*sarama.ProducerMessage, len(msgs))
+	for i, msg := range msgs {
+		ensureMessageID(msg)
+		kafkaMsgs[i] = newProducerMessage(msg)
+		kafkaMsgs[i].Metadata = i
+	}
+
+	err := k.producer.SendMessages(kafkaMsgs)
+	for i, kafkaMsg := range kafkaMsgs {
+		msgs[i].Partition, msgs[i].Offset = kafkaMsg.Partition, kafkaMsg.Offset
+	}
+	if err == nil {
+		logger.Debug("Message batch published successfully", zap.Int("count", len(msgs)))
+		return nil
+	}
+
+	var producerErrs sarama.ProducerErrors
+	if !errors.As(err, &producerErrs) {
+		logger.FromContext(ctx).Error("Failed to send message batch", zap.Error(err))
+		return fmt.Errorf("failed to send message batch: %w", err)
+	}
+
+	errs := make([]error, len(msgs))
+	for _, producerErr := range producerErrs {
+		i := producerErr.Msg.Metadata.(int)
+		errs[i] = fmt.Errorf("failed to send message: %w", producerErr.Err)
+	}
+	logger.FromContext(ctx).Error("Failed to send part of message batch",
+		zap.Int("count", len(msgs)),
+		zap.Int("failed", len(producerErrs)))
+	return newBatchError(errs)
+}
+
+// newProducerMessage converts a message to a Kafka message. The key lets the
+// partitioner keep related messages on one partition.
+func newProducerMessage(msg *Message) *sarama.ProducerMessage {
 	kafkaMsg := &sarama.ProducerMessage{
-		Topic: msg.Topic,
-		Value: sarama.ByteEncoder(msg.Payload),
+		Topic:     msg.Topic,
+		Value:     sarama.ByteEncoder(msg.Payload),
+		Timestamp: msg.Timestamp,
+		Partition: msg.Partition,
+	}
+	if msg.Key != "" {
+		kafkaMsg.Key = sarama.StringEncoder(msg.Key)
 	}
 
 	// Add headers
@@ -72,20 +188,7 @@ func (k *Kafka) Publish(ctx context.Context, msg *Message) error {
 		}
 		kafkaMsg.Headers = headers
 	}
-
-	// Send message
-	partition, offset, err := k.producer.SendMessage(kafkaMsg)
-	if err != nil {
-		logger.Error("Failed to send message", zap.Error(err))
-		return fmt.Errorf("failed to send message: %w", err)
-	}
-
-	logger.Debug("Message published successfully",
-		zap.String("topic", msg.Topic),
-		zap.Int32("partition", partition),
-		zap.Int64("offset", offset))
-
-	return nil
+	return kafkaMsg
 }
 
 // SubscribeMultiple subscribes to multiple topics with the same handler
@@ -97,25 +200,141 @@ func (k *Kafka) SubscribeMultiple(ctx context.Context, topics []string, handler
 		return ErrNotConnected
 	}
 
-	// Create consumer group handler
-	h := &consumerGroupHandler{
-		handler: handler,
+	for _, topic := range topics {
+		k.subscribed[topic] = true
 	}
 
 	// Start consuming in a goroutine
+	k.consume(ctx, k.consumer, topics, newConsumerGroupHandler(handler, k.concurrency))
+	return nil
+}
+
+// consume runs consumer group sessions for the topics that are not paused
+// in the background until ctx is done or the group is closed. A session
+// ends when a pause or resume changes its topics, and the group rejoins
+// with the new set; with every topic paused the instance leaves the group.
+func (k *Kafka) consume(ctx context.Context, group sarama.ConsumerGroup, topics []string, h sarama.ConsumerGroupHandler) {
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				if err := k.consumer.Consume(ctx, topics, h); err != nil {
-					logger.Error("Error from consumer", zap.Error(err))
+		for ctx.Err() == nil {
+			active, changed := k.paused.active(topics)
+			if len(active) == 0 {
+				select {
+				case <-changed:
+				case <-ctx.Done():
 				}
+				continue
+			}
+
+			sessionCtx, cancel := context.WithCancel(ctx)
+			go k.watchPaused(sessionCtx, cancel, topics, active, changed)
+
+			err := group.Consume(sessionCtx, active, h)
+			cancel()
+			if err != nil {
+				if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+					return
+				}
+				logger.FromContext(ctx).Error("Error from consumer", zap.Error(err))
 			}
 		}
 	}()
+}
+
+// watchPaused cancels a session once pausing or resuming changes which of
+// its topics are active
+func (k *Kafka) watchPaused(ctx context.Context, cancel context.CancelFunc, topics, active []string, changed <-chan struct{}) {
+	for {
+		select {
+		case <-changed:
+			var next []string
+			next, changed = k.paused.active(topics)
+			if !slices.Equal(next, active) {
+				cancel()
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// newGroup creates an additional consumer group on the shared client. A
+// group consumes one session at a time, so replies, responders and batch
+// consumers cannot share the group used by SubscribeMultiple.
+func (k *Kafka) newGroup(groupID string) (sarama.ConsumerGroup, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.client == nil || k.client.Closed() {
+		return nil, ErrNotConnected
+	}
+	group, err := sarama.NewConsumerGroupFromClient(groupID, k.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer group %s: %w", groupID, err)
+	}
+	k.groups = append(k.groups, group)
+	return group, nil
+}
+
+// Request publishes a request with a correlation ID and waits for the reply
+// on the reply topic
+func (k *Kafka) Request(ctx context.Context, topic string, msg *Message) (*Message, error) {
+	return k.inbox.request(ctx, k.Publish, topic, msg)
+}
+
+// Respond serves requests on topic. Responders of all instances share one
+// consumer group per topic, so each request is answered once.
+func (k *Kafka) Respond(ctx context.Context, topic string, responder Responder) error {
+	group, err := k.newGroup(fmt.Sprintf("%s.responders.%s", k.groupID, topic))
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.subscribed[topic] = true
+	k.mu.Unlock()
+
+	k.consume(ctx, group, []string{topic}, newConsumerGroupHandler(replyHandler(k.Publish, responder), k.concurrency))
+	return nil
+}
 
+// subscribeReplies consumes the reply topic with a consumer group of its own
+// so every instance sees all replies. It waits until the group has joined,
+// since with the latest offset reset replies sent earlier would be missed.
+func (k *Kafka) subscribeReplies(topic string, handler Handler) error {
+	group, err := k.newGroup(fmt.Sprintf("%s.replies.%s", k.groupID, uuid.New().String()))
+	if err != nil {
+		return err
+	}
+
+	h := newConsumerGroupHandler(handler, k.concurrency)
+	k.consume(k.ctx, group, []string{topic}, h)
+
+	select {
+	case <-h.ready:
+		return nil
+	case <-time.After(replyGroupReadyTimeout):
+		return fmt.Errorf("reply consumer group did not join within %s", replyGroupReadyTimeout)
+	case <-k.ctx.Done():
+		return ErrNotConnected
+	}
+}
+
+// SubscribeBatch consumes topics in batches per partition. It joins the
+// group with a member of its own, since a member runs one session at a time.
+func (k *Kafka) SubscribeBatch(ctx context.Context, topics []string, opts BatchOptions, handler BatchHandler) error {
+	group, err := k.newGroup(k.groupID)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	for _, topic := range topics {
+		k.subscribed[topic] = true
+	}
+	k.mu.Unlock()
+
+	k.consume(ctx, group, topics, &batchConsumerGroupHandler{handler: handler, opts: opts.withDefaults()})
 	return nil
 }
 
@@ -131,6 +350,13 @@ func (k *Kafka) Close() error {
 
 	var errs []error
 
+	k.cancel()
+	for _, group := range k.groups {
+		if err := group.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close consumer group: %w", err))
+		}
+	}
+
 	if k.producer != nil {
 		if err := k.producer.Close(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to close producer: %w", err))
@@ -143,6 +369,12 @@ func (k *Kafka) Close() error {
 		}
 	}
 
+	if k.client != nil && !k.client.Closed() {
+		if err := k.client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close client: %w", err))
+		}
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("multiple close errors: %v", errs)
 	}
@@ -150,15 +382,78 @@ func (k *Kafka) Close() error {
 	return nil
 }
 
+// HealthCheck checks that the cluster answers a metadata request
+func (k *Kafka) HealthCheck(ctx context.Context) error {
+	if k.client == nil || k.client.Closed() {
+		return ErrNotConnected
+	}
+
+	// sarama does not take a context, so the check runs in the background
+	done := make(chan error, 1)
+	go func() {
+		done <- k.client.RefreshMetadata()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("kafka cluster unreachable: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Backoff between redeliveries of a message whose handler keeps failing
+const (
+	redeliveryInitialBackoff = 500 * time.Millisecond
+	redeliveryMaxBackoff     = 30 * time.Second
+)
+
 // consumerGroupHandler implements sarama.ConsumerGroupHandler
 type consumerGroupHandler struct {
-	handler Handler
+	handler     Handler
+	concurrency config.ConcurrencyConfig
+
+	// ready is closed when the first session starts
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+func newConsumerGroupHandler(handler Handler, concurrency config.ConcurrencyConfig) *consumerGroupHandler {
+	return &consumerGroupHandler{
+		handler:     handler,
+		concurrency: concurrency,
+		ready:       make(chan struct{}),
+	}
+}
+
+func (h *consumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error {
+	h.readyOnce.Do(func() { close(h.ready) })
+	return nil
 }
 
-func (h *consumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
 func (h *consumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
 
+// ConsumeClaim hands the messages of a partition to a worker pool. Messages
+// with the same key run in order on one worker. Offsets are marked only up
+// to the first message that is not done yet, so a restart never skips a
+// message that may still succeed. The claim returns once all workers are
+// idle, before the partition can be assigned elsewhere.
 func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+
+	workers, maxInFlight := concurrencyLimits(h.concurrency, claim.Topic())
+	pool := newWorkerPool(workers, maxInFlight)
+	defer pool.stop()
+
+	// A slot is held until the offset is marked
+	offsets := newOffsetTracker(func(msg *sarama.ConsumerMessage) {
+		session.MarkMessage(msg, "")
+		pool.release()
+	})
+
 	for {
 		select {
 		case msg := <-claim.Messages():
@@ -171,29 +466,166 @@ func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 				zap.Int32("partition", msg.Partition),
 				zap.Int64("offset", msg.Offset))
 
-			message := &Message{
-				Topic:   msg.Topic,
-				Payload: msg.Value,
-				Headers: make(map[string]string),
+			// Wait for a free slot before taking more messages
+			if !pool.acquire(ctx) {
+				return nil
 			}
 
-			// Copy headers
-			for _, header := range msg.Headers {
-				message.Headers[string(header.Key)] = string(header.Value)
-			}
+			message := messageFromKafka(msg)
 
-			// Call handler
-			if err := h.handler(session.Context(), message); err != nil {
-				logger.Error("Failed to handle message",
-					zap.String("topic", msg.Topic),
-					zap.Error(err))
-				continue
+			// Call handler. A failed message is retried until it succeeds,
+			// fails permanently or the session ends.
+			tracked := offsets.add(msg)
+			pool.submit(message.Key, func() {
+				if ctx.Err() == nil && h.handle(ctx, message) {
+					offsets.done(tracked)
+				}
+			})
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// offsetTracker marks offsets of a partition in order as messages finish,
+// stopping at the first message still in progress
+type offsetTracker struct {
+	mark func(*sarama.ConsumerMessage)
+
+	mu      sync.Mutex
+	pending []*trackedOffset // in offset order
+}
+
+// trackedOffset is a message whose offset is not marked yet
+type trackedOffset struct {
+	msg  *sarama.ConsumerMessage
+	done bool
+}
+
+func newOffsetTracker(mark func(*sarama.ConsumerMessage)) *offsetTracker {
+	return &offsetTracker{mark: mark}
+}
+
+// add records a message that was handed to a worker
+func (t *offsetTracker) add(msg *sarama.ConsumerMessage) *trackedOffset {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked := &trackedOffset{msg: msg}
+	t.pending = append(t.pending, tracked)
+	return tracked
+}
+
+// done records that a message was processed and marks every completed
+// offset at the start of the pending list
+func (t *offsetTracker) done(tracked *trackedOffset) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked.done = true
+	n := 0
+	for n < len(t.pending) && t.pending[n].done {
+		t.mark(t.pending[n].msg)
+		n++
+	}
+	t.pending = t.pending[n:]
+}
+
+// handle calls the handler until it succeeds or fails permanently. It
+// returns false if the session ended before the message was processed.
+func (h *consumerGroupHandler) handle(ctx context.Context, message *Message) bool {
+	return redeliver(ctx, messageLogger(ctx, message), func() error {
+		return h.handler(ctx, message)
+	})
+}
+
+// messageFromKafka converts a consumed Kafka message
+func messageFromKafka(msg *sarama.ConsumerMessage) *Message {
+	message := &Message{
+		Topic:     msg.Topic,
+		Key:       string(msg.Key),
+		Payload:   msg.Value,
+		Headers:   make(map[string]string),
+		Timestamp: msg.Timestamp,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+	}
+
+	// Copy headers
+	for _, header := range msg.Headers {
+		message.Headers[string(header.Key)] = string(header.Value)
+	}
+	return message
+}
+
+// batchConsumerGroupHandler hands the messages of each claimed partition
+// to a batch handler
+type batchConsumerGroupHandler struct {
+	handler BatchHandler
+	opts    BatchOptions
+}
+
+func (h *batchConsumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
+func (h *batchConsumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim collects batches from a partition. A failed batch is retried
+// like a single message, and the offset is marked after the last message of
+// a batch that was processed.
+func (h *batchConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx := session.Context()
+	for {
+		batch, open := collectBatch(ctx, claim.Messages(), h.opts.Size, h.opts.Wait)
+		if len(batch) > 0 {
+			messages := make([]*Message, len(batch))
+			for i, msg := range batch {
+				messages[i] = messageFromKafka(msg)
 			}
 
-			// Mark message as processed
-			session.MarkMessage(msg, "")
-		case <-session.Context().Done():
+			log := logger.FromContext(ctx).With(zap.String("topic", claim.Topic()), zap.Int("batch_size", len(messages)))
+			processed := redeliver(ctx, log, func() error {
+				return h.handler(ctx, messages)
+			})
+			if !processed {
+				return nil
+			}
+			session.MarkMessage(batch[len(batch)-1], "")
+		}
+		if !open {
 			return nil
 		}
 	}
-} 
\ No newline at end of file
+}
+
+// redeliver calls fn until it succeeds or fails permanently, logging
+// failures to log. It returns false if ctx ended first.
+func redeliver(ctx context.Context, log *zap.Logger, fn func() error) bool {
+	for failures := 1; ; failures++ {
+		err := fn()
+		if err == nil {
+			return true
+		}
+		if IsPermanent(err) {
+			log.Error("Failed to handle message, skipping", zap.Error(err))
+			return true
+		}
+
+		backoff := redeliveryBackoff(failures)
+		log.Error("Failed to handle message, redelivering",
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		if sleep(ctx, backoff) != nil {
+			return false
+		}
+	}
+}
+
+// redeliveryBackoff returns the wait before redelivering a message that
+// failed failures times in a row. It doubles up to redeliveryMaxBackoff.
+func redeliveryBackoff(failures int) time.Duration {
+	backoff := redeliveryInitialBackoff
+	for i := 1; i < failures && backoff < redeliveryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, redeliveryMaxBackoff)
+}
@@ -0,0 +1,289 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/IBM/sarama"
+)
+
+// clusterAdmin returns the admin client on the shared connection. It is
+// never closed on its own, since closing it closes the shared client.
+func (k *Kafka) clusterAdmin() (sarama.ClusterAdmin, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.client == nil || k.client.Closed() {
+		return nil, ErrNotConnected
+	}
+	if k.admin == nil {
+		admin, err := sarama.NewClusterAdminFromClient(k.client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cluster admin: %w", err)
+		}
+		k.admin = admin
+	}
+	return k.admin, nil
+}
+
+// DescribeTopics returns partition counts of the given topics. Topics that
+// do not exist are reported without partitions rather than created.
+func (k *Kafka) DescribeTopics(ctx context.Context, topics []string) ([]TopicInfo, error) {
+	admin, err := k.clusterAdmin()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topics: %w", err)
+	}
+
+	infos := make([]TopicInfo, 0, len(metadata))
+	for _, topic := range metadata {
+		info := TopicInfo{Name: topic.Name}
+		if topic.Err == sarama.ErrNoError {
+			info.Exists = true
+			info.Partitions = len(topic.Partitions)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Lag compares the committed offsets of group with the newest offset of
+// every partition. Partitions without a committed offset count from the
+// oldest retained message.
+func (k *Kafka) Lag(ctx context.Context, group string, topics []string) (*GroupLag, error) {
+	admin, err := k.clusterAdmin()
+	if err != nil {
+		return nil, err
+	}
+
+	if group == "" {
+		group = k.groupID
+	}
+	partitions, err := k.partitions(admin, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	committed, err := admin.ListConsumerGroupOffsets(group, partitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of group %s: %w", group, err)
+	}
+
+	lag := &GroupLag{Group: group, Partitions: []PartitionLag{}}
+	for _, topic := range topics {
+		for _, partition := range partitions[topic] {
+			latest, err := k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get newest offset of %s/%d: %w", topic, partition, err)
+			}
+
+			p := PartitionLag{Topic: topic, Partition: partition, Committed: -1, Latest: latest}
+			start := int64(0)
+			if block := committed.GetBlock(topic, partition); block != nil && block.Offset >= 0 {
+				p.Committed = block.Offset
+				start = block.Offset
+			} else if start, err = k.client.GetOffset(topic, partition, sarama.OffsetOldest); err != nil {
+				return nil, fmt.Errorf("failed to get oldest offset of %s/%d: %w", topic, partition, err)
+			}
+			p.Lag = max(latest-start, 0)
+
+			lag.Partitions = append(lag.Partitions, p)
+			lag.Total += p.Lag
+		}
+	}
+	return lag, nil
+}
+
+// ResetOffsets commits new offsets for group on every partition of topics.
+// Kafka only accepts the commit while the group has no members, so every
+// instance must pause the topics of the group first.
+func (k *Kafka) ResetOffsets(ctx context.Context, group string, topics []string, reset OffsetReset) error {
+	if err := reset.Validate(); err != nil {
+		return err
+	}
+
+	admin, err := k.clusterAdmin()
+	if err != nil {
+		return err
+	}
+
+	if group == "" {
+		group = k.groupID
+	}
+
+	// Refuse while the group is consuming
+	groups, err := admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return fmt.Errorf("failed to describe group %s: %w", group, err)
+	}
+	for _, description := range groups {
+		if len(description.Members) > 0 {
+			return fmt.Errorf("%w: %s has %d members", ErrGroupActive, group, len(description.Members))
+		}
+	}
+
+	partitions, err := k.partitions(admin, topics)
+	if err != nil {
+		return err
+	}
+
+	// Resolve the target offset of every partition
+	targets := make(map[string]map[int32]int64, len(partitions))
+	for topic, ids := range partitions {
+		targets[topic] = make(map[int32]int64, len(ids))
+		for _, partition := range ids {
+			offset, err := k.resetOffset(topic, partition, reset)
+			if err != nil {
+				return err
+			}
+			targets[topic][partition] = offset
+		}
+	}
+
+	if err := k.commitOffsets(group, targets); err != nil {
+		return err
+	}
+
+	// The offset manager reports commit failures only through its error
+	// channels, so read the offsets back
+	committed, err := admin.ListConsumerGroupOffsets(group, partitions)
+	if err != nil {
+		return fmt.Errorf("failed to verify offsets of group %s: %w", group, err)
+	}
+	for topic, offsets := range targets {
+		for partition, offset := range offsets {
+			block := committed.GetBlock(topic, partition)
+			if block == nil || block.Offset != offset {
+				return fmt.Errorf("failed to reset offset of %s/%d for group %s", topic, partition, group)
+			}
+		}
+	}
+	return nil
+}
+
+// resetOffset returns the offset a partition is reset to
+func (k *Kafka) resetOffset(topic string, partition int32, reset OffsetReset) (int64, error) {
+	at := sarama.OffsetNewest
+	switch reset.To {
+	case ResetEarliest:
+		at = sarama.OffsetOldest
+	case ResetTimestamp:
+		at = reset.Timestamp.UnixMilli()
+	}
+
+	offset, err := k.client.GetOffset(topic, partition, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up offset of %s/%d: %w", topic, partition, err)
+	}
+
+	// No message at or after the timestamp: start after the last one
+	if offset < 0 {
+		offset, err = k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return 0, fmt.Errorf("failed to look up offset of %s/%d: %w", topic, partition, err)
+		}
+	}
+	return offset, nil
+}
+
+// commitOffsets commits offsets for group outside of a group session
+func (k *Kafka) commitOffsets(group string, targets map[string]map[int32]int64) error {
+	manager, err := sarama.NewOffsetManagerFromClient(group, k.client)
+	if err != nil {
+		return fmt.Errorf("failed to create offset manager: %w", err)
+	}
+	defer manager.Close()
+
+	var managed []sarama.PartitionOffsetManager
+	defer func() {
+		for _, pom := range managed {
+			pom.AsyncClose()
+		}
+	}()
+
+	for topic, offsets := range targets {
+		for partition, offset := range offsets {
+			pom, err := manager.ManagePartition(topic, partition)
+			if err != nil {
+				return fmt.Errorf("failed to manage offset of %s/%d: %w", topic, partition, err)
+			}
+			managed = append(managed, pom)
+			pom.ResetOffset(offset, "")
+		}
+	}
+
+	manager.Commit()
+	return nil
+}
+
+// partitions returns the partition IDs of every topic. Unknown topics are
+// an error.
+func (k *Kafka) partitions(admin sarama.ClusterAdmin, topics []string) (map[string][]int32, error) {
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe topics: %w", err)
+	}
+
+	partitions := make(map[string][]int32, len(metadata))
+	for _, topic := range metadata {
+		if errors.Is(topic.Err, sarama.ErrUnknownTopicOrPartition) {
+			return nil, fmt.Errorf("%w: %s", ErrTopicNotFound, topic.Name)
+		}
+		if topic.Err != sarama.ErrNoError {
+			return nil, fmt.Errorf("topic %s: %w", topic.Name, topic.Err)
+		}
+		ids := make([]int32, 0, len(topic.Partitions))
+		for _, partition := range topic.Partitions {
+			ids = append(ids, partition.ID)
+		}
+		slices.Sort(ids)
+		partitions[topic.Name] = ids
+	}
+	return partitions, nil
+}
+
+// Subscriptions lists the topics subscribed on this client
+func (k *Kafka) Subscriptions() []SubscriptionInfo {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	infos := make([]SubscriptionInfo, 0, len(k.subscribed))
+	for topic := range k.subscribed {
+		infos = append(infos, SubscriptionInfo{Topic: topic, Paused: k.paused.isPaused(topic)})
+	}
+	slices.SortFunc(infos, func(a, b SubscriptionInfo) int {
+		return strings.Compare(a.Topic, b.Topic)
+	})
+	return infos
+}
+
+// Pause removes topic from this instance's group sessions, so its
+// partitions are assigned to other members. Offsets of messages in flight
+// are committed before the session ends.
+func (k *Kafka) Pause(topic string) error {
+	return k.setPaused(topic, true)
+}
+
+// Resume adds a paused topic back to this instance's group sessions
+func (k *Kafka) Resume(topic string) error {
+	return k.setPaused(topic, false)
+}
+
+func (k *Kafka) setPaused(topic string, paused bool) error {
+	k.mu.Lock()
+	subscribed := k.subscribed[topic]
+	k.mu.Unlock()
+
+	if !subscribed {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, topic)
+	}
+	k.paused.set(topic, paused)
+	return nil
+}
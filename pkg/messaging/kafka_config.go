@@ -0,0 +1,203 @@
+package messaging
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+	"go-microservice/internal/config"
+)
+
+// SASL mechanisms supported for Kafka authentication
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	SASLMechanismSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+// newSaramaConfig builds the sarama client config from the Kafka config
+func newSaramaConfig(cfg *config.KafkaConfig) (*sarama.Config, error) {
+	sc := sarama.NewConfig()
+	if cfg.ClientID != "" {
+		sc.ClientID = cfg.ClientID
+	}
+
+	if cfg.Version != "" {
+		version, err := sarama.ParseKafkaVersion(cfg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kafka version: %w", err)
+		}
+		sc.Version = version
+	}
+
+	// Producer
+	sc.Producer.Return.Successes = true
+	sc.Producer.Retry.Max = 5
+	if cfg.Producer.MaxRetries > 0 {
+		sc.Producer.Retry.Max = cfg.Producer.MaxRetries
+	}
+
+	switch strings.ToLower(cfg.Producer.Acks) {
+	case "", "all", "-1":
+		sc.Producer.RequiredAcks = sarama.WaitForAll
+	case "leader", "1":
+		sc.Producer.RequiredAcks = sarama.WaitForLocal
+	case "none", "0":
+		sc.Producer.RequiredAcks = sarama.NoResponse
+	default:
+		return nil, fmt.Errorf("unsupported producer acks %q", cfg.Producer.Acks)
+	}
+
+	if cfg.Producer.Compression != "" {
+		var codec sarama.CompressionCodec
+		if err := codec.UnmarshalText([]byte(strings.ToLower(cfg.Producer.Compression))); err != nil {
+			return nil, fmt.Errorf("unsupported producer compression %q", cfg.Producer.Compression)
+		}
+		sc.Producer.Compression = codec
+	}
+
+	partitioner, err := partitioner(cfg.Producer.Partitioner)
+	if err != nil {
+		return nil, err
+	}
+	sc.Producer.Partitioner = partitioner
+
+	// Idempotence needs acks from all replicas and one request in flight
+	if cfg.Producer.Idempotent {
+		sc.Producer.Idempotent = true
+		sc.Producer.RequiredAcks = sarama.WaitForAll
+		sc.Net.MaxOpenRequests = 1
+		if !sc.Version.IsAtLeast(sarama.V0_11_0_0) {
+			sc.Version = sarama.V0_11_0_0
+		}
+	}
+
+	// Consumer
+	sc.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	switch strings.ToLower(cfg.AutoOffsetReset) {
+	case "", "latest":
+		sc.Consumer.Offsets.Initial = sarama.OffsetNewest
+	case "earliest":
+		sc.Consumer.Offsets.Initial = sarama.OffsetOldest
+	default:
+		return nil, fmt.Errorf("unsupported auto offset reset %q", cfg.AutoOffsetReset)
+	}
+	if cfg.SessionTimeout > 0 {
+		sc.Consumer.Group.Session.Timeout = cfg.SessionTimeout
+	}
+	if cfg.HeartbeatInterval > 0 {
+		sc.Consumer.Group.Heartbeat.Interval = cfg.HeartbeatInterval
+	}
+	// Like the Java client, the rebalance timeout follows the max poll interval
+	if cfg.MaxPollInterval > 0 {
+		sc.Consumer.Group.Rebalance.Timeout = cfg.MaxPollInterval
+	}
+	if cfg.MaxPollRecords > 0 {
+		sc.ChannelBufferSize = cfg.MaxPollRecords
+	}
+
+	if err := applySecurity(sc, &cfg.Security); err != nil {
+		return nil, err
+	}
+
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid Kafka config: %w", err)
+	}
+	return sc, nil
+}
+
+// applySecurity configures SASL authentication and TLS
+func applySecurity(sc *sarama.Config, sec *config.SecurityConfig) error {
+	if sec.Enabled {
+		sc.Net.SASL.Enable = true
+		sc.Net.SASL.User = sec.Username
+		sc.Net.SASL.Password = sec.Password
+
+		switch strings.ToUpper(sec.Mechanism) {
+		case "", SASLMechanismPlain:
+			sc.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case SASLMechanismSCRAMSHA256:
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hash: scram.HashGeneratorFcn(sha256.New)}
+			}
+		case SASLMechanismSCRAMSHA512:
+			sc.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			sc.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &scramClient{hash: scram.HashGeneratorFcn(sha512.New)}
+			}
+		default:
+			return fmt.Errorf("unsupported SASL mechanism %q", sec.Mechanism)
+		}
+	}
+
+	if sec.TLS || sec.CAFile != "" || sec.CertFile != "" {
+		tlsConfig, err := newTLSConfig(sec)
+		if err != nil {
+			return err
+		}
+		sc.Net.TLS.Enable = true
+		sc.Net.TLS.Config = tlsConfig
+	}
+
+	return nil
+}
+
+// newTLSConfig loads the CA and client certificate files
+func newTLSConfig(sec *config.SecurityConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: sec.SkipVerify,
+	}
+
+	if sec.CAFile != "" {
+		ca, err := os.ReadFile(sec.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", sec.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if sec.CertFile != "" || sec.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(sec.CertFile, sec.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// scramClient implements sarama.SCRAMClient
+type scramClient struct {
+	hash scram.HashGeneratorFcn
+	conv *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(username, password, authzID string) error {
+	client, err := c.hash.NewClient(username, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conv = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conv.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conv.Done()
+}
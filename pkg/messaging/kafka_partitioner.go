@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// defaultPartitioner is used when no partitioner is configured
+const defaultPartitioner = "hash"
+
+// stickyPartitionerBatch is how many unkeyed messages the sticky
+// partitioner sends to one partition before it moves on
+const stickyPartitionerBatch = 100
+
+// Registry of Kafka producer partitioners
+var partitioners = make(map[string]sarama.PartitionerConstructor)
+
+// RegisterPartitioner registers a Kafka producer partitioner that can be
+// selected with kafka.producer.partitioner
+func RegisterPartitioner(name string, constructor sarama.PartitionerConstructor) {
+	partitioners[name] = constructor
+}
+
+func init() {
+	RegisterPartitioner("hash", sarama.NewHashPartitioner)
+	RegisterPartitioner("crc32", sarama.NewConsistentCRCHashPartitioner)
+	RegisterPartitioner("sticky", NewStickyPartitioner)
+	RegisterPartitioner("random", sarama.NewRandomPartitioner)
+	RegisterPartitioner("roundrobin", sarama.NewRoundRobinPartitioner)
+	RegisterPartitioner("manual", sarama.NewManualPartitioner)
+}
+
+// partitioner returns the partitioner registered under name
+func partitioner(name string) (sarama.PartitionerConstructor, error) {
+	if name == "" {
+		name = defaultPartitioner
+	}
+	constructor, exists := partitioners[name]
+	if !exists {
+		return nil, fmt.Errorf("unsupported producer partitioner %q", name)
+	}
+	return constructor, nil
+}
+
+// stickyPartitioner hashes keyed messages like the hash partitioner and
+// keeps unkeyed messages on one random partition for a while, so they fill
+// larger produce requests than when spread message by message
+type stickyPartitioner struct {
+	hash sarama.Partitioner
+
+	mu        sync.Mutex
+	partition int32
+	remaining int
+}
+
+// NewStickyPartitioner creates a sticky partitioner for a topic
+func NewStickyPartitioner(topic string) sarama.Partitioner {
+	return &stickyPartitioner{hash: sarama.NewHashPartitioner(topic)}
+}
+
+// Partition picks the partition of a message
+func (p *stickyPartitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if msg.Key != nil {
+		return p.hash.Partition(msg, numPartitions)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.remaining <= 0 || p.partition >= numPartitions {
+		p.partition = rand.Int32N(numPartitions)
+		p.remaining = stickyPartitionerBatch
+	}
+	p.remaining--
+	return p.partition, nil
+}
+
+// RequiresConsistency reports that keyed messages must keep their partition
+func (p *stickyPartitioner) RequiresConsistency() bool {
+	return true
+}
+
+// MessageRequiresConsistency lets unkeyed messages move to another
+// partition when theirs is unavailable
+func (p *stickyPartitioner) MessageRequiresConsistency(msg *sarama.ProducerMessage) bool {
+	return msg.Key != nil
+}
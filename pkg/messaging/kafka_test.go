@@ -0,0 +1,175 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+func TestOffsetTrackerMarksContiguousPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []int     // indexes of the messages, in completion order
+		want  [][]int64 // offsets marked after each completion
+	}{
+		{
+			name:  "in order",
+			order: []int{0, 1, 2},
+			want:  [][]int64{{100}, {101}, {102}},
+		},
+		{
+			name:  "reverse order",
+			order: []int{2, 1, 0},
+			want:  [][]int64{nil, nil, {100, 101, 102}},
+		},
+		{
+			name:  "gap filled last",
+			order: []int{0, 2, 3, 1},
+			want:  [][]int64{{100}, nil, nil, {101, 102, 103}},
+		},
+		{
+			name:  "pairs swapped",
+			order: []int{1, 0, 3, 2},
+			want:  [][]int64{nil, {100, 101}, nil, {102, 103}},
+		},
+		{
+			name:  "first message last",
+			order: []int{3, 1, 2, 0},
+			want:  [][]int64{nil, nil, nil, {100, 101, 102, 103}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var marked []int64
+			tracker := newOffsetTracker(func(msg *sarama.ConsumerMessage) {
+				marked = append(marked, msg.Offset)
+			})
+
+			tracked := make([]*trackedOffset, len(tt.order))
+			for i := range tracked {
+				tracked[i] = tracker.add(&sarama.ConsumerMessage{Offset: int64(100 + i)})
+			}
+
+			for step, i := range tt.order {
+				marked = nil
+				tracker.done(tracked[i])
+				if !slices.Equal(marked, tt.want[step]) {
+					t.Errorf("after completing offset %d marked %v, want %v", 100+i, marked, tt.want[step])
+				}
+			}
+			if len(tracker.pending) != 0 {
+				t.Errorf("%d offsets still pending after all completed", len(tracker.pending))
+			}
+		})
+	}
+}
+
+func TestWorkerPoolKeepsKeyOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		workers     int
+		maxInFlight int
+		keys        []string
+	}{
+		{"single worker", 1, 4, []string{"a", "b", "a", "b"}},
+		{"one key", 4, 8, []string{"a", "a", "a", "a", "a", "a"}},
+		{"several keys", 4, 8, []string{"a", "b", "c", "a", "b", "c", "a", "b", "c"}},
+		{"without keys", 3, 6, []string{"", "", "", "", "", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := newWorkerPool(tt.workers, tt.maxInFlight)
+
+			var mu sync.Mutex
+			ran := make(map[string][]int)
+			for i, key := range tt.keys {
+				if !pool.acquire(t.Context()) {
+					t.Fatal("acquire failed")
+				}
+				if !pool.submit(key, func() {
+					defer pool.release()
+					// Give tasks of other keys a chance to overtake
+					time.Sleep(time.Millisecond)
+					mu.Lock()
+					ran[key] = append(ran[key], i)
+					mu.Unlock()
+				}) {
+					t.Fatal("submit failed")
+				}
+			}
+			pool.stop()
+
+			var total int
+			for key, order := range ran {
+				total += len(order)
+				if key != "" && !slices.IsSorted(order) {
+					t.Errorf("tasks of key %q ran in order %v, want submission order", key, order)
+				}
+			}
+			if total != len(tt.keys) {
+				t.Errorf("%d tasks ran, want %d", total, len(tt.keys))
+			}
+		})
+	}
+}
+
+func TestWorkerPoolBoundsInFlight(t *testing.T) {
+	pool := newWorkerPool(2, 2)
+	defer pool.stop()
+
+	for i := 0; i < 2; i++ {
+		if !pool.acquire(t.Context()) {
+			t.Fatalf("acquire %d failed with free slots", i)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if pool.acquire(ctx) {
+		t.Fatal("acquire succeeded with all slots taken")
+	}
+
+	pool.release()
+	if !pool.acquire(t.Context()) {
+		t.Fatal("acquire failed after a slot was released")
+	}
+}
+
+func TestWorkerPoolRejectsTasksAfterStop(t *testing.T) {
+	pool := newWorkerPool(2, 2)
+	pool.stop()
+
+	if pool.submit("a", func() { t.Error("task ran after stop") }) {
+		t.Error("submit succeeded after stop")
+	}
+}
+
+func TestRedeliveryBackoffIsCapped(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 500 * time.Millisecond},
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{6, 16 * time.Second},
+		{7, 30 * time.Second},
+		{8, 30 * time.Second},
+		{1000, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.failures), func(t *testing.T) {
+			if got := redeliveryBackoff(tt.failures); got != tt.want {
+				t.Errorf("redeliveryBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
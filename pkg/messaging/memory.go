@@ -0,0 +1,328 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go-microservice/internal/config"
+	"go.uber.org/zap"
+)
+
+// Delivery semantics of the in-process backend
+const (
+	DeliveryAtMostOnce  = "at-most-once"
+	DeliveryAtLeastOnce = "at-least-once"
+)
+
+// Defaults for in-process backend settings left empty in the config
+const (
+	defaultMemoryBufferSize      = 1000
+	defaultMemoryRedeliveryDelay = 100 * time.Millisecond
+)
+
+// memoryInboxPrefix starts the private reply topic of each client
+const memoryInboxPrefix = "_INBOX."
+
+func init() {
+	RegisterBackend("memory", func(_ context.Context, cfg *config.Config) (Messaging, error) {
+		return NewMemory(cfg.Messaging.Memory, cfg.Kafka.ConsumerGroup)
+	})
+}
+
+// memoryDelivery is a message queued for a consumer group
+type memoryDelivery struct {
+	msg     *Message
+	attempt int
+}
+
+// MemoryBroker routes messages between in-process clients. Every consumer
+// group subscribed to a topic gets its own copy of each message, and the
+// subscribers of one group compete for them. Only groups that subscribed
+// before a message was published receive it.
+type MemoryBroker struct {
+	config config.MemoryMessagingConfig
+
+	mu     sync.Mutex
+	queues map[string]map[string]chan *memoryDelivery // topic -> group -> queue
+}
+
+// NewMemoryBroker creates an in-process broker
+func NewMemoryBroker(cfg config.MemoryMessagingConfig) (*MemoryBroker, error) {
+	switch cfg.Delivery {
+	case "":
+		cfg.Delivery = DeliveryAtLeastOnce
+	case DeliveryAtMostOnce, DeliveryAtLeastOnce:
+	default:
+		return nil, fmt.Errorf("unsupported delivery semantics %q", cfg.Delivery)
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultMemoryBufferSize
+	}
+	if cfg.RedeliveryDelay <= 0 {
+		cfg.RedeliveryDelay = defaultMemoryRedeliveryDelay
+	}
+
+	return &MemoryBroker{
+		config: cfg,
+		queues: make(map[string]map[string]chan *memoryDelivery),
+	}, nil
+}
+
+// Client returns a client that consumes as a member of group
+func (b *MemoryBroker) Client(group string) *Memory {
+	m := &Memory{
+		broker: b,
+		group:  group,
+		done:   make(chan struct{}),
+	}
+	m.inbox = newReplyInbox(memoryInboxPrefix+uuid.New().String(), func(topic string, handler Handler) error {
+		return m.Subscribe(context.Background(), topic, handler)
+	})
+	return m
+}
+
+// queue returns the queue of a consumer group on a topic, creating it on first use
+func (b *MemoryBroker) queue(topic, group string) chan *memoryDelivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	groups := b.queues[topic]
+	if groups == nil {
+		groups = make(map[string]chan *memoryDelivery)
+		b.queues[topic] = groups
+	}
+	q := groups[group]
+	if q == nil {
+		q = make(chan *memoryDelivery, b.config.BufferSize)
+		groups[group] = q
+	}
+	return q
+}
+
+// publish queues a copy of msg for every group subscribed to its topic. It
+// blocks while a group's queue is full.
+func (b *MemoryBroker) publish(ctx context.Context, msg *Message) error {
+	b.mu.Lock()
+	queues := make([]chan *memoryDelivery, 0, len(b.queues[msg.Topic]))
+	for _, q := range b.queues[msg.Topic] {
+		queues = append(queues, q)
+	}
+	b.mu.Unlock()
+
+	for _, q := range queues {
+		select {
+		case q <- &memoryDelivery{msg: copyMessage(msg), attempt: 1}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Memory implements the Messaging interface in process. It is meant for
+// tests and single-node development; messages do not survive a restart.
+type Memory struct {
+	broker *MemoryBroker
+	group  string
+	inbox  *replyInbox
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewMemory creates a client of a new in-process broker
+func NewMemory(cfg config.MemoryMessagingConfig, group string) (*Memory, error) {
+	broker, err := NewMemoryBroker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return broker.Client(group), nil
+}
+
+// Publish publishes a message to a topic
+func (m *Memory) Publish(ctx context.Context, msg *Message) error {
+	if m.closed() {
+		return ErrNotConnected
+	}
+
+	ensureMessageID(msg)
+	return m.broker.publish(ctx, msg)
+}
+
+// PublishBatch publishes the messages one after another
+func (m *Memory) PublishBatch(ctx context.Context, msgs []*Message) error {
+	errs := make([]error, len(msgs))
+	for i, msg := range msgs {
+		errs[i] = m.Publish(ctx, msg)
+	}
+	return newBatchError(errs)
+}
+
+// Subscribe subscribes to a single topic
+func (m *Memory) Subscribe(ctx context.Context, topic string, handler Handler) error {
+	return m.SubscribeMultiple(ctx, []string{topic}, handler)
+}
+
+// SubscribeMultiple consumes the topics as a member of the client's group
+// until ctx is done or the client is closed
+func (m *Memory) SubscribeMultiple(ctx context.Context, topics []string, handler Handler) error {
+	if m.closed() {
+		return ErrNotConnected
+	}
+
+	for _, topic := range topics {
+		q := m.broker.queue(topic, m.group)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for {
+				select {
+				case d := <-q:
+					m.handle(ctx, q, d, handler)
+				case <-ctx.Done():
+					return
+				case <-m.done:
+					return
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// handle runs the handler for one delivery and schedules a redelivery if it
+// fails with at-least-once semantics
+func (m *Memory) handle(ctx context.Context, q chan *memoryDelivery, d *memoryDelivery, handler Handler) {
+	m.settle(ctx, q, d, handler(ctx, copyMessage(d.msg)))
+}
+
+// settle schedules a redelivery of a failed delivery with at-least-once
+// semantics and drops it otherwise
+func (m *Memory) settle(ctx context.Context, q chan *memoryDelivery, d *memoryDelivery, err error) {
+	if err == nil {
+		return
+	}
+
+	cfg := m.broker.config
+	if cfg.Delivery == DeliveryAtMostOnce || IsPermanent(err) ||
+		(cfg.MaxDeliveries > 0 && d.attempt >= cfg.MaxDeliveries) {
+		messageLogger(ctx, d.msg).Error("Failed to handle message, dropping",
+			zap.Int("delivery", d.attempt),
+			zap.Error(err))
+		return
+	}
+
+	messageLogger(ctx, d.msg).Error("Failed to handle message, redelivering",
+		zap.Int("delivery", d.attempt),
+		zap.Duration("delay", cfg.RedeliveryDelay),
+		zap.Error(err))
+
+	next := &memoryDelivery{msg: d.msg, attempt: d.attempt + 1}
+	time.AfterFunc(cfg.RedeliveryDelay, func() {
+		select {
+		case q <- next:
+		case <-m.done:
+		}
+	})
+}
+
+// SubscribeBatch consumes the topics in batches as a member of the client's
+// group until ctx is done or the client is closed. Every message of a failed
+// batch is redelivered on its own schedule.
+func (m *Memory) SubscribeBatch(ctx context.Context, topics []string, opts BatchOptions, handler BatchHandler) error {
+	if m.closed() {
+		return ErrNotConnected
+	}
+	opts = opts.withDefaults()
+
+	// Stop collecting once the client is closed
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		select {
+		case <-ctx.Done():
+		case <-m.done:
+		}
+	}()
+
+	for _, topic := range topics {
+		q := m.broker.queue(topic, m.group)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for {
+				batch, open := collectBatch(ctx, q, opts.Size, opts.Wait)
+				if len(batch) > 0 {
+					messages := make([]*Message, len(batch))
+					for i, d := range batch {
+						messages[i] = copyMessage(d.msg)
+					}
+
+					err := handler(ctx, messages)
+					for _, d := range batch {
+						m.settle(ctx, q, d, err)
+					}
+				}
+				if !open {
+					return
+				}
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Request publishes a request and waits for the reply on the client's
+// private reply topic
+func (m *Memory) Request(ctx context.Context, topic string, msg *Message) (*Message, error) {
+	return m.inbox.request(ctx, m.Publish, topic, msg)
+}
+
+// Respond serves requests on topic as a member of the client's group
+func (m *Memory) Respond(ctx context.Context, topic string, responder Responder) error {
+	return m.Subscribe(ctx, topic, replyHandler(m.Publish, responder))
+}
+
+// HealthCheck reports an error once the client is closed
+func (m *Memory) HealthCheck(ctx context.Context) error {
+	if m.closed() {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+// Close stops all subscriptions of the client
+func (m *Memory) Close() error {
+	m.closeOnce.Do(func() { close(m.done) })
+	m.wg.Wait()
+	return nil
+}
+
+func (m *Memory) closed() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// copyMessage returns a copy of msg with its own header map, so that
+// consumers cannot see each other's changes
+func copyMessage(msg *Message) *Message {
+	c := *msg
+	c.Headers = maps.Clone(msg.Headers)
+	if c.Headers == nil {
+		c.Headers = make(map[string]string)
+	}
+	return &c
+}
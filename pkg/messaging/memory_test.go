@@ -0,0 +1,136 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-microservice/internal/config"
+)
+
+// payloads counts the deliveries of each payload
+func payloads(deliveries ...[]delivery) map[string]int {
+	seen := make(map[string]int)
+	for _, ds := range deliveries {
+		for _, d := range ds {
+			seen[string(d.msg.Payload)]++
+		}
+	}
+	return seen
+}
+
+func TestMemoryFansOutToGroupsAndSharesWithinGroup(t *testing.T) {
+	broker, err := NewMemoryBroker(config.MemoryMessagingConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients := map[string]*Memory{
+		"billing-1": broker.Client("billing"),
+		"billing-2": broker.Client("billing"),
+		"shipping":  broker.Client("shipping"),
+	}
+	waits := make(map[string]func(n int) []delivery)
+	for name, client := range clients {
+		t.Cleanup(func() { client.Close() })
+		waits[name] = consume(t, client, "orders", func(int) error { return nil })
+	}
+
+	const total = 5
+	for i := 0; i < total; i++ {
+		if err := clients["shipping"].Publish(t.Context(), &Message{Topic: "orders", Payload: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Groups subscribing later only see later messages
+	late := broker.Client("audit")
+	t.Cleanup(func() { late.Close() })
+	waitLate := consume(t, late, "orders", func(int) error { return nil })
+
+	shipping := waits["shipping"](total)
+	time.Sleep(50 * time.Millisecond)
+	billing := payloads(waits["billing-1"](0), waits["billing-2"](0))
+
+	if len(shipping) != total || len(payloads(shipping)) != total {
+		t.Errorf("shipping got %d deliveries of %d messages, want each of the %d once", len(shipping), len(payloads(shipping)), total)
+	}
+	for i := 0; i < total; i++ {
+		if n := billing[fmt.Sprint(i)]; n != 1 {
+			t.Errorf("billing group got message %d %d times, want once across its clients", i, n)
+		}
+	}
+	if got := len(waitLate(0)); got != 0 {
+		t.Errorf("group subscribed after publishing got %d deliveries, want none", got)
+	}
+}
+
+func TestMemoryDeliverySemantics(t *testing.T) {
+	handlerErr := errors.New("handler failed")
+
+	tests := []struct {
+		name      string
+		config    config.MemoryMessagingConfig
+		failures  int // deliveries that fail before the handler succeeds
+		err       error
+		wantTotal int
+	}{
+		{
+			name:      "at-least-once redelivers until the handler succeeds",
+			failures:  2,
+			err:       handlerErr,
+			wantTotal: 3,
+		},
+		{
+			name:      "at-most-once drops failed messages",
+			config:    config.MemoryMessagingConfig{Delivery: DeliveryAtMostOnce},
+			failures:  2,
+			err:       handlerErr,
+			wantTotal: 1,
+		},
+		{
+			name:      "permanent failures are dropped",
+			failures:  2,
+			err:       Permanent(handlerErr),
+			wantTotal: 1,
+		},
+		{
+			name:      "max deliveries reached",
+			config:    config.MemoryMessagingConfig{MaxDeliveries: 2},
+			failures:  5,
+			err:       handlerErr,
+			wantTotal: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.RedeliveryDelay = time.Millisecond
+			client, err := NewMemory(tt.config, "group")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { client.Close() })
+
+			wait := consume(t, client, "orders", func(n int) error {
+				if n <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if err := client.Publish(t.Context(), &Message{Topic: "orders", Payload: []byte(`{"id":"o1"}`)}); err != nil {
+				t.Fatal(err)
+			}
+
+			deliveries := wait(tt.wantTotal)
+			for i, d := range deliveries {
+				if string(d.msg.Payload) != `{"id":"o1"}` {
+					t.Errorf("delivery %d has payload %s", i+1, d.msg.Payload)
+				}
+			}
+			time.Sleep(50 * time.Millisecond)
+			if got := len(wait(0)); got != tt.wantTotal {
+				t.Errorf("got %d deliveries, want %d", got, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package messaging
 import (
 	"context"
 	"errors"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 // Common errors
@@ -10,11 +13,40 @@ var (
 	ErrNotConnected = errors.New("not connected to messaging system")
 )
 
+// HeaderMessageID carries a unique message ID that stays the same across
+// redeliveries. Retried, dead-lettered and replayed copies get their own ID
+// and carry the first one in HeaderOriginalMessageID.
+const HeaderMessageID = "x-message-id"
+
+// HeaderPartitionKey carries the message key on backends without record
+// keys. Kafka sends the key as the record key instead.
+const HeaderPartitionKey = "x-partition-key"
+
+// HeaderTimestamp carries the publish time in RFC 3339 format on backends
+// that do not record one
+const HeaderTimestamp = "x-timestamp"
+
 // Message represents a message in the messaging system
 type Message struct {
-	Topic   string
+	Topic string
+
+	// Key routes related messages to the same partition, or subject on
+	// NATS, so consumers see them in order
+	Key string
+
 	Payload []byte
 	Headers map[string]string
+
+	// Timestamp is when the message was published. Publish sets it when
+	// empty.
+	Timestamp time.Time
+
+	// Partition and Offset locate a consumed or published message. Backends
+	// without partitions report partition 0, and Offset is only set where
+	// the backend numbers messages. Publish ignores the partition unless the
+	// Kafka producer uses the manual partitioner.
+	Partition int32
+	Offset    int64
 }
 
 // Handler is a function that handles incoming messages
@@ -24,13 +56,34 @@ type Handler func(context.Context, *Message) error
 type Messaging interface {
 	// Subscribe subscribes to a single topic
 	Subscribe(ctx context.Context, topic string, handler Handler) error
-	
+
 	// SubscribeMultiple subscribes to multiple topics with the same handler
 	SubscribeMultiple(ctx context.Context, topics []string, handler Handler) error
-	
+
 	// Publish publishes a message to a topic
 	Publish(ctx context.Context, msg *Message) error
-	
+
+	// PublishBatch publishes several messages at once. When some fail it
+	// returns a *BatchError with the error of each message.
+	PublishBatch(ctx context.Context, msgs []*Message) error
+
+	// HealthCheck reports whether the messaging system is reachable
+	HealthCheck(ctx context.Context) error
+
 	// Close closes the messaging connection
 	Close() error
-} 
\ No newline at end of file
+}
+
+// ensureMessageID sets a new message ID header unless the message already
+// has one, and the publish time unless it is set
+func ensureMessageID(msg *Message) {
+	if msg.Headers == nil {
+		msg.Headers = make(map[string]string)
+	}
+	if msg.Headers[HeaderMessageID] == "" {
+		msg.Headers[HeaderMessageID] = uuid.New().String()
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+}
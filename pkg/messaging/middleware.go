@@ -0,0 +1,169 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"go-microservice/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Logging stores a logger identifying the message in the handler's context,
+// see logger.FromContext, and logs every handled message with its outcome
+// and duration
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			start := time.Now()
+			ctx = logger.WithContext(ctx, messageFields(msg)...)
+			err := next(ctx, msg)
+
+			log := logger.FromContext(ctx)
+			if err != nil {
+				log.Warn("Message handling failed", zap.Duration("duration", time.Since(start)), zap.Error(err))
+			} else {
+				log.Debug("Message handled", zap.Duration("duration", time.Since(start)))
+			}
+			return err
+		}
+	}
+}
+
+// messageLogger returns the logger of ctx with the fields identifying msg,
+// for backends and wrappers logging about a message outside its handler
+func messageLogger(ctx context.Context, msg *Message) *zap.Logger {
+	return logger.FromContext(ctx).With(messageFields(msg)...)
+}
+
+// messageFields returns the fields identifying a message in its logs
+func messageFields(msg *Message) []zap.Field {
+	fields := []zap.Field{
+		zap.String("topic", msg.Topic),
+		zap.String("message_id", msg.Headers[HeaderMessageID]),
+	}
+	if originalID := msg.Headers[HeaderOriginalMessageID]; originalID != "" {
+		fields = append(fields, zap.String("original_message_id", originalID))
+	}
+	if msg.Key != "" {
+		fields = append(fields, zap.String("key", msg.Key))
+	}
+	if attempt := msg.Headers[HeaderAttempt]; attempt != "" {
+		fields = append(fields, zap.String("attempt", attempt))
+	}
+	if correlationID := msg.Headers[HeaderCorrelationID]; correlationID != "" {
+		fields = append(fields, zap.String("correlation_id", correlationID))
+	}
+	return fields
+}
+
+// Recovery turns a panicking handler into a permanent error
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.FromContext(ctx).Error("Message handler panicked",
+						zap.Any("panic", r),
+						zap.ByteString("stack", debug.Stack()))
+					err = Permanent(fmt.Errorf("handler panicked: %v", r))
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Timeout cancels the handler's context after d. A zero duration disables it.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		if d <= 0 {
+			return next
+		}
+		return func(ctx context.Context, msg *Message) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// TopicMetrics holds handler statistics for a single topic
+type TopicMetrics struct {
+	Topic         string  `json:"topic"`
+	Handled       uint64  `json:"handled"`
+	Failed        uint64  `json:"failed"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	MaxDurationMs float64 `json:"max_duration_ms"`
+
+	total time.Duration
+	max   time.Duration
+}
+
+// HandlerMetrics collects per-topic handler statistics
+type HandlerMetrics struct {
+	mu     sync.Mutex
+	topics map[string]*TopicMetrics
+}
+
+// NewHandlerMetrics creates an empty metrics collector
+func NewHandlerMetrics() *HandlerMetrics {
+	return &HandlerMetrics{
+		topics: make(map[string]*TopicMetrics),
+	}
+}
+
+// Middleware records the outcome and duration of every handled message
+// under the topic it was originally published to
+func (m *HandlerMetrics) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			m.record(OriginalTopic(msg), time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// Snapshot returns the current statistics ordered by topic
+func (m *HandlerMetrics) Snapshot() []TopicMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make([]TopicMetrics, 0, len(m.topics))
+	for _, metrics := range m.topics {
+		s := *metrics
+		if s.Handled > 0 {
+			s.AvgDurationMs = float64(s.total.Microseconds()) / float64(s.Handled) / 1000
+		}
+		s.MaxDurationMs = float64(s.max.Microseconds()) / 1000
+		snapshot = append(snapshot, s)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Topic < snapshot[j].Topic
+	})
+	return snapshot
+}
+
+// record adds a single handler run to the statistics
+func (m *HandlerMetrics) record(topic string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics, ok := m.topics[topic]
+	if !ok {
+		metrics = &TopicMetrics{Topic: topic}
+		m.topics[topic] = metrics
+	}
+
+	metrics.Handled++
+	if err != nil {
+		metrics.Failed++
+	}
+	metrics.total += duration
+	metrics.max = max(metrics.max, duration)
+}
@@ -0,0 +1,63 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go-microservice/internal/config"
+	"go-microservice/pkg/logger"
+)
+
+func TestFailureLogsCarryMessageFields(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := logger.NewContext(t.Context(), zap.New(core).With(zap.String("component", "consumer")))
+
+	broker, err := NewMemoryBroker(config.MemoryMessagingConfig{MaxDeliveries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := broker.Client("consumer")
+	defer client.Close()
+
+	retrier := NewRetrier(client, config.RetryConfig{MaxAttempts: 1, InitialBackoff: time.Millisecond})
+	handled := make(chan struct{})
+	if err := client.Subscribe(ctx, "orders", func(ctx context.Context, msg *Message) error {
+		defer close(handled)
+		// Shutting down leaves the message to the backend, which drops it
+		// after its last delivery
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		return retrier.Wrap(func(context.Context, *Message) error {
+			return errors.New("handler failed")
+		})(ctx, msg)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Publish(t.Context(), &Message{Topic: "orders", Headers: map[string]string{HeaderMessageID: "m1"}}); err != nil {
+		t.Fatal(err)
+	}
+	<-handled
+
+	for _, message := range []string{"Message handler failed", "Failed to handle message, dropping"} {
+		deadline := time.Now().Add(time.Second)
+		for logs.FilterMessage(message).Len() == 0 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		entries := logs.FilterMessage(message).All()
+		if len(entries) != 1 {
+			t.Errorf("%q logged %d times, want once", message, len(entries))
+			continue
+		}
+		fields := entries[0].ContextMap()
+		if fields["component"] != "consumer" || fields["message_id"] != "m1" || fields["topic"] != "orders" {
+			t.Errorf("%q logged with %v, want the context logger and the message fields", message, fields)
+		}
+	}
+}
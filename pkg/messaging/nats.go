@@ -3,23 +3,38 @@ package messaging
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
+	"go-microservice/internal/config"
 	"go-microservice/pkg/logger"
 	"go.uber.org/zap"
 )
 
-// NATS implements the Messaging interface
+// NATS implements the Messaging interface on core NATS. Delivery is at most
+// once: messages published while no subscriber is online are lost. Use
+// JetStream when messages must survive restarts.
 type NATS struct {
 	conn          *nats.Conn
-	subscriptions map[string]*nats.Subscription
+	subscriptions map[string]*nats.Subscription // by subject
+	pools         []*workerPool
+	concurrency   config.ConcurrencyConfig
+	keySubjects   bool
 	mu            sync.RWMutex
 }
 
-// NewNATS creates a new NATS client
-func NewNATS(url string) (*NATS, error) {
-	conn, err := nats.Connect(url)
+func init() {
+	RegisterBackend("nats-core", func(_ context.Context, cfg *config.Config) (Messaging, error) {
+		return NewNATS(cfg.NATS, cfg.Messaging.Concurrency)
+	})
+}
+
+// NewNATS creates a new NATS client whose subscriptions handle messages
+// with the given concurrency
+func NewNATS(cfg config.NATSConfig, concurrency config.ConcurrencyConfig) (*NATS, error) {
+	conn, err := nats.Connect(cfg.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
@@ -27,6 +42,8 @@ func NewNATS(url string) (*NATS, error) {
 	return &NATS{
 		conn:          conn,
 		subscriptions: make(map[string]*nats.Subscription),
+		concurrency:   concurrency,
+		keySubjects:   cfg.KeySubjects,
 	}, nil
 }
 
@@ -43,45 +60,61 @@ func (n *NATS) SubscribeMultiple(ctx context.Context, topics []string, handler H
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(topics))
 
+	var storeMu sync.Mutex
 	for _, topic := range topics {
 		wg.Add(1)
 		go func(t string) {
 			defer wg.Done()
 
-			// Create subscription
-			sub, err := n.conn.Subscribe(t, func(msg *nats.Msg) {
-				// Create a new context for each message
-				msgCtx := context.Background()
-				
+			// Handlers run on a worker pool instead of the subscription
+			// goroutine, which blocks while all slots are taken
+			pool := newWorkerPool(concurrencyLimits(n.concurrency, t))
+
+			callback := func(msg *nats.Msg) {
+				// Handlers stop retrying once the subscription context is done
+				msgCtx := ctx
+
 				// Create message wrapper
-				message := &Message{
-					Topic:   t,
-					Payload: msg.Data,
-					Headers: make(map[string]string),
+				message := messageFromNATS(t, msg)
+
+				if !pool.acquire(msgCtx) {
+					return
 				}
+				submitted := pool.submit(message.Key, func() {
+					defer pool.release()
 
-				// Copy headers from NATS message
-				for key, values := range msg.Header {
-					if len(values) > 0 {
-						message.Headers[key] = values[0]
+					// Call handler
+					if err := handler(msgCtx, message); err != nil {
+						messageLogger(msgCtx, message).Error("Error handling message", zap.Error(err))
 					}
+				})
+				if !submitted {
+					pool.release()
 				}
+			}
 
-				// Call handler
-				if err := handler(msgCtx, message); err != nil {
-					logger.Error("Error handling message",
-						zap.String("topic", t),
-						zap.Error(err))
+			// Create subscriptions
+			subs := make(map[string]*nats.Subscription)
+			for _, subject := range topicSubjects(t, n.keySubjects) {
+				sub, err := n.conn.Subscribe(subject, callback)
+				if err != nil {
+					for _, sub := range subs {
+						sub.Unsubscribe()
+					}
+					pool.stop()
+					errChan <- fmt.Errorf("failed to subscribe to topic %s: %w", t, err)
+					return
 				}
-			})
-
-			if err != nil {
-				errChan <- fmt.Errorf("failed to subscribe to topic %s: %w", t, err)
-				return
+				subs[subject] = sub
 			}
 
-			// Store subscription
-			n.subscriptions[t] = sub
+			// Store subscriptions
+			storeMu.Lock()
+			for subject, sub := range subs {
+				n.subscriptions[subject] = sub
+			}
+			n.pools = append(n.pools, pool)
+			storeMu.Unlock()
 		}(topic)
 	}
 
@@ -121,18 +154,197 @@ func (n *NATS) Close() error {
 		}
 	}
 
+	// Let running handlers finish
+	for _, pool := range n.pools {
+		pool.stop()
+	}
+	n.pools = nil
+
 	// Close connection
 	n.conn.Close()
 	return nil
 }
 
+// HealthCheck checks the connection with a round trip to the server
+func (n *NATS) HealthCheck(ctx context.Context) error {
+	if n.conn == nil || !n.conn.IsConnected() {
+		return ErrNotConnected
+	}
+
+	// FlushWithContext requires a deadline; Flush uses the default timeout
+	var err error
+	if _, ok := ctx.Deadline(); ok {
+		err = n.conn.FlushWithContext(ctx)
+	} else {
+		err = n.conn.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("nats server unreachable: %w", err)
+	}
+	return nil
+}
+
+// Publish publishes a message to a subject, carrying its headers
 func (n *NATS) Publish(ctx context.Context, msg *Message) error {
-	err := n.conn.Publish(msg.Topic, msg.Payload)
+	ensureMessageID(msg)
+
+	err := n.conn.PublishMsg(n.newMsg(msg))
 	if err != nil {
-		logger.Error("Failed to publish message", zap.Error(err))
+		logger.FromContext(ctx).Error("Failed to publish message", zap.Error(err))
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 	return nil
 }
 
-// ... existing code ... 
\ No newline at end of file
+// PublishBatch buffers all messages on the connection and flushes them
+// together. Core NATS does not acknowledge messages, so success means the
+// server received them.
+func (n *NATS) PublishBatch(ctx context.Context, msgs []*Message) error {
+	errs := make([]error, len(msgs))
+	for i, msg := range msgs {
+		ensureMessageID(msg)
+		if err := n.conn.PublishMsg(n.newMsg(msg)); err != nil {
+			errs[i] = fmt.Errorf("failed to publish message: %w", err)
+		}
+	}
+
+	var err error
+	if _, ok := ctx.Deadline(); ok {
+		err = n.conn.FlushWithContext(ctx)
+	} else {
+		err = n.conn.Flush()
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to flush message batch", zap.Error(err))
+		return fmt.Errorf("failed to flush message batch: %w", err)
+	}
+	return newBatchError(errs)
+}
+
+// newMsg converts a message to be published, moving keyed messages to their
+// key subject
+func (n *NATS) newMsg(msg *Message) *nats.Msg {
+	natsMsg := newNATSMsg(msg)
+	natsMsg.Subject = keySubject(msg.Topic, msg.Key, n.keySubjects)
+	return natsMsg
+}
+
+// Request sends a request with native NATS request/reply
+func (n *NATS) Request(ctx context.Context, topic string, msg *Message) (*Message, error) {
+	return natsRequest(ctx, n.conn, topic, msg)
+}
+
+// Respond serves requests on a subject through a queue subscription
+func (n *NATS) Respond(ctx context.Context, topic string, responder Responder) error {
+	return natsRespond(ctx, n.conn, topic, natsResponderQueue, responder)
+}
+
+// natsResponderQueue is the queue group of core NATS responders
+const natsResponderQueue = "responders"
+
+// natsRequest sends a request over conn and converts the reply
+func natsRequest(ctx context.Context, conn *nats.Conn, topic string, msg *Message) (*Message, error) {
+	ctx, cancel := withRequestTimeout(ctx)
+	defer cancel()
+
+	req := copyMessage(msg)
+	req.Topic = topic
+	ensureMessageID(req)
+
+	reply, err := conn.RequestMsgWithContext(ctx, newNATSMsg(req))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, requestError(ctx)
+		}
+		return nil, fmt.Errorf("request to %s failed: %w", topic, err)
+	}
+
+	message := messageFromNATS(reply.Subject, reply)
+	return message, replyError(message)
+}
+
+// natsRespond serves requests on a subject with a queue subscription, so
+// responders on several replicas share the requests. Replies are published
+// on conn to the request's reply subject.
+func natsRespond(ctx context.Context, conn *nats.Conn, topic, queue string, responder Responder) error {
+	handler := replyHandler(func(_ context.Context, reply *Message) error {
+		return conn.PublishMsg(newNATSMsg(reply))
+	}, responder)
+
+	sub, err := conn.QueueSubscribe(topic, queue, func(msg *nats.Msg) {
+		req := messageFromNATS(topic, msg)
+		req.Headers[HeaderReplyTo] = msg.Reply
+		if err := handler(ctx, req); err != nil {
+			messageLogger(ctx, req).Error("Failed to reply to request", zap.Error(err))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe responder to %s: %w", topic, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		sub.Unsubscribe()
+	}()
+	return nil
+}
+
+// newNATSMsg converts a message to a NATS message, carrying its headers,
+// key and publish time
+func newNATSMsg(msg *Message) *nats.Msg {
+	natsMsg := nats.NewMsg(msg.Topic)
+	natsMsg.Data = msg.Payload
+	for key, value := range msg.Headers {
+		natsMsg.Header.Set(key, value)
+	}
+	if msg.Key != "" {
+		natsMsg.Header.Set(HeaderPartitionKey, msg.Key)
+	}
+	if !msg.Timestamp.IsZero() {
+		natsMsg.Header.Set(HeaderTimestamp, msg.Timestamp.Format(time.RFC3339Nano))
+	}
+	return natsMsg
+}
+
+// messageFromNATS converts a NATS message received on topic
+func messageFromNATS(topic string, msg *nats.Msg) *Message {
+	message := &Message{
+		Topic:   topic,
+		Payload: msg.Data,
+		Headers: make(map[string]string),
+	}
+	for key, values := range msg.Header {
+		if len(values) > 0 {
+			message.Headers[key] = values[0]
+		}
+	}
+	message.Key = message.Headers[HeaderPartitionKey]
+	message.Timestamp, _ = time.Parse(time.RFC3339Nano, message.Headers[HeaderTimestamp])
+	return message
+}
+
+// subjectTokenReplacer replaces characters that may not appear in a subject
+// token
+var subjectTokenReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_")
+
+// keySubject returns the subject a message is published to. With key
+// subjects, keyed messages go to <topic>.<key> so subscribers and streams
+// can filter on the key.
+func keySubject(topic, key string, keySubjects bool) string {
+	if !keySubjects || key == "" {
+		return topic
+	}
+	return topic + "." + subjectTokenReplacer.Replace(key)
+}
+
+// topicSubjects returns the subjects a subscription to topic listens on:
+// the topic itself and, with key subjects, its key subjects. Wildcard
+// topics are used as they are.
+func topicSubjects(topic string, keySubjects bool) []string {
+	if !keySubjects || strings.ContainsAny(topic, "*>") {
+		return []string{topic}
+	}
+	return []string{topic, topic + ".>"}
+}
+
+// ... existing code ...
@@ -0,0 +1,73 @@
+// Package natstest runs an embedded NATS server with JetStream enabled for
+// testing messaging.JetStream without an external broker:
+//
+//	func TestJetStream(t *testing.T) {
+//		cfg := natstest.NewJetStreamServer(t, "orders", "orders.>")
+//		js, err := messaging.NewJetStream(context.Background(), cfg, config.ConcurrencyConfig{})
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		defer js.Close()
+//	}
+package natstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+
+	"go-microservice/internal/config"
+)
+
+// readyTimeout bounds how long to wait for the server to accept connections
+const readyTimeout = 10 * time.Second
+
+// NewJetStreamServer starts an in-process NATS server with JetStream storage
+// in a temporary directory and returns a config whose stream captures the
+// given subjects. Consumers redeliver quickly so retries can be observed in
+// tests. The server shuts down when the test finishes.
+func NewJetStreamServer(t *testing.T, subjects ...string) config.NATSConfig {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("create NATS server: %v", err)
+	}
+
+	go srv.Start()
+	if !srv.ReadyForConnections(readyTimeout) {
+		srv.Shutdown()
+		t.Fatal("NATS server not ready for connections")
+	}
+	t.Cleanup(func() {
+		srv.Shutdown()
+		srv.WaitForShutdown()
+	})
+
+	return config.NATSConfig{
+		URL:           srv.ClientURL(),
+		Name:          t.Name(),
+		MaxReconnects: -1,
+		ReconnectWait: 50 * time.Millisecond,
+		Stream: config.NATSStreamConfig{
+			Name:     "TEST",
+			Subjects: subjects,
+			Storage:  "memory",
+		},
+		Consumer: config.NATSConsumerConfig{
+			Durable:    "test",
+			AckWait:    time.Second,
+			MaxDeliver: 3,
+			Backoff:    []time.Duration{50 * time.Millisecond, 100 * time.Millisecond},
+			FetchBatch: 10,
+		},
+	}
+}
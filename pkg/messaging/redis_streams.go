@@ -0,0 +1,405 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go-microservice/internal/config"
+	"go-microservice/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Defaults for Redis Streams settings left empty in the config
+const (
+	defaultRedisStreamsBatchSize     = 100
+	defaultRedisStreamsBlock         = 2 * time.Second
+	defaultRedisStreamsClaimInterval = 30 * time.Second
+	defaultRedisStreamsClaimMinIdle  = time.Minute
+)
+
+// Stream entry fields. Headers are stored as separate fields with a prefix.
+const (
+	redisFieldPayload      = "payload"
+	redisFieldKey          = "key"
+	redisFieldHeaderPrefix = "h:"
+)
+
+// redisErrorBackoff is the pause after a failed read before trying again
+const redisErrorBackoff = time.Second
+
+func init() {
+	RegisterBackend("redis", func(ctx context.Context, cfg *config.Config) (Messaging, error) {
+		return NewRedisStreams(ctx, &cfg.Redis, cfg.Messaging.Redis, cfg.Kafka.ConsumerGroup)
+	})
+}
+
+// RedisStreams implements the Messaging interface on Redis Streams. Each
+// topic is a stream read through a consumer group, so replicas sharing the
+// group split the messages between them. Entries are acknowledged after the
+// handler succeeds; failed entries stay pending and are claimed again once
+// they have been idle for ClaimMinIdle.
+type RedisStreams struct {
+	client   *redis.Client
+	config   config.RedisStreamsConfig
+	group    string
+	consumer string
+
+	mu      sync.Mutex
+	cancels []context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewRedisStreams connects to Redis and returns a client consuming as a
+// member of group
+func NewRedisStreams(ctx context.Context, redisCfg *config.RedisConfig, cfg config.RedisStreamsConfig, group string) (*RedisStreams, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultRedisStreamsBatchSize
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = defaultRedisStreamsBlock
+	}
+	if cfg.ClaimInterval <= 0 {
+		cfg.ClaimInterval = defaultRedisStreamsClaimInterval
+	}
+	if cfg.ClaimMinIdle <= 0 {
+		cfg.ClaimMinIdle = defaultRedisStreamsClaimMinIdle
+	}
+
+	// Consumer names must be unique within the group
+	consumer := cfg.Consumer
+	if consumer == "" {
+		hostname, _ := os.Hostname()
+		consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", redisCfg.Host, redisCfg.Port),
+		Password: redisCfg.Password,
+		DB:       redisCfg.DB,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisStreams{
+		client:   client,
+		config:   cfg,
+		group:    group,
+		consumer: consumer,
+	}, nil
+}
+
+// stream returns the stream key of a topic
+func (r *RedisStreams) stream(topic string) string {
+	return r.config.KeyPrefix + topic
+}
+
+// Publish appends a message to the topic's stream
+func (r *RedisStreams) Publish(ctx context.Context, msg *Message) error {
+	ensureMessageID(msg)
+
+	err := r.client.XAdd(ctx, r.xAddArgs(msg)).Err()
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to publish message", zap.Error(err))
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return nil
+}
+
+// PublishBatch appends all messages in one pipeline
+func (r *RedisStreams) PublishBatch(ctx context.Context, msgs []*Message) error {
+	cmds := make([]*redis.StringCmd, len(msgs))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, msg := range msgs {
+			ensureMessageID(msg)
+			cmds[i] = pipe.XAdd(ctx, r.xAddArgs(msg))
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+
+	logger.FromContext(ctx).Error("Failed to publish message batch", zap.Error(err))
+	errs := make([]error, len(msgs))
+	for i, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil {
+			errs[i] = fmt.Errorf("failed to publish message: %w", cmdErr)
+		}
+	}
+	if batchErr := newBatchError(errs); batchErr != nil {
+		return batchErr
+	}
+	return fmt.Errorf("failed to publish message batch: %w", err)
+}
+
+// xAddArgs returns the XADD arguments that append msg to its topic's stream
+func (r *RedisStreams) xAddArgs(msg *Message) *redis.XAddArgs {
+	values := map[string]interface{}{redisFieldPayload: msg.Payload}
+	if msg.Key != "" {
+		values[redisFieldKey] = msg.Key
+	}
+	for key, value := range msg.Headers {
+		values[redisFieldHeaderPrefix+key] = value
+	}
+
+	return &redis.XAddArgs{
+		Stream: r.stream(msg.Topic),
+		MaxLen: r.config.MaxLen,
+		Approx: r.config.MaxLen > 0,
+		Values: values,
+	}
+}
+
+// Subscribe subscribes to a single topic
+func (r *RedisStreams) Subscribe(ctx context.Context, topic string, handler Handler) error {
+	return r.SubscribeMultiple(ctx, []string{topic}, handler)
+}
+
+// SubscribeMultiple creates the consumer group on every topic's stream and
+// consumes them until ctx is done or the client is closed
+func (r *RedisStreams) SubscribeMultiple(ctx context.Context, topics []string, handler Handler) error {
+	topicOf := make(map[string]string, len(topics))
+	for _, topic := range topics {
+		stream := r.stream(topic)
+		err := r.client.XGroupCreateMkStream(ctx, stream, r.group, "$").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("failed to create consumer group on %s: %w", stream, err)
+		}
+		topicOf[stream] = topic
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r.mu.Lock()
+	r.cancels = append(r.cancels, cancel)
+	r.mu.Unlock()
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.readLoop(ctx, topicOf, handler)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.claimLoop(ctx, topicOf, handler)
+	}()
+
+	return nil
+}
+
+// readLoop reads new entries of the streams until ctx is done
+func (r *RedisStreams) readLoop(ctx context.Context, topicOf map[string]string, handler Handler) {
+	streams := make([]string, 0, 2*len(topicOf))
+	for stream := range topicOf {
+		streams = append(streams, stream)
+	}
+	for range topicOf {
+		streams = append(streams, ">")
+	}
+
+	for ctx.Err() == nil {
+		results, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.group,
+			Consumer: r.consumer,
+			Streams:  streams,
+			Count:    r.config.BatchSize,
+			Block:    r.config.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.FromContext(ctx).Error("Failed to read from Redis streams", zap.Error(err))
+			sleep(ctx, redisErrorBackoff)
+			continue
+		}
+
+		for _, result := range results {
+			for _, entry := range result.Messages {
+				r.handle(ctx, topicOf[result.Stream], result.Stream, entry, handler)
+			}
+		}
+	}
+}
+
+// claimLoop periodically claims entries that stayed pending too long
+func (r *RedisStreams) claimLoop(ctx context.Context, topicOf map[string]string, handler Handler) {
+	ticker := time.NewTicker(r.config.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for stream, topic := range topicOf {
+				if err := r.claim(ctx, topic, stream, handler); err != nil && ctx.Err() == nil {
+					logger.FromContext(ctx).Error("Failed to claim pending entries",
+						zap.String("stream", stream),
+						zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// claim takes over idle pending entries of a stream and handles them again.
+// Entries delivered more than MaxDeliveries times are acknowledged and dropped.
+func (r *RedisStreams) claim(ctx context.Context, topic, stream string, handler Handler) error {
+	start := "0-0"
+	for {
+		entries, next, err := r.autoClaim(ctx, stream, start)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if r.config.MaxDeliveries > 0 {
+				pending, err := r.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+					Stream: stream,
+					Group:  r.group,
+					Start:  entry.ID,
+					End:    entry.ID,
+					Count:  1,
+				}).Result()
+				if err != nil {
+					return err
+				}
+				if len(pending) == 1 && pending[0].RetryCount > r.config.MaxDeliveries {
+					logger.FromContext(ctx).Error("Message exceeded max deliveries, dropping",
+						zap.String("topic", topic),
+						zap.String("id", entry.ID),
+						zap.Int64("deliveries", pending[0].RetryCount))
+					r.ack(ctx, stream, entry.ID)
+					continue
+				}
+			}
+			r.handle(ctx, topic, stream, entry, handler)
+		}
+
+		if next == "0-0" || next == "" {
+			return nil
+		}
+		start = next
+	}
+}
+
+// autoClaim runs XAUTOCLAIM. The command is sent raw because go-redis v8
+// only parses the two-element reply of Redis 6.2, while Redis 7 adds a third
+// element with the IDs of deleted entries.
+func (r *RedisStreams) autoClaim(ctx context.Context, stream, start string) ([]redis.XMessage, string, error) {
+	reply, err := r.client.Do(ctx, "XAUTOCLAIM", stream, r.group, r.consumer,
+		r.config.ClaimMinIdle.Milliseconds(), start, "COUNT", r.config.BatchSize).Result()
+	if err != nil {
+		return nil, "", err
+	}
+
+	parts, ok := reply.([]interface{})
+	if !ok || len(parts) < 2 {
+		return nil, "", fmt.Errorf("unexpected XAUTOCLAIM reply %v", reply)
+	}
+	next, _ := parts[0].(string)
+	rawEntries, _ := parts[1].([]interface{})
+
+	var entries []redis.XMessage
+	for _, raw := range rawEntries {
+		// Redis 6.2 returns nil for entries deleted while pending
+		entry, ok := raw.([]interface{})
+		if !ok || len(entry) != 2 {
+			continue
+		}
+		id, _ := entry[0].(string)
+		fields, _ := entry[1].([]interface{})
+
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			key, _ := fields[i].(string)
+			values[key] = fields[i+1]
+		}
+		entries = append(entries, redis.XMessage{ID: id, Values: values})
+	}
+	return entries, next, nil
+}
+
+// handle runs the handler for one entry and acknowledges it on success.
+// Entries failing with a permanent error are acknowledged and dropped.
+func (r *RedisStreams) handle(ctx context.Context, topic, stream string, entry redis.XMessage, handler Handler) {
+	message := &Message{
+		Topic:   topic,
+		Headers: make(map[string]string),
+	}
+	for key, value := range entry.Values {
+		s, _ := value.(string)
+		if key == redisFieldPayload {
+			message.Payload = []byte(s)
+		} else if key == redisFieldKey {
+			message.Key = s
+		} else if header, ok := strings.CutPrefix(key, redisFieldHeaderPrefix); ok {
+			message.Headers[header] = s
+		}
+	}
+
+	// Entry IDs start with the time the entry was added in milliseconds
+	if ms, _, ok := strings.Cut(entry.ID, "-"); ok {
+		if millis, err := strconv.ParseInt(ms, 10, 64); err == nil {
+			message.Timestamp = time.UnixMilli(millis)
+		}
+	}
+
+	err := handler(ctx, message)
+	switch {
+	case err == nil:
+		r.ack(ctx, stream, entry.ID)
+	case IsPermanent(err):
+		messageLogger(ctx, message).Error("Failed to handle message, dropping",
+			zap.String("id", entry.ID),
+			zap.Error(err))
+		r.ack(ctx, stream, entry.ID)
+	default:
+		messageLogger(ctx, message).Error("Failed to handle message, leaving it pending",
+			zap.String("id", entry.ID),
+			zap.Error(err))
+	}
+}
+
+// ack acknowledges an entry
+func (r *RedisStreams) ack(ctx context.Context, stream, id string) {
+	if err := r.client.XAck(ctx, stream, r.group, id).Err(); err != nil {
+		logger.FromContext(ctx).Error("Failed to ack message",
+			zap.String("stream", stream),
+			zap.String("id", id),
+			zap.Error(err))
+	}
+}
+
+// HealthCheck pings Redis
+func (r *RedisStreams) HealthCheck(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis unreachable: %w", err)
+	}
+	return nil
+}
+
+// Close stops all subscriptions and closes the Redis connection
+func (r *RedisStreams) Close() error {
+	r.mu.Lock()
+	for _, cancel := range r.cancels {
+		cancel()
+	}
+	r.cancels = nil
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return r.client.Close()
+}
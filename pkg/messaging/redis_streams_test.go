@@ -0,0 +1,198 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+
+	"go-microservice/internal/config"
+)
+
+// newRedisStreams connects a client consuming as consumer of group to an
+// in-memory Redis, claiming entries idle for 50ms every 10ms
+func newRedisStreams(t *testing.T, server *miniredis.Miniredis, group, consumer string, maxDeliveries int64) *RedisStreams {
+	t.Helper()
+
+	r, err := NewRedisStreams(t.Context(), &config.RedisConfig{Host: server.Host(), Port: server.Port()}, config.RedisStreamsConfig{
+		Consumer:      consumer,
+		KeyPrefix:     "stream:",
+		Block:         10 * time.Millisecond,
+		ClaimInterval: 10 * time.Millisecond,
+		ClaimMinIdle:  50 * time.Millisecond,
+		MaxDeliveries: maxDeliveries,
+	}, group)
+	if err != nil {
+		t.Fatalf("NewRedisStreams() error = %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// assertNoPending checks that group acknowledges every entry of topic,
+// which happens just after the handler returns
+func assertNoPending(t *testing.T, r *RedisStreams, topic string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		pending, err := r.client.XPending(t.Context(), r.stream(topic), r.group).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pending.Count == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("%d entries pending, want none", pending.Count)
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRedisStreamsFansOutToGroupsAndSharesWithinGroup(t *testing.T) {
+	server := miniredis.RunT(t)
+	clients := map[string]*RedisStreams{
+		"billing-1": newRedisStreams(t, server, "billing", "billing-1", 0),
+		"billing-2": newRedisStreams(t, server, "billing", "billing-2", 0),
+		"shipping":  newRedisStreams(t, server, "shipping", "shipping", 0),
+	}
+	waits := make(map[string]func(n int) []delivery)
+	for name, client := range clients {
+		waits[name] = consume(t, client, "orders", func(int) error { return nil })
+	}
+
+	const total = 5
+	for i := 0; i < total; i++ {
+		if err := clients["shipping"].Publish(t.Context(), &Message{Topic: "orders", Payload: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shipping := waits["shipping"](total)
+	time.Sleep(100 * time.Millisecond)
+	billing := payloads(waits["billing-1"](0), waits["billing-2"](0))
+
+	if len(shipping) != total || len(payloads(shipping)) != total {
+		t.Errorf("shipping got %d deliveries of %d messages, want each of the %d once", len(shipping), len(payloads(shipping)), total)
+	}
+	for i := 0; i < total; i++ {
+		if n := billing[fmt.Sprint(i)]; n != 1 {
+			t.Errorf("billing group got message %d %d times, want once across its consumers", i, n)
+		}
+	}
+	for _, client := range clients {
+		assertNoPending(t, client, "orders")
+	}
+}
+
+func TestRedisStreamsDeliversMessageFields(t *testing.T) {
+	server := miniredis.RunT(t)
+	r := newRedisStreams(t, server, "group", "consumer", 0)
+	wait := consume(t, r, "orders", func(int) error { return nil })
+
+	sent := &Message{
+		Topic:   "orders",
+		Key:     "customer-1",
+		Payload: []byte(`{"id":"o1"}`),
+		Headers: map[string]string{"trace-id": "t1"},
+	}
+	if err := r.Publish(t.Context(), sent); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := wait(1)[0].msg
+	if msg.Topic != "orders" || msg.Key != "customer-1" || string(msg.Payload) != `{"id":"o1"}` {
+		t.Errorf("delivered %s with key %q on %s", msg.Payload, msg.Key, msg.Topic)
+	}
+	if msg.Headers["trace-id"] != "t1" || msg.Headers[HeaderMessageID] != sent.Headers[HeaderMessageID] {
+		t.Errorf("delivered headers %v, want the published ones %v", msg.Headers, sent.Headers)
+	}
+	if time.Since(msg.Timestamp) > time.Minute {
+		t.Errorf("timestamp %v, want the time the entry was added", msg.Timestamp)
+	}
+}
+
+func TestRedisStreamsRedeliversFailedMessages(t *testing.T) {
+	handlerErr := errors.New("handler failed")
+
+	tests := []struct {
+		name          string
+		maxDeliveries int64
+		failures      int // deliveries that fail before the handler succeeds
+		err           error
+		wantTotal     int
+	}{
+		{"claimed again until the handler succeeds", 0, 2, handlerErr, 3},
+		{"permanent failures are dropped", 0, 2, Permanent(handlerErr), 1},
+		{"max deliveries reached", 2, 5, handlerErr, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			r := newRedisStreams(t, server, "group", "consumer", tt.maxDeliveries)
+			wait := consume(t, r, "orders", func(n int) error {
+				if n <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if err := r.Publish(t.Context(), &Message{Topic: "orders", Payload: []byte(`{"id":"o1"}`)}); err != nil {
+				t.Fatal(err)
+			}
+
+			deliveries := wait(tt.wantTotal)
+			for i := 1; i < len(deliveries); i++ {
+				if gap := deliveries[i].at.Sub(deliveries[i-1].at); gap < 50*time.Millisecond {
+					t.Errorf("delivery %d came %v after the one before, want it claimed after the 50ms idle time", i+1, gap)
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
+			if got := len(wait(0)); got != tt.wantTotal {
+				t.Errorf("got %d deliveries, want %d", got, tt.wantTotal)
+			}
+			assertNoPending(t, r, "orders")
+		})
+	}
+}
+
+func TestRedisStreamsReclaimsEntriesOfCrashedConsumers(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	// Another consumer of the group reads an entry and crashes before
+	// acknowledging it
+	crashed := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer crashed.Close()
+	if err := crashed.XGroupCreateMkStream(t.Context(), "stream:orders", "group", "$").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.XAdd(t.Context(), &redis.XAddArgs{
+		Stream: "stream:orders",
+		Values: map[string]interface{}{redisFieldPayload: `{"id":"o1"}`},
+	}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.XReadGroup(t.Context(), &redis.XReadGroupArgs{
+		Group:    "group",
+		Consumer: "crashed",
+		Streams:  []string{"stream:orders", ">"},
+		Count:    1,
+	}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The entry is not new to the group, so only claiming delivers it
+	survivor := newRedisStreams(t, server, "group", "survivor", 0)
+	wait := consume(t, survivor, "orders", func(int) error { return nil })
+
+	msg := wait(1)[0].msg
+	if string(msg.Payload) != `{"id":"o1"}` {
+		t.Errorf("reclaimed %s", msg.Payload)
+	}
+	assertNoPending(t, survivor, "orders")
+}
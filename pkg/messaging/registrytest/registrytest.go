@@ -0,0 +1,122 @@
+// Package registrytest provides an in-memory stand-in for a Confluent
+// compatible schema registry. It implements the endpoints used by
+// messaging.SchemaRegistryClient and is meant for tests and local development:
+//
+//	server := httptest.NewServer(registrytest.NewHandler())
+//	defer server.Close()
+//	client := messaging.NewSchemaRegistryClient(server.URL, "", "", 0)
+package registrytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// schema is a registered schema
+type schema struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// Registry stores schemas in memory. IDs are shared across subjects, and
+// registering an identical schema again returns its existing ID.
+type Registry struct {
+	mu       sync.Mutex
+	schemas  []schema
+	subjects map[string][]int
+}
+
+// NewHandler returns an http.Handler serving an empty registry
+func NewHandler() *Registry {
+	return &Registry{subjects: make(map[string][]int)}
+}
+
+// ServeHTTP implements http.Handler
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+
+	path := strings.Trim(req.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case req.Method == http.MethodPost && len(parts) == 3 && parts[0] == "subjects" && parts[2] == "versions":
+		r.register(w, req, parts[1])
+	case req.Method == http.MethodGet && len(parts) == 3 && parts[0] == "schemas" && parts[1] == "ids":
+		r.get(w, parts[2])
+	case req.Method == http.MethodGet && len(parts) == 1 && parts[0] == "subjects":
+		r.listSubjects(w)
+	default:
+		writeError(w, http.StatusNotFound, 404, "HTTP 404 Not Found")
+	}
+}
+
+func (r *Registry) register(w http.ResponseWriter, req *http.Request, subject string) {
+	var s schema
+	if err := json.NewDecoder(req.Body).Decode(&s); err != nil || s.Schema == "" {
+		writeError(w, http.StatusUnprocessableEntity, 42201, "Invalid schema")
+		return
+	}
+	if s.SchemaType == "AVRO" {
+		s.SchemaType = ""
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := 0
+	for i, existing := range r.schemas {
+		if existing == s {
+			id = i + 1
+			break
+		}
+	}
+	if id == 0 {
+		r.schemas = append(r.schemas, s)
+		id = len(r.schemas)
+	}
+
+	registered := false
+	for _, existing := range r.subjects[subject] {
+		registered = registered || existing == id
+	}
+	if !registered {
+		r.subjects[subject] = append(r.subjects[subject], id)
+	}
+
+	json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+func (r *Registry) get(w http.ResponseWriter, rawID string) {
+	id, err := strconv.Atoi(rawID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil || id < 1 || id > len(r.schemas) {
+		writeError(w, http.StatusNotFound, 40403, "Schema not found")
+		return
+	}
+	json.NewEncoder(w).Encode(r.schemas[id-1])
+}
+
+func (r *Registry) listSubjects(w http.ResponseWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subjects := make([]string, 0, len(r.subjects))
+	for subject := range r.subjects {
+		subjects = append(subjects, subject)
+	}
+	json.NewEncoder(w).Encode(subjects)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error_code": code,
+		"message":    message,
+	})
+}
@@ -0,0 +1,191 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers used by request/reply. Requests carry a correlation ID and the
+// topic to reply to; replies echo the correlation ID and carry the
+// responder's error, if any.
+const (
+	HeaderCorrelationID = "x-correlation-id"
+	HeaderReplyTo       = "x-reply-to"
+	HeaderReplyError    = "x-reply-error"
+)
+
+// DefaultRequestTimeout bounds requests whose context has no deadline
+const DefaultRequestTimeout = 10 * time.Second
+
+// Request/reply errors
+var (
+	ErrRequestNotSupported = errors.New("backend does not support request/reply")
+	ErrRequestTimeout      = errors.New("request timed out")
+	ErrRemote              = errors.New("responder returned an error")
+	ErrNoReplyTo           = errors.New("request has no reply-to topic")
+)
+
+// Responder handles a request and returns the reply. A returned error is
+// sent back to the requester, which receives it wrapped in ErrRemote.
+type Responder func(ctx context.Context, req *Message) (*Message, error)
+
+// Requester is implemented by backends that support request/reply
+type Requester interface {
+	// Request publishes msg to topic and waits for the reply
+	Request(ctx context.Context, topic string, msg *Message) (*Message, error)
+
+	// Respond serves requests published to topic until ctx is done.
+	// Responders on several replicas share the requests between them.
+	Respond(ctx context.Context, topic string, responder Responder) error
+}
+
+// Request sends a request through client and waits for the reply. Without a
+// deadline on ctx, the request times out after DefaultRequestTimeout.
+func Request(ctx context.Context, client Messaging, topic string, msg *Message) (*Message, error) {
+	requester, ok := client.(Requester)
+	if !ok {
+		return nil, ErrRequestNotSupported
+	}
+	return requester.Request(ctx, topic, msg)
+}
+
+// Respond registers a responder for topic on client
+func Respond(ctx context.Context, client Messaging, topic string, responder Responder) error {
+	requester, ok := client.(Requester)
+	if !ok {
+		return ErrRequestNotSupported
+	}
+	return requester.Respond(ctx, topic, responder)
+}
+
+// withRequestTimeout applies DefaultRequestTimeout if ctx has no deadline
+func withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultRequestTimeout)
+}
+
+// requestError converts a context error into a request error
+func requestError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrRequestTimeout
+	}
+	return ctx.Err()
+}
+
+// replyError returns the responder's error carried by a reply, if any
+func replyError(reply *Message) error {
+	if msg := reply.Headers[HeaderReplyError]; msg != "" {
+		return fmt.Errorf("%w: %s", ErrRemote, msg)
+	}
+	return nil
+}
+
+// replyHandler wraps a responder in a handler that publishes its reply to
+// the request's reply-to topic
+func replyHandler(publish func(context.Context, *Message) error, responder Responder) Handler {
+	return func(ctx context.Context, req *Message) error {
+		replyTo := req.Headers[HeaderReplyTo]
+		if replyTo == "" {
+			return Permanent(ErrNoReplyTo)
+		}
+
+		reply, err := responder(ctx, req)
+		if err != nil || reply == nil {
+			reply = &Message{}
+		}
+		reply = copyMessage(reply)
+		reply.Topic = replyTo
+		reply.Headers[HeaderCorrelationID] = req.Headers[HeaderCorrelationID]
+		if err != nil {
+			reply.Headers[HeaderReplyError] = err.Error()
+		}
+
+		return publish(ctx, reply)
+	}
+}
+
+// replyInbox matches replies arriving on a reply topic to waiting requests
+// by correlation ID, for backends without native request/reply. The inbox
+// subscribes to its topic on the first request.
+type replyInbox struct {
+	topic     string
+	subscribe func(topic string, handler Handler) error
+
+	once     sync.Once
+	startErr error
+
+	mu      sync.Mutex
+	pending map[string]chan *Message
+}
+
+// newReplyInbox creates an inbox for topic. subscribe is called once to
+// start consuming replies for the lifetime of the client.
+func newReplyInbox(topic string, subscribe func(topic string, handler Handler) error) *replyInbox {
+	return &replyInbox{
+		topic:     topic,
+		subscribe: subscribe,
+		pending:   make(map[string]chan *Message),
+	}
+}
+
+// request publishes a request and waits for its reply
+func (i *replyInbox) request(ctx context.Context, publish func(context.Context, *Message) error, topic string, msg *Message) (*Message, error) {
+	i.once.Do(func() {
+		i.startErr = i.subscribe(i.topic, i.deliver)
+	})
+	if i.startErr != nil {
+		return nil, fmt.Errorf("failed to subscribe to replies: %w", i.startErr)
+	}
+
+	ctx, cancel := withRequestTimeout(ctx)
+	defer cancel()
+
+	id := uuid.New().String()
+	replies := make(chan *Message, 1)
+	i.mu.Lock()
+	i.pending[id] = replies
+	i.mu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		delete(i.pending, id)
+		i.mu.Unlock()
+	}()
+
+	req := copyMessage(msg)
+	req.Topic = topic
+	req.Headers[HeaderCorrelationID] = id
+	req.Headers[HeaderReplyTo] = i.topic
+	if err := publish(ctx, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case reply := <-replies:
+		return reply, replyError(reply)
+	case <-ctx.Done():
+		return nil, requestError(ctx)
+	}
+}
+
+// deliver hands a reply to the waiting request. Replies nobody waits for,
+// such as late replies or replies to other replicas, are dropped.
+func (i *replyInbox) deliver(_ context.Context, reply *Message) error {
+	i.mu.Lock()
+	replies, ok := i.pending[reply.Headers[HeaderCorrelationID]]
+	i.mu.Unlock()
+
+	if ok {
+		select {
+		case replies <- reply:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go-microservice/internal/config"
+	"go.uber.org/zap"
+)
+
+// Headers added to messages that are retried or dead-lettered
+const (
+	HeaderOriginalTopic  = "x-original-topic"
+	HeaderAttempt        = "x-attempt"
+	HeaderNotBefore      = "x-retry-not-before"
+	HeaderError          = "x-error"
+	HeaderFirstFailureAt = "x-first-failure-at"
+	HeaderLastFailureAt  = "x-last-failure-at"
+	HeaderDeadLetterID   = "x-dead-letter-id"
+	HeaderDeadLetteredAt = "x-dead-lettered-at"
+
+	// HeaderOriginalMessageID carries the ID of the message first published,
+	// since retried, dead-lettered and replayed messages get their own
+	HeaderOriginalMessageID = "x-original-message-id"
+)
+
+// retryHeaders lists the headers managed by the retrier
+var retryHeaders = []string{
+	HeaderOriginalTopic,
+	HeaderAttempt,
+	HeaderNotBefore,
+	HeaderError,
+	HeaderFirstFailureAt,
+	HeaderLastFailureAt,
+	HeaderDeadLetterID,
+	HeaderDeadLetteredAt,
+}
+
+// Default retry settings used when the config leaves them empty
+const (
+	defaultMaxAttempts      = 3
+	defaultInitialBackoff   = 100 * time.Millisecond
+	defaultMaxBackoff       = 2 * time.Second
+	defaultRetrySuffix      = ".retry"
+	defaultDeadLetterSuffix = ".dlq"
+)
+
+// Retrier adds retries and dead-lettering to message handlers. A failing
+// message is first retried in process with exponential backoff. If it still
+// fails it is published to the next retry topic, where it is consumed again
+// once its delay has passed. After the last retry topic, or right away for
+// permanent errors, it is published to the dead-letter topic together with
+// the error and attempt history.
+type Retrier struct {
+	client Messaging
+	policy config.RetryConfig
+}
+
+// NewRetrier creates a retrier that publishes retries through client
+func NewRetrier(client Messaging, policy config.RetryConfig) *Retrier {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultMaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultMaxBackoff
+	}
+	if policy.RetrySuffix == "" {
+		policy.RetrySuffix = defaultRetrySuffix
+	}
+	if policy.DeadLetterSuffix == "" {
+		policy.DeadLetterSuffix = defaultDeadLetterSuffix
+	}
+
+	return &Retrier{
+		client: client,
+		policy: policy,
+	}
+}
+
+// RetryTopics returns the delayed retry topics of a topic, in order
+func (r *Retrier) RetryTopics(topic string) []string {
+	topics := make([]string, len(r.policy.Delays))
+	for i := range r.policy.Delays {
+		topics[i] = fmt.Sprintf("%s%s.%d", topic, r.policy.RetrySuffix, i+1)
+	}
+	return topics
+}
+
+// DeadLetterTopic returns the dead-letter topic of a topic
+func (r *Retrier) DeadLetterTopic(topic string) string {
+	return topic + r.policy.DeadLetterSuffix
+}
+
+// Topics returns a topic together with all of its retry topics. Consumers
+// have to subscribe to all of them for delayed retries to be processed.
+func (r *Retrier) Topics(topic string) []string {
+	return append([]string{topic}, r.RetryTopics(topic)...)
+}
+
+// Wrap returns a handler with retry and dead-letter handling. Once a failed
+// message has been handed to a retry or dead-letter topic the wrapped handler
+// returns nil so the consumer can move on. It only returns an error if the
+// message could not be forwarded or the context was cancelled.
+func (r *Retrier) Wrap(handler Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		stage := r.stageOf(msg)
+		log := messageLogger(ctx, msg)
+
+		// Messages on retry topics wait until their delay has passed
+		if stage > 0 {
+			if err := waitUntil(ctx, notBefore(msg)); err != nil {
+				return err
+			}
+		}
+
+		err := r.attempt(ctx, log, handler, msg)
+		if err == nil {
+			return nil
+		}
+
+		// Leave the message for redelivery when shutting down. Messages
+		// another consumer is still processing are never forwarded.
+		if ctx.Err() != nil || errors.Is(err, ErrDuplicateInProgress) {
+			return err
+		}
+
+		return r.forward(ctx, log, msg, stage, err)
+	}
+}
+
+// attempt runs the handler with in-process retries and exponential backoff.
+// While another consumer is processing the same message it keeps waiting
+// without using up attempts, until the message is skipped as processed or
+// ctx is done.
+func (r *Retrier) attempt(ctx context.Context, log *zap.Logger, handler Handler, msg *Message) error {
+	backoff := r.policy.InitialBackoff
+
+	var err error
+	for attempts := 0; attempts < r.policy.MaxAttempts; {
+		if err != nil {
+			if waitErr := sleep(ctx, backoff); waitErr != nil {
+				return err
+			}
+			backoff = min(backoff*2, r.policy.MaxBackoff)
+		}
+
+		if err = handler(ctx, msg); err == nil {
+			return nil
+		}
+		if IsPermanent(err) {
+			return err
+		}
+		if errors.Is(err, ErrDuplicateInProgress) {
+			continue
+		}
+		attempts++
+
+		log.Warn("Message handler failed",
+			zap.Int("attempt", attempts),
+			zap.Error(err))
+	}
+	return err
+}
+
+// forward publishes a failed message to the next retry topic or the
+// dead-letter topic. The forwarded message gets its own ID, derived from the
+// failed message's and the target topic, so the forward is a new message to
+// brokers and deduplication but a repeated forward of the same message is not.
+func (r *Retrier) forward(ctx context.Context, log *zap.Logger, msg *Message, stage int, handlerErr error) error {
+	original := OriginalTopic(msg)
+	now := time.Now().UTC()
+
+	headers := make(map[string]string, len(msg.Headers)+6)
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+
+	attempts, _ := strconv.Atoi(headers[HeaderAttempt])
+	headers[HeaderOriginalTopic] = original
+	headers[HeaderAttempt] = strconv.Itoa(attempts + r.policy.MaxAttempts)
+	headers[HeaderError] = handlerErr.Error()
+	headers[HeaderLastFailureAt] = now.Format(time.RFC3339Nano)
+	if headers[HeaderFirstFailureAt] == "" {
+		headers[HeaderFirstFailureAt] = headers[HeaderLastFailureAt]
+	}
+	keepOriginalMessageID(headers)
+
+	forwarded := &Message{
+		Key:     msg.Key,
+		Payload: msg.Payload,
+		Headers: headers,
+	}
+
+	if stage < len(r.policy.Delays) && !IsPermanent(handlerErr) {
+		forwarded.Topic = r.RetryTopics(original)[stage]
+		headers[HeaderNotBefore] = now.Add(r.policy.Delays[stage]).Format(time.RFC3339Nano)
+	} else {
+		forwarded.Topic = r.DeadLetterTopic(original)
+		delete(headers, HeaderNotBefore)
+		headers[HeaderDeadLetterID] = uuid.New().String()
+		headers[HeaderDeadLetteredAt] = headers[HeaderLastFailureAt]
+	}
+	headers[HeaderMessageID] = derivedMessageID(msg.Headers[HeaderMessageID], forwarded.Topic)
+
+	if err := r.client.Publish(ctx, forwarded); err != nil {
+		return fmt.Errorf("failed to forward message to %s: %w", forwarded.Topic, errors.Join(err, handlerErr))
+	}
+
+	log.Warn("Message forwarded after failed processing",
+		zap.String("forwarded_to", forwarded.Topic),
+		zap.String("forwarded_message_id", headers[HeaderMessageID]),
+		zap.String("attempts", headers[HeaderAttempt]),
+		zap.Error(handlerErr))
+
+	return nil
+}
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying. The retrier sends messages
+// failing with a permanent error straight to the dead-letter topic.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// stageOf returns 0 for messages on an original topic and n for messages on
+// the n-th retry topic
+func (r *Retrier) stageOf(msg *Message) int {
+	original := msg.Headers[HeaderOriginalTopic]
+	if original == "" {
+		return 0
+	}
+
+	prefix := original + r.policy.RetrySuffix + "."
+	if !strings.HasPrefix(msg.Topic, prefix) {
+		return 0
+	}
+	stage, err := strconv.Atoi(strings.TrimPrefix(msg.Topic, prefix))
+	if err != nil {
+		return 0
+	}
+	return stage
+}
+
+// OriginalTopic returns the topic a message was first published to
+func OriginalTopic(msg *Message) string {
+	if original := msg.Headers[HeaderOriginalTopic]; original != "" {
+		return original
+	}
+	return msg.Topic
+}
+
+// ReplayMessage returns a copy of a retried or dead-lettered message addressed
+// to its original topic, with all retry headers removed so that it starts over
+// with a fresh attempt count. The copy gets its own ID so deduplication does
+// not skip it as processed; replays of the same dead letter share one ID.
+func ReplayMessage(msg *Message) *Message {
+	headers := make(map[string]string, len(msg.Headers))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	keepOriginalMessageID(headers)
+	if deadLetterID := headers[HeaderDeadLetterID]; deadLetterID != "" {
+		headers[HeaderMessageID] = derivedMessageID(deadLetterID, "replay")
+	} else {
+		headers[HeaderMessageID] = uuid.New().String()
+	}
+	for _, k := range retryHeaders {
+		delete(headers, k)
+	}
+
+	return &Message{
+		Topic:   OriginalTopic(msg),
+		Key:     msg.Key,
+		Payload: msg.Payload,
+		Headers: headers,
+	}
+}
+
+// keepOriginalMessageID records the message ID as the original one unless an
+// earlier forward or replay already did
+func keepOriginalMessageID(headers map[string]string) {
+	if headers[HeaderOriginalMessageID] == "" {
+		headers[HeaderOriginalMessageID] = headers[HeaderMessageID]
+	}
+}
+
+// derivedMessageID returns a stable message ID for the copy of message id
+// published for purpose, such as a target topic
+func derivedMessageID(id, purpose string) string {
+	if id == "" {
+		return uuid.New().String()
+	}
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(id+"\x00"+purpose)).String()
+}
+
+// notBefore returns the earliest time a retried message may be processed
+func notBefore(msg *Message) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, msg.Headers[HeaderNotBefore])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// waitUntil blocks until t or until the context is cancelled
+func waitUntil(ctx context.Context, t time.Time) error {
+	return sleep(ctx, time.Until(t))
+}
+
+// sleep blocks for d or until the context is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
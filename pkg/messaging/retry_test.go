@@ -0,0 +1,211 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-microservice/internal/config"
+)
+
+// forwardRecorder is a Messaging client passing on every message the
+// Retrier forwards to retry and dead-letter topics
+type forwardRecorder struct {
+	Messaging
+	forwarded chan *Message
+}
+
+func newForwardRecorder() *forwardRecorder {
+	return &forwardRecorder{forwarded: make(chan *Message, 10)}
+}
+
+// Publish implements the Messaging interface
+func (r *forwardRecorder) Publish(ctx context.Context, msg *Message) error {
+	r.forwarded <- msg
+	return nil
+}
+
+func TestRetrierWaitsForDuplicateInProgress(t *testing.T) {
+	recorder := newForwardRecorder()
+	retrier := NewRetrier(recorder, config.RetryConfig{
+		MaxAttempts:    1,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Delays:         []time.Duration{time.Minute},
+	})
+	forwarded := recorder.forwarded
+
+	store := NewMemoryDedupStore()
+	dedup := NewDeduplicator(store, "group", config.DedupConfig{})
+
+	// Another consumer is processing the message
+	if status, _, err := store.Claim(t.Context(), "group:m1", time.Minute); err != nil || status != DedupClaimed {
+		t.Fatalf("Claim() = %v, %v", status, err)
+	}
+
+	var calls atomic.Int32
+	handler := retrier.Wrap(dedup.Wrap(func(ctx context.Context, msg *Message) error {
+		calls.Add(1)
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(t.Context(), &Message{Topic: "orders", Headers: map[string]string{HeaderMessageID: "m1"}})
+	}()
+
+	// Several lock checks take longer than the single attempt allowed
+	time.Sleep(50 * time.Millisecond)
+	if err := store.Complete(t.Context(), "group:m1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("handler error = %v, want nil once the other consumer is done", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not return after the other consumer finished")
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("handler ran %d times, want the processed message to be skipped", n)
+	}
+
+	select {
+	case msg := <-forwarded:
+		t.Errorf("message forwarded to %s, want it never forwarded", msg.Topic)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRetrierNeverForwardsDuplicateInProgress(t *testing.T) {
+	recorder := newForwardRecorder()
+	retrier := NewRetrier(recorder, config.RetryConfig{
+		MaxAttempts:    1,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+	forwarded := recorder.forwarded
+
+	// The lock holder does not finish before the consumer shuts down
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+
+	var calls atomic.Int32
+	err := retrier.Wrap(func(ctx context.Context, msg *Message) error {
+		calls.Add(1)
+		return ErrDuplicateInProgress
+	})(ctx, &Message{Topic: "orders"})
+
+	if !errors.Is(err, ErrDuplicateInProgress) {
+		t.Errorf("handler error = %v, want ErrDuplicateInProgress so the message is redelivered", err)
+	}
+	if n := calls.Load(); n < 2 {
+		t.Errorf("handler ran %d times, want it retried beyond MaxAttempts", n)
+	}
+
+	select {
+	case msg := <-forwarded:
+		t.Errorf("message forwarded to %s, want it never forwarded", msg.Topic)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRetrierForwardsWithOwnMessageID(t *testing.T) {
+	recorder := newForwardRecorder()
+	retrier := NewRetrier(recorder, config.RetryConfig{
+		MaxAttempts:    1,
+		InitialBackoff: time.Millisecond,
+		Delays:         []time.Duration{time.Minute},
+	})
+	forwarded := recorder.forwarded
+
+	handlerErr := errors.New("handler failed")
+	handler := retrier.Wrap(func(ctx context.Context, msg *Message) error { return handlerErr })
+	receive := func() *Message {
+		t.Helper()
+		select {
+		case msg := <-forwarded:
+			return msg
+		case <-time.After(time.Second):
+			t.Fatal("message not forwarded")
+			return nil
+		}
+	}
+
+	// The same message failing twice, as after a redelivery, is forwarded
+	// under the same ID both times
+	for range 2 {
+		if err := handler(t.Context(), &Message{Topic: "orders", Headers: map[string]string{HeaderMessageID: "m1"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, second := receive(), receive()
+	if id := first.Headers[HeaderMessageID]; id == "" || id == "m1" || id != second.Headers[HeaderMessageID] {
+		t.Errorf("retries have IDs %q and %q, want the same new ID", id, second.Headers[HeaderMessageID])
+	}
+	if first.Headers[HeaderOriginalMessageID] != "m1" {
+		t.Errorf("retry has original ID %q, want m1", first.Headers[HeaderOriginalMessageID])
+	}
+
+	// The retry failing on the last stage is dead-lettered under yet another
+	// ID, still pointing at the first message
+	first.Headers[HeaderNotBefore] = ""
+	if err := handler(t.Context(), first); err != nil {
+		t.Fatal(err)
+	}
+	deadLetter := receive()
+	if deadLetter.Topic != "orders.dlq" {
+		t.Fatalf("forwarded to %s, want orders.dlq", deadLetter.Topic)
+	}
+	if id := deadLetter.Headers[HeaderMessageID]; id == first.Headers[HeaderMessageID] || id == "m1" {
+		t.Errorf("dead letter reuses ID %q", id)
+	}
+	if deadLetter.Headers[HeaderOriginalMessageID] != "m1" {
+		t.Errorf("dead letter has original ID %q, want m1", deadLetter.Headers[HeaderOriginalMessageID])
+	}
+}
+
+func TestReplayMessageIsNotSkippedAsProcessed(t *testing.T) {
+	dedup := NewDeduplicator(NewMemoryDedupStore(), "group", config.DedupConfig{})
+	var calls atomic.Int32
+	handler := dedup.Wrap(func(ctx context.Context, msg *Message) error {
+		calls.Add(1)
+		return nil
+	})
+
+	deadLetter := &Message{
+		Topic: "orders.dlq",
+		Headers: map[string]string{
+			HeaderMessageID:     "m1",
+			HeaderOriginalTopic: "orders",
+			HeaderDeadLetterID:  "d1",
+			HeaderAttempt:       "3",
+		},
+	}
+	// The ID the dead letter carries was processed before
+	if err := handler(t.Context(), &Message{Topic: "orders", Headers: map[string]string{HeaderMessageID: "m1"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	replay := ReplayMessage(deadLetter)
+	if err := handler(t.Context(), replay); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want the replay handled", n)
+	}
+
+	if replay.Topic != "orders" || replay.Headers[HeaderAttempt] != "" || replay.Headers[HeaderDeadLetterID] != "" {
+		t.Errorf("replay on %s with headers %v, want the original topic without retry headers", replay.Topic, replay.Headers)
+	}
+	if replay.Headers[HeaderOriginalMessageID] != "m1" {
+		t.Errorf("replay has original ID %q, want m1", replay.Headers[HeaderOriginalMessageID])
+	}
+	if again := ReplayMessage(deadLetter); again.Headers[HeaderMessageID] != replay.Headers[HeaderMessageID] {
+		t.Errorf("replays of one dead letter have IDs %q and %q, want one ID", replay.Headers[HeaderMessageID], again.Headers[HeaderMessageID])
+	}
+}
@@ -0,0 +1,125 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Router errors
+var (
+	ErrNoRoute       = errors.New("no handler registered for topic")
+	ErrDecodePayload = errors.New("failed to decode message payload")
+)
+
+// Middleware wraps a handler with cross-cutting behaviour
+type Middleware func(Handler) Handler
+
+// route is a handler registered for a logical topic
+type route struct {
+	name    string
+	handler Handler
+}
+
+// Router dispatches messages to handlers registered by logical topic name.
+// Logical names are the keys of the configured topic map; the values are the
+// topics actually consumed.
+type Router struct {
+	topics     map[string]string
+	routes     map[string]*route
+	middleware []Middleware
+	errs       []error
+}
+
+// NewRouter creates a router for the given logical to physical topic mapping
+func NewRouter(topics map[string]string) *Router {
+	return &Router{
+		topics: topics,
+		routes: make(map[string]*route),
+	}
+}
+
+// Use adds middleware applied to every handler, outermost first. It must be
+// called before handlers are registered.
+func (r *Router) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Handle registers a raw handler for a logical topic. Middleware passed here
+// runs inside the router-wide middleware.
+func (r *Router) Handle(name string, handler Handler, middleware ...Middleware) {
+	topic, ok := r.topics[name]
+	if !ok {
+		r.errs = append(r.errs, fmt.Errorf("handler registered for unknown topic %q", name))
+		return
+	}
+	if _, exists := r.routes[topic]; exists {
+		r.errs = append(r.errs, fmt.Errorf("duplicate handler for topic %q", name))
+		return
+	}
+
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+
+	r.routes[topic] = &route{name: name, handler: handler}
+}
+
+// Register registers a handler that receives the message payload decoded from
+// JSON into T. Payloads that cannot be decoded fail permanently.
+func Register[T any](r *Router, name string, fn func(context.Context, T) error, middleware ...Middleware) {
+	r.Handle(name, func(ctx context.Context, msg *Message) error {
+		var payload T
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return Permanent(fmt.Errorf("%w: %v", ErrDecodePayload, err))
+		}
+		return fn(ctx, payload)
+	}, middleware...)
+}
+
+// Validate reports registration errors and configured topics without a handler
+func (r *Router) Validate() error {
+	errs := append([]error(nil), r.errs...)
+	for _, name := range r.names() {
+		if _, ok := r.routes[r.topics[name]]; !ok {
+			errs = append(errs, fmt.Errorf("no handler registered for topic %q", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Topics returns the physical topics that have a handler
+func (r *Router) Topics() []string {
+	topics := make([]string, 0, len(r.routes))
+	for _, name := range r.names() {
+		if topic := r.topics[name]; r.routes[topic] != nil {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// Dispatch passes a message to the handler of the topic it was originally published to
+func (r *Router) Dispatch(ctx context.Context, msg *Message) error {
+	topic := OriginalTopic(msg)
+	route, ok := r.routes[topic]
+	if !ok {
+		return Permanent(fmt.Errorf("%w: %s", ErrNoRoute, topic))
+	}
+	return route.handler(ctx, msg)
+}
+
+// names returns the configured logical topic names in a stable order
+func (r *Router) names() []string {
+	names := make([]string, 0, len(r.topics))
+	for name := range r.topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,152 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type orderCreated struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+type fileDeleted struct {
+	Key string `json:"key"`
+}
+
+func TestRegisterDispatchesDecodedPayloadsByTopic(t *testing.T) {
+	router := NewRouter(map[string]string{"orders": "orders-v1", "files": "files-v1"})
+	orders := make(chan orderCreated, 1)
+	files := make(chan fileDeleted, 1)
+	Register(router, "orders", func(ctx context.Context, event orderCreated) error {
+		orders <- event
+		return nil
+	})
+	Register(router, "files", func(ctx context.Context, event fileDeleted) error {
+		files <- event
+		return nil
+	})
+	if err := router.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	for _, msg := range []*Message{
+		{Topic: "orders-v1", Payload: []byte(`{"id":"o1","total":42}`)},
+		{Topic: "files-v1", Payload: []byte(`{"key":"a/b.txt"}`)},
+	} {
+		if err := router.Dispatch(t.Context(), msg); err != nil {
+			t.Fatalf("Dispatch() error = %v", err)
+		}
+	}
+
+	if event := <-orders; event != (orderCreated{ID: "o1", Total: 42}) {
+		t.Errorf("orders handler got %+v", event)
+	}
+	if event := <-files; event.Key != "a/b.txt" {
+		t.Errorf("files handler got %+v", event)
+	}
+}
+
+func TestRouterDispatchesRetriesToOriginalTopic(t *testing.T) {
+	router := NewRouter(map[string]string{"orders": "orders-v1"})
+	orders := make(chan orderCreated, 1)
+	Register(router, "orders", func(ctx context.Context, event orderCreated) error {
+		orders <- event
+		return nil
+	})
+
+	err := router.Dispatch(t.Context(), &Message{
+		Topic:   "orders-v1.retry.1",
+		Payload: []byte(`{"id":"o1"}`),
+		Headers: map[string]string{HeaderOriginalTopic: "orders-v1"},
+	})
+	if err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if event := <-orders; event.ID != "o1" {
+		t.Errorf("orders handler got %+v", event)
+	}
+}
+
+func TestRouterRejectsUndeliverableMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     *Message
+		wantErr error
+	}{
+		{
+			name:    "topic without a route",
+			msg:     &Message{Topic: "unrouted", Payload: []byte(`{"id":"o1"}`)},
+			wantErr: ErrNoRoute,
+		},
+		{
+			name:    "malformed payload",
+			msg:     &Message{Topic: "orders-v1", Payload: []byte(`{"id":`)},
+			wantErr: ErrDecodePayload,
+		},
+		{
+			name:    "payload of another type",
+			msg:     &Message{Topic: "orders-v1", Payload: []byte(`{"id":"o1","total":"many"}`)},
+			wantErr: ErrDecodePayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(map[string]string{"orders": "orders-v1"})
+			Register(router, "orders", func(ctx context.Context, event orderCreated) error {
+				t.Errorf("handler called with %+v", event)
+				return nil
+			})
+
+			// Permanent errors are dropped instead of redelivered
+			err := router.Dispatch(t.Context(), tt.msg)
+			if !errors.Is(err, tt.wantErr) || !IsPermanent(err) {
+				t.Errorf("Dispatch() error = %v, want permanent %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouterValidate(t *testing.T) {
+	noop := func(ctx context.Context, event orderCreated) error { return nil }
+
+	tests := []struct {
+		name     string
+		register func(*Router)
+		wantErr  string
+	}{
+		{
+			name: "unknown topic",
+			register: func(r *Router) {
+				Register(r, "orders", noop)
+				Register(r, "payments", noop)
+			},
+			wantErr: `handler registered for unknown topic "payments"`,
+		},
+		{
+			name: "duplicate handler",
+			register: func(r *Router) {
+				Register(r, "orders", noop)
+				Register(r, "orders", noop)
+			},
+			wantErr: `duplicate handler for topic "orders"`,
+		},
+		{
+			name:     "topic without a handler",
+			register: func(r *Router) {},
+			wantErr:  `no handler registered for topic "orders"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(map[string]string{"orders": "orders-v1"})
+			tt.register(router)
+			if err := router.Validate(); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
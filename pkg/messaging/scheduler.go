@@ -0,0 +1,398 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go-microservice/internal/config"
+	"go-microservice/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErrScheduledNotFound is returned for unknown scheduled message IDs
+var ErrScheduledNotFound = errors.New("scheduled message not found")
+
+// Default scheduler settings used when the config leaves them empty
+const (
+	defaultSchedulerPollInterval = time.Second
+	defaultSchedulerBatchSize    = 100
+	defaultSchedulerLockTTL      = 10 * time.Second
+	defaultSchedulerRetryDelay   = 30 * time.Second
+)
+
+// ScheduledMessage is a message waiting to be published at DeliverAt. Its ID
+// is the message ID, which the published message keeps.
+type ScheduledMessage struct {
+	ID        string            `json:"id"`
+	Topic     string            `json:"topic"`
+	Key       string            `json:"key,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   []byte            `json:"payload"`
+	DeliverAt time.Time         `json:"deliver_at"`
+	CreatedAt time.Time         `json:"created_at"`
+	Attempts  int               `json:"attempts"`
+	LastError string            `json:"last_error,omitempty"`
+}
+
+// message returns the message to publish. Its timestamp is left empty so
+// that Publish sets the delivery time.
+func (m *ScheduledMessage) message() *Message {
+	return &Message{
+		Topic:   m.Topic,
+		Key:     m.Key,
+		Payload: m.Payload,
+		Headers: maps.Clone(m.Headers),
+	}
+}
+
+// ScheduleStore keeps scheduled messages ordered by delivery time
+type ScheduleStore interface {
+	// Add stores a scheduled message, replacing one with the same ID
+	Add(ctx context.Context, msg *ScheduledMessage) error
+
+	// Get returns a scheduled message or ErrScheduledNotFound
+	Get(ctx context.Context, id string) (*ScheduledMessage, error)
+
+	// Remove deletes a scheduled message or returns ErrScheduledNotFound
+	Remove(ctx context.Context, id string) error
+
+	// Due returns up to limit messages due at now, earliest first
+	Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledMessage, error)
+
+	// Pending returns up to limit messages, earliest first, and the number
+	// of messages stored
+	Pending(ctx context.Context, limit int) ([]*ScheduledMessage, int, error)
+}
+
+// LeaderLock elects the replica that dispatches scheduled messages
+type LeaderLock interface {
+	// Acquire takes the lock or extends it for ttl and reports whether this
+	// instance holds it
+	Acquire(ctx context.Context, ttl time.Duration) (bool, error)
+
+	// Release gives up the lock if this instance holds it
+	Release(ctx context.Context) error
+}
+
+// SchedulerStats reports the dispatcher on this instance
+type SchedulerStats struct {
+	Leader         bool       `json:"leader"`
+	Dispatched     uint64     `json:"dispatched"`
+	Failed         uint64     `json:"failed"`
+	LastDispatchAt *time.Time `json:"last_dispatch_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// Scheduler publishes messages at a later time. Scheduled messages are kept
+// in a store shared by all replicas, and the replica holding the leader lock
+// hands due messages to the messaging backend. Delivery is at least once: a
+// message is removed from the store after it was published, so a crash or a
+// lost lock in between publishes it again with the same message ID.
+type Scheduler struct {
+	config config.SchedulerConfig
+	client Messaging
+	store  ScheduleStore
+	lock   LeaderLock
+
+	mu    sync.Mutex
+	stats SchedulerStats
+}
+
+// NewScheduler creates a scheduler publishing through client
+func NewScheduler(cfg config.SchedulerConfig, client Messaging, store ScheduleStore, lock LeaderLock) *Scheduler {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultSchedulerPollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultSchedulerBatchSize
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = defaultSchedulerLockTTL
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultSchedulerRetryDelay
+	}
+
+	return &Scheduler{
+		config: cfg,
+		client: client,
+		store:  store,
+		lock:   lock,
+	}
+}
+
+// PublishAt schedules msg for publishing at the given time. Messages due
+// already are published on the next dispatch.
+func (s *Scheduler) PublishAt(ctx context.Context, msg *Message, at time.Time) (*ScheduledMessage, error) {
+	ensureMessageID(msg)
+
+	scheduled := &ScheduledMessage{
+		ID:        msg.Headers[HeaderMessageID],
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Headers:   maps.Clone(msg.Headers),
+		Payload:   msg.Payload,
+		DeliverAt: at.UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.store.Add(ctx, scheduled); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// PublishAfter schedules msg for publishing after delay
+func (s *Scheduler) PublishAfter(ctx context.Context, msg *Message, delay time.Duration) (*ScheduledMessage, error) {
+	return s.PublishAt(ctx, msg, time.Now().Add(delay))
+}
+
+// Cancel removes a scheduled message. A message being dispatched at the same
+// time may still be published.
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	return s.store.Remove(ctx, id)
+}
+
+// Get returns a scheduled message
+func (s *Scheduler) Get(ctx context.Context, id string) (*ScheduledMessage, error) {
+	return s.store.Get(ctx, id)
+}
+
+// Pending returns up to limit scheduled messages, earliest first, and the
+// number of messages waiting. Without a limit it returns one batch.
+func (s *Scheduler) Pending(ctx context.Context, limit int) ([]*ScheduledMessage, int, error) {
+	if limit <= 0 {
+		limit = s.config.BatchSize
+	}
+	return s.store.Pending(ctx, limit)
+}
+
+// Stats returns the dispatcher statistics of this instance
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Start dispatches due messages while this instance holds the leader lock,
+// until the context is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	logger.FromContext(ctx).Info("Message scheduler started",
+		zap.Duration("poll_interval", s.config.PollInterval),
+		zap.Int("batch_size", s.config.BatchSize))
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Let another replica take over without waiting for the lock to expire
+			if err := s.lock.Release(context.WithoutCancel(ctx)); err != nil {
+				logger.FromContext(ctx).Warn("Failed to release scheduler lock", zap.Error(err))
+			}
+			logger.FromContext(ctx).Info("Message scheduler stopped")
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+// tick dispatches every due message while this instance leads. The lock is
+// renewed before each batch so a long backlog does not let it expire.
+func (s *Scheduler) tick(ctx context.Context) {
+	for ctx.Err() == nil {
+		if !s.lead(ctx) {
+			return
+		}
+
+		dispatched, err := s.Dispatch(ctx)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to dispatch scheduled messages", zap.Error(err))
+			return
+		}
+		if dispatched < s.config.BatchSize {
+			return
+		}
+	}
+}
+
+// lead takes or renews the leader lock and reports whether this instance holds it
+func (s *Scheduler) lead(ctx context.Context) bool {
+	leader, err := s.lock.Acquire(ctx, s.config.LockTTL)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to acquire scheduler lock", zap.Error(err))
+		leader = false
+	}
+
+	s.mu.Lock()
+	changed := s.stats.Leader != leader
+	s.stats.Leader = leader
+	s.mu.Unlock()
+
+	if changed {
+		logger.FromContext(ctx).Info("Message scheduler leadership changed", zap.Bool("leader", leader))
+	}
+	return leader
+}
+
+// Dispatch publishes one batch of due messages and returns how many were
+// handled. Messages that fail to publish are rescheduled after RetryDelay.
+func (s *Scheduler) Dispatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.store.Due(ctx, now, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, scheduled := range due {
+		if err := s.client.Publish(ctx, scheduled.message()); err != nil {
+			s.recordFailure(ctx, scheduled, now, err)
+			continue
+		}
+
+		// The message is published again on the next run
+		if err := s.store.Remove(ctx, scheduled.ID); err != nil && !errors.Is(err, ErrScheduledNotFound) {
+			return 0, err
+		}
+		s.recordDispatch(now)
+	}
+	return len(due), nil
+}
+
+// recordDispatch updates the counters after a published message
+func (s *Scheduler) recordDispatch(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Dispatched++
+	s.stats.LastDispatchAt = &now
+}
+
+// recordFailure reschedules a message that failed to publish
+func (s *Scheduler) recordFailure(ctx context.Context, scheduled *ScheduledMessage, now time.Time, publishErr error) {
+	logger.FromContext(ctx).Error("Failed to publish scheduled message",
+		zap.String("id", scheduled.ID),
+		zap.String("topic", scheduled.Topic),
+		zap.Int("attempts", scheduled.Attempts+1),
+		zap.Error(publishErr))
+
+	s.mu.Lock()
+	s.stats.Failed++
+	s.stats.LastError = publishErr.Error()
+	s.mu.Unlock()
+
+	scheduled.Attempts++
+	scheduled.LastError = publishErr.Error()
+	scheduled.DeliverAt = now.Add(s.config.RetryDelay).UTC()
+	if err := s.store.Add(ctx, scheduled); err != nil {
+		logger.FromContext(ctx).Error("Failed to reschedule message",
+			zap.String("id", scheduled.ID),
+			zap.Error(err))
+	}
+}
+
+// MemoryScheduleStore is an in-memory ScheduleStore for single-instance
+// deployments and tests. Scheduled messages are lost on restart.
+type MemoryScheduleStore struct {
+	mu       sync.Mutex
+	messages map[string]*ScheduledMessage
+}
+
+// NewMemoryScheduleStore creates an empty in-memory schedule store
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{
+		messages: make(map[string]*ScheduledMessage),
+	}
+}
+
+// Add implements the ScheduleStore interface
+func (s *MemoryScheduleStore) Add(ctx context.Context, msg *ScheduledMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *msg
+	s.messages[msg.ID] = &stored
+	return nil
+}
+
+// Get implements the ScheduleStore interface
+func (s *MemoryScheduleStore) Get(ctx context.Context, id string) (*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[id]
+	if !ok {
+		return nil, ErrScheduledNotFound
+	}
+	stored := *msg
+	return &stored, nil
+}
+
+// Remove implements the ScheduleStore interface
+func (s *MemoryScheduleStore) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.messages[id]; !ok {
+		return ErrScheduledNotFound
+	}
+	delete(s.messages, id)
+	return nil
+}
+
+// Due implements the ScheduleStore interface
+func (s *MemoryScheduleStore) Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*ScheduledMessage
+	for _, msg := range s.sorted() {
+		if msg.DeliverAt.After(now) || len(due) == limit {
+			break
+		}
+		due = append(due, msg)
+	}
+	return due, nil
+}
+
+// Pending implements the ScheduleStore interface
+func (s *MemoryScheduleStore) Pending(ctx context.Context, limit int) ([]*ScheduledMessage, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sorted := s.sorted()
+	return sorted[:min(limit, len(sorted))], len(sorted), nil
+}
+
+// sorted returns copies of all messages ordered by delivery time. Callers
+// hold s.mu.
+func (s *MemoryScheduleStore) sorted() []*ScheduledMessage {
+	sorted := make([]*ScheduledMessage, 0, len(s.messages))
+	for _, msg := range s.messages {
+		stored := *msg
+		sorted = append(sorted, &stored)
+	}
+	slices.SortFunc(sorted, func(a, b *ScheduledMessage) int {
+		return a.DeliverAt.Compare(b.DeliverAt)
+	})
+	return sorted
+}
+
+// LocalLock is a LeaderLock that is always held, for deployments with a
+// single replica
+type LocalLock struct{}
+
+// Acquire implements the LeaderLock interface
+func (LocalLock) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+// Release implements the LeaderLock interface
+func (LocalLock) Release(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,212 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	database "go-microservice/pkg/cache"
+)
+
+// scheduleAddScript stores a scheduled message and indexes it by delivery time.
+// KEYS: index, messages. ARGV: ID, delivery time in milliseconds, message JSON.
+const scheduleAddScript = `
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[3])
+return 1
+`
+
+// scheduleRemoveScript deletes a scheduled message and returns 1 if it existed.
+// KEYS: index, messages. ARGV: ID.
+const scheduleRemoveScript = `
+redis.call('HDEL', KEYS[2], ARGV[1])
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`
+
+// scheduleGetScript returns the JSON of a scheduled message.
+// KEYS: messages. ARGV: ID.
+const scheduleGetScript = `
+return redis.call('HGET', KEYS[1], ARGV[1])
+`
+
+// scheduleDueScript returns the JSON of messages due at a time, earliest first.
+// KEYS: index, messages. ARGV: time in milliseconds, limit.
+const scheduleDueScript = `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+if #ids == 0 then
+	return {}
+end
+return redis.call('HMGET', KEYS[2], unpack(ids))
+`
+
+// schedulePendingScript returns the number of scheduled messages and the JSON
+// of the earliest ones. KEYS: index, messages. ARGV: limit.
+const schedulePendingScript = `
+local count = redis.call('ZCARD', KEYS[1])
+local ids = redis.call('ZRANGE', KEYS[1], 0, ARGV[1] - 1)
+if #ids == 0 then
+	return {count, {}}
+end
+return {count, redis.call('HMGET', KEYS[2], unpack(ids))}
+`
+
+// leaderAcquireScript takes the lock or extends it if held with the token.
+// KEYS: lock key. ARGV: token, TTL in milliseconds. Returns 1 when held.
+const leaderAcquireScript = `
+local current = redis.call('GET', KEYS[1])
+if current == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if current then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`
+
+// RedisScheduleStore keeps scheduled messages in Redis so they are shared by
+// all replicas and survive restarts. A sorted set indexes the message IDs by
+// delivery time; the messages are stored as JSON in a hash.
+type RedisScheduleStore struct {
+	client   *database.RedisClient
+	index    string
+	messages string
+}
+
+// NewRedisScheduleStore creates a Redis-backed schedule store
+func NewRedisScheduleStore(client *database.RedisClient) *RedisScheduleStore {
+	return &RedisScheduleStore{
+		client:   client,
+		index:    "messaging:scheduled",
+		messages: "messaging:scheduled:messages",
+	}
+}
+
+// Add implements the ScheduleStore interface
+func (s *RedisScheduleStore) Add(ctx context.Context, msg *ScheduledMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scheduled message %s: %w", msg.ID, err)
+	}
+
+	_, err = s.client.Eval(ctx, scheduleAddScript, []string{s.index, s.messages}, msg.ID, msg.DeliverAt.UnixMilli(), data)
+	if err != nil {
+		return fmt.Errorf("failed to schedule message %s: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Get implements the ScheduleStore interface
+func (s *RedisScheduleStore) Get(ctx context.Context, id string) (*ScheduledMessage, error) {
+	result, err := s.client.Eval(ctx, scheduleGetScript, []string{s.messages}, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled message %s: %w", id, err)
+	}
+	if result == nil {
+		return nil, ErrScheduledNotFound
+	}
+
+	msgs, err := decodeScheduled([]interface{}{result})
+	if err != nil {
+		return nil, err
+	}
+	return msgs[0], nil
+}
+
+// Remove implements the ScheduleStore interface
+func (s *RedisScheduleStore) Remove(ctx context.Context, id string) error {
+	result, err := s.client.Eval(ctx, scheduleRemoveScript, []string{s.index, s.messages}, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove scheduled message %s: %w", id, err)
+	}
+	if removed, _ := result.(int64); removed == 0 {
+		return ErrScheduledNotFound
+	}
+	return nil
+}
+
+// Due implements the ScheduleStore interface
+func (s *RedisScheduleStore) Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledMessage, error) {
+	result, err := s.client.Eval(ctx, scheduleDueScript, []string{s.index, s.messages}, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read due messages: %w", err)
+	}
+
+	values, _ := result.([]interface{})
+	return decodeScheduled(values)
+}
+
+// Pending implements the ScheduleStore interface
+func (s *RedisScheduleStore) Pending(ctx context.Context, limit int) ([]*ScheduledMessage, int, error) {
+	result, err := s.client.Eval(ctx, schedulePendingScript, []string{s.index, s.messages}, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read scheduled messages: %w", err)
+	}
+
+	parts, ok := result.([]interface{})
+	if !ok || len(parts) != 2 {
+		return nil, 0, fmt.Errorf("unexpected pending result %v", result)
+	}
+	count, _ := parts[0].(int64)
+	values, _ := parts[1].([]interface{})
+
+	msgs, err := decodeScheduled(values)
+	if err != nil {
+		return nil, 0, err
+	}
+	return msgs, int(count), nil
+}
+
+// decodeScheduled parses scheduled messages stored as JSON. Entries removed
+// between reading the index and the hash are nil and skipped.
+func decodeScheduled(values []interface{}) ([]*ScheduledMessage, error) {
+	msgs := make([]*ScheduledMessage, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var msg ScheduledMessage
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal scheduled message: %w", err)
+		}
+		msgs = append(msgs, &msg)
+	}
+	return msgs, nil
+}
+
+// RedisLeaderLock is a LeaderLock held in a Redis key with an expiry, so a
+// replica that stops renewing it loses it after the TTL
+type RedisLeaderLock struct {
+	client *database.RedisClient
+	key    string
+	token  string
+}
+
+// NewRedisLeaderLock creates a lock on key identified by a random token
+func NewRedisLeaderLock(client *database.RedisClient, key string) *RedisLeaderLock {
+	return &RedisLeaderLock{
+		client: client,
+		key:    key,
+		token:  uuid.New().String(),
+	}
+}
+
+// Acquire implements the LeaderLock interface
+func (l *RedisLeaderLock) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	result, err := l.client.Eval(ctx, leaderAcquireScript, []string{l.key}, l.token, ttl.Milliseconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", l.key, err)
+	}
+	held, _ := result.(int64)
+	return held == 1, nil
+}
+
+// Release implements the LeaderLock interface
+func (l *RedisLeaderLock) Release(ctx context.Context) error {
+	_, err := l.client.Eval(ctx, abandonScript, []string{l.key}, l.token)
+	return err
+}
@@ -0,0 +1,241 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+
+	"go-microservice/internal/config"
+	database "go-microservice/pkg/cache"
+)
+
+// recordingPublisher records published messages and fails the ones for
+// which fail returns an error. Only Publish is implemented.
+type recordingPublisher struct {
+	Messaging
+	fail func(msg *Message) error
+
+	mu        sync.Mutex
+	published []*Message
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, msg *Message) error {
+	if p.fail != nil {
+		if err := p.fail(msg); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, msg)
+	return nil
+}
+
+// topics returns the topics of the published messages in publishing order
+func (p *recordingPublisher) topics() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	topics := make([]string, len(p.published))
+	for i, msg := range p.published {
+		topics[i] = msg.Topic
+	}
+	return topics
+}
+
+// schedule schedules a message on topic at an offset from now
+func schedule(t *testing.T, s *Scheduler, topic string, in time.Duration) *ScheduledMessage {
+	t.Helper()
+	scheduled, err := s.PublishAfter(t.Context(), &Message{Topic: topic, Payload: []byte(`{}`)}, in)
+	if err != nil {
+		t.Fatalf("PublishAfter() error = %v", err)
+	}
+	return scheduled
+}
+
+func TestSchedulerDispatchPublishesDueMessagesInOrder(t *testing.T) {
+	publisher := &recordingPublisher{}
+	s := NewScheduler(config.SchedulerConfig{BatchSize: 2}, publisher, NewMemoryScheduleStore(), LocalLock{})
+
+	third := schedule(t, s, "third", -time.Second)
+	schedule(t, s, "first", -3*time.Second)
+	schedule(t, s, "second", -2*time.Second)
+	schedule(t, s, "later", time.Hour)
+
+	// One batch per dispatch
+	for _, want := range []int{2, 1, 0} {
+		if n, err := s.Dispatch(t.Context()); err != nil || n != want {
+			t.Fatalf("Dispatch() = %d, %v, want %d", n, err, want)
+		}
+	}
+
+	if got := publisher.topics(); !slices.Equal(got, []string{"first", "second", "third"}) {
+		t.Errorf("published %v, want the due messages earliest first", got)
+	}
+	if id := publisher.published[2].Headers[HeaderMessageID]; id != third.ID {
+		t.Errorf("published message ID %q, want the scheduled ID %q", id, third.ID)
+	}
+	pending, total, err := s.Pending(t.Context(), 0)
+	if err != nil || total != 1 || pending[0].Topic != "later" {
+		t.Errorf("Pending() = %d messages, %v, want only the later message left", total, err)
+	}
+	if stats := s.Stats(); stats.Dispatched != 3 || stats.LastDispatchAt == nil {
+		t.Errorf("stats = %+v, want 3 dispatched", stats)
+	}
+}
+
+func TestSchedulerReschedulesFailedPublishes(t *testing.T) {
+	publishErr := errors.New("broker unavailable")
+	publisher := &recordingPublisher{fail: func(msg *Message) error {
+		if msg.Topic == "broken" {
+			return publishErr
+		}
+		return nil
+	}}
+	s := NewScheduler(config.SchedulerConfig{RetryDelay: time.Minute}, publisher, NewMemoryScheduleStore(), LocalLock{})
+
+	broken := schedule(t, s, "broken", -time.Second)
+	schedule(t, s, "working", -time.Second)
+
+	before := time.Now()
+	if n, err := s.Dispatch(t.Context()); err != nil || n != 2 {
+		t.Fatalf("Dispatch() = %d, %v, want 2 handled", n, err)
+	}
+
+	if got := publisher.topics(); !slices.Equal(got, []string{"working"}) {
+		t.Errorf("published %v, want only the working message", got)
+	}
+	retried, err := s.Get(t.Context(), broken.ID)
+	if err != nil {
+		t.Fatalf("Get() of the failed message error = %v", err)
+	}
+	if retried.Attempts != 1 || retried.LastError != publishErr.Error() {
+		t.Errorf("failed message has %d attempts and error %q", retried.Attempts, retried.LastError)
+	}
+	if retried.DeliverAt.Before(before.Add(time.Minute)) {
+		t.Errorf("failed message rescheduled at %v, want after the one minute retry delay", retried.DeliverAt)
+	}
+	if stats := s.Stats(); stats.Failed != 1 || stats.Dispatched != 1 || stats.LastError != publishErr.Error() {
+		t.Errorf("stats = %+v, want one dispatched and one failed", stats)
+	}
+
+	// Not due again before the retry delay
+	if n, _ := s.Dispatch(t.Context()); n != 0 {
+		t.Errorf("Dispatch() right after the failure handled %d messages, want none", n)
+	}
+}
+
+func TestSchedulerCancelAndPending(t *testing.T) {
+	s := NewScheduler(config.SchedulerConfig{}, &recordingPublisher{}, NewMemoryScheduleStore(), LocalLock{})
+
+	third := schedule(t, s, "third", 3*time.Hour)
+	first := schedule(t, s, "first", time.Hour)
+	schedule(t, s, "second", 2*time.Hour)
+
+	pending, total, err := s.Pending(t.Context(), 2)
+	if err != nil || total != 3 || len(pending) != 2 || pending[0].ID != first.ID || pending[1].Topic != "second" {
+		t.Fatalf("Pending(2) = %d of %d, %v, want the 2 earliest of 3", len(pending), total, err)
+	}
+
+	if err := s.Cancel(t.Context(), third.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if err := s.Cancel(t.Context(), third.ID); !errors.Is(err, ErrScheduledNotFound) {
+		t.Errorf("Cancel() of a cancelled message error = %v, want ErrScheduledNotFound", err)
+	}
+	if _, err := s.Get(t.Context(), third.ID); !errors.Is(err, ErrScheduledNotFound) {
+		t.Errorf("Get() of a cancelled message error = %v, want ErrScheduledNotFound", err)
+	}
+	if _, total, _ := s.Pending(t.Context(), 0); total != 2 {
+		t.Errorf("Pending() after Cancel counts %d messages, want 2", total)
+	}
+}
+
+func TestRedisLeaderLockLetsOneSchedulerDispatch(t *testing.T) {
+	server := miniredis.RunT(t)
+	client, err := database.NewRedisClient(&config.RedisConfig{Host: server.Host(), Port: server.Port()}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	// Two replicas sharing the store, each with its own lock token
+	cfg := config.SchedulerConfig{PollInterval: 10 * time.Millisecond, LockTTL: time.Minute}
+	store := NewRedisScheduleStore(client)
+	publisher := &recordingPublisher{}
+	schedulers := make([]*Scheduler, 2)
+	stops := make([]context.CancelFunc, 2)
+	var wg sync.WaitGroup
+	for i := range schedulers {
+		schedulers[i] = NewScheduler(cfg, publisher, store, NewRedisLeaderLock(client, "messaging:scheduler:leader"))
+		ctx, cancel := context.WithCancel(t.Context())
+		stops[i] = cancel
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			schedulers[i].Start(ctx)
+		}()
+	}
+	t.Cleanup(func() {
+		for _, stop := range stops {
+			stop()
+		}
+		wg.Wait()
+	})
+
+	// waitPublished waits until n messages were published in total
+	waitPublished := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(publisher.topics()) < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("published %d messages, want %d", len(publisher.topics()), n)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		schedule(t, schedulers[i%2], "orders", 0)
+	}
+	waitPublished(5)
+	// Counted once the store has dropped the message
+	time.Sleep(50 * time.Millisecond)
+
+	leader := -1
+	for i, s := range schedulers {
+		if stats := s.Stats(); stats.Leader {
+			if leader >= 0 {
+				t.Fatal("both schedulers lead")
+			}
+			leader = i
+		}
+	}
+	if leader < 0 {
+		t.Fatal("no scheduler leads")
+	}
+	follower := 1 - leader
+	if n := schedulers[leader].Stats().Dispatched; n != 5 {
+		t.Errorf("leader dispatched %d messages, want all 5", n)
+	}
+	if n := schedulers[follower].Stats().Dispatched; n != 0 {
+		t.Errorf("follower dispatched %d messages, want none", n)
+	}
+	if got := len(publisher.topics()); got != 5 {
+		t.Errorf("published %d messages, want each of the 5 once", got)
+	}
+
+	// A stopped leader releases the lock and the other replica takes over
+	stops[leader]()
+	schedule(t, schedulers[follower], "orders", 0)
+	waitPublished(6)
+	time.Sleep(50 * time.Millisecond)
+	if stats := schedulers[follower].Stats(); !stats.Leader || stats.Dispatched != 1 {
+		t.Errorf("follower stats after the leader stopped = %+v, want it to lead and dispatch", stats)
+	}
+}
@@ -0,0 +1,151 @@
+package messaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Schema errors
+var (
+	ErrSchemaNotFound   = errors.New("schema not found")
+	ErrSchemaValidation = errors.New("payload does not match schema")
+)
+
+// Schema formats that can be stored next to each other for an event version
+const (
+	SchemaFormatJSON     = "json"
+	SchemaFormatAvro     = "avsc"
+	SchemaFormatProtobuf = "proto"
+)
+
+// schemaFileName matches files named <event type>.v<version>.<format>
+var schemaFileName = regexp.MustCompile(`^(.+)\.v([0-9]+)\.(json|avsc|proto)$`)
+
+// schemaVersion holds the schema sources of one event type version
+type schemaVersion struct {
+	sources   map[string]string
+	validator *jsonschema.Schema
+}
+
+// SchemaSet holds versioned event schemas loaded from a local directory.
+// Files are named <event type>.v<version>.<format>, for example
+// file.uploaded.v2.json for a JSON Schema or file.uploaded.v2.avsc for the
+// Avro schema of the same version.
+type SchemaSet struct {
+	types map[string]map[int]*schemaVersion
+}
+
+// LoadSchemas loads all schemas from dir. JSON Schemas are compiled up front
+// so broken schemas are reported at startup.
+func LoadSchemas(dir string) (*SchemaSet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema directory: %w", err)
+	}
+
+	set := &SchemaSet{types: make(map[string]map[int]*schemaVersion)}
+	compiler := jsonschema.NewCompiler()
+	for _, entry := range entries {
+		match := schemaFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		eventType, format := match[1], match[3]
+		version, _ := strconv.Atoi(match[2])
+
+		path := filepath.Join(dir, entry.Name())
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema %s: %w", entry.Name(), err)
+		}
+
+		versions := set.types[eventType]
+		if versions == nil {
+			versions = make(map[int]*schemaVersion)
+			set.types[eventType] = versions
+		}
+		sv := versions[version]
+		if sv == nil {
+			sv = &schemaVersion{sources: make(map[string]string)}
+			versions[version] = sv
+		}
+		sv.sources[format] = string(source)
+
+		if format == SchemaFormatJSON {
+			doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(source))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse schema %s: %w", entry.Name(), err)
+			}
+			if err := compiler.AddResource(path, doc); err != nil {
+				return nil, fmt.Errorf("failed to add schema %s: %w", entry.Name(), err)
+			}
+			if sv.validator, err = compiler.Compile(path); err != nil {
+				return nil, fmt.Errorf("failed to compile schema %s: %w", entry.Name(), err)
+			}
+		}
+	}
+
+	return set, nil
+}
+
+// Latest returns the highest schema version of an event type
+func (s *SchemaSet) Latest(eventType string) (int, bool) {
+	latest := 0
+	for version := range s.types[eventType] {
+		latest = max(latest, version)
+	}
+	return latest, latest > 0
+}
+
+// Source returns the schema of an event type version in the given format
+func (s *SchemaSet) Source(eventType string, version int, format string) (string, error) {
+	sv := s.types[eventType][version]
+	if sv == nil || sv.sources[format] == "" {
+		return "", fmt.Errorf("%w: %s for %s", ErrSchemaNotFound, format, SchemaRef(eventType, version))
+	}
+	return sv.sources[format], nil
+}
+
+// Validate checks JSON data against the JSON Schema of an event type version.
+// Versions without a JSON Schema are not validated.
+func (s *SchemaSet) Validate(eventType string, version int, data []byte) error {
+	sv := s.types[eventType][version]
+	if sv == nil || sv.validator == nil {
+		return nil
+	}
+
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSchemaValidation, err)
+	}
+	if err := sv.validator.Validate(value); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrSchemaValidation, SchemaRef(eventType, version), err)
+	}
+	return nil
+}
+
+// SchemaRef returns the reference stored in an event's dataschema attribute
+func SchemaRef(eventType string, version int) string {
+	return fmt.Sprintf("%s.v%d", eventType, version)
+}
+
+// parseSchemaRef splits a dataschema reference into event type and version
+func parseSchemaRef(ref string) (string, int, bool) {
+	i := strings.LastIndex(ref, ".v")
+	if i < 0 {
+		return "", 0, false
+	}
+	version, err := strconv.Atoi(ref[i+2:])
+	if err != nil {
+		return "", 0, false
+	}
+	return ref[:i], version, true
+}
@@ -0,0 +1,182 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema types understood by Confluent-compatible schema registries
+const (
+	RegistrySchemaAvro     = "AVRO"
+	RegistrySchemaProtobuf = "PROTOBUF"
+	RegistrySchemaJSON     = "JSON"
+)
+
+// registryContentType is the media type of schema registry requests
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+// wireMagicByte starts every payload in the Confluent wire format
+const wireMagicByte = 0
+
+// Schema registry errors
+var (
+	ErrSchemaRegistry    = errors.New("schema registry request failed")
+	ErrInvalidWireFormat = errors.New("invalid schema registry wire format")
+)
+
+// RegisteredSchema is a schema stored in the registry
+type RegisteredSchema struct {
+	ID         int    `json:"id,omitempty"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// SchemaRegistryClient talks to a Confluent-compatible schema registry.
+// Registered IDs and fetched schemas are cached for the client's lifetime.
+type SchemaRegistryClient struct {
+	baseURL  string
+	username string
+	password string
+	http     *http.Client
+
+	mu      sync.RWMutex
+	ids     map[string]int
+	schemas map[int]*RegisteredSchema
+}
+
+// NewSchemaRegistryClient creates a registry client for baseURL
+func NewSchemaRegistryClient(baseURL, username, password string, timeout time.Duration) *SchemaRegistryClient {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &SchemaRegistryClient{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		username: username,
+		password: password,
+		http:     &http.Client{Timeout: timeout},
+		ids:      make(map[string]int),
+		schemas:  make(map[int]*RegisteredSchema),
+	}
+}
+
+// Register registers a schema under subject and returns its ID. Registering
+// a schema that already exists returns the existing ID.
+func (c *SchemaRegistryClient) Register(ctx context.Context, subject, schemaType, schema string) (int, error) {
+	cacheKey := subject + "\x00" + schemaType + "\x00" + schema
+
+	c.mu.RLock()
+	id, ok := c.ids[cacheKey]
+	c.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	var result RegisteredSchema
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	body := RegisteredSchema{Schema: schema, SchemaType: schemaType}
+	if err := c.do(ctx, http.MethodPost, path, body, &result); err != nil {
+		return 0, fmt.Errorf("%w: register schema for %s: %v", ErrSchemaRegistry, subject, err)
+	}
+
+	c.mu.Lock()
+	c.ids[cacheKey] = result.ID
+	c.schemas[result.ID] = &RegisteredSchema{ID: result.ID, Schema: schema, SchemaType: schemaType}
+	c.mu.Unlock()
+
+	return result.ID, nil
+}
+
+// Schema returns the schema with the given ID
+func (c *SchemaRegistryClient) Schema(ctx context.Context, id int) (*RegisteredSchema, error) {
+	c.mu.RLock()
+	schema, ok := c.schemas[id]
+	c.mu.RUnlock()
+	if ok {
+		return schema, nil
+	}
+
+	var result RegisteredSchema
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, &result); err != nil {
+		return nil, fmt.Errorf("%w: fetch schema %d: %v", ErrSchemaRegistry, id, err)
+	}
+
+	// The registry omits the type for Avro schemas
+	result.ID = id
+	if result.SchemaType == "" {
+		result.SchemaType = RegistrySchemaAvro
+	}
+
+	c.mu.Lock()
+	c.schemas[id] = &result
+	c.mu.Unlock()
+
+	return &result, nil
+}
+
+// do sends a request to the registry and decodes the JSON response into out
+func (c *SchemaRegistryClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", registryContentType)
+	if in != nil {
+		req.Header.Set("Content-Type", registryContentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var registryErr struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&registryErr)
+		return fmt.Errorf("registry returned %d: %s", resp.StatusCode, registryErr.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// frameWire prefixes a payload with the magic byte and schema ID. Protobuf
+// payloads also carry message indexes; extra holds them already encoded.
+func frameWire(id int, extra, payload []byte) []byte {
+	framed := make([]byte, 5, 5+len(extra)+len(payload))
+	framed[0] = wireMagicByte
+	binary.BigEndian.PutUint32(framed[1:5], uint32(id))
+	framed = append(framed, extra...)
+	return append(framed, payload...)
+}
+
+// unframeWire splits a framed payload into schema ID and the rest
+func unframeWire(data []byte) (int, []byte, error) {
+	if len(data) < 5 || data[0] != wireMagicByte {
+		return 0, nil, ErrInvalidWireFormat
+	}
+	return int(binary.BigEndian.Uint32(data[1:5])), data[5:], nil
+}
@@ -2,27 +2,22 @@ package upload
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/textproto"
 	"path"
-	"time"
+	"strings"
+
+	"go-microservice/internal/config"
 
 	"github.com/jlaffaye/ftp"
 )
 
-// FTPConfig holds FTP connection settings
-type FTPConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     string `mapstructure:"port"`
-	Username string `mapstructure:"username"`
-	Password string `mapstructure:"password"`
-	BaseDir  string `mapstructure:"base_dir"`
-}
-
 // FTPUploader implements the Uploader interface for FTP
 type FTPUploader struct {
-	client  *ftp.ServerConn
-	config  *FTPConfig
+	pool    *connPool[*ftp.ServerConn]
+	config  *config.FTPConfig
 	baseURL string
 }
 
@@ -32,21 +27,24 @@ func init() {
 
 // NewFTPUploader creates a new FTP uploader instance
 func NewFTPUploader(cfg interface{}) (Uploader, error) {
-	ftpCfg, ok := cfg.(*FTPConfig)
+	ftpCfg, ok := cfg.(*config.FTPConfig)
 	if !ok {
 		return nil, ErrInvalidConfig
 	}
 
-	// Connect to FTP server
-	addr := fmt.Sprintf("%s:%s", ftpCfg.Host, ftpCfg.Port)
-	client, err := ftp.Dial(addr, ftp.DialWithTimeout(5*time.Second))
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	// Returned URLs leave out the credentials
+	u := &FTPUploader{
+		config:  ftpCfg,
+		baseURL: fmt.Sprintf("ftp://%s:%s", ftpCfg.Host, ftpCfg.Port),
 	}
+	u.pool = newConnPool(ftpCfg.Pool, u.dial, (*ftp.ServerConn).NoOp, (*ftp.ServerConn).Quit)
 
-	// Login
-	if err := client.Login(ftpCfg.Username, ftpCfg.Password); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	// Establish the first connection eagerly so that bad settings fail fast
+	ctx := context.Background()
+	client, err := u.pool.acquire(ctx)
+	if err != nil {
+		u.pool.Close()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
 	// Create base directory if it doesn't exist
@@ -55,49 +53,99 @@ func NewFTPUploader(cfg interface{}) (Uploader, error) {
 			// Ignore error if directory already exists
 		}
 	}
+	u.pool.release(client, nil)
 
-	return &FTPUploader{
-		client:  client,
-		config:  ftpCfg,
-		baseURL: fmt.Sprintf("ftp://%s:%s@%s:%s", ftpCfg.Username, ftpCfg.Password, ftpCfg.Host, ftpCfg.Port),
-	}, nil
+	return u, nil
+}
+
+// dial opens and authenticates a new FTP connection
+func (u *FTPUploader) dial(ctx context.Context) (*ftp.ServerConn, error) {
+	addr := fmt.Sprintf("%s:%s", u.config.Host, u.config.Port)
+	client, err := ftp.Dial(addr, ftp.DialWithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// Login
+	if err := client.Login(u.config.Username, u.config.Password); err != nil {
+		client.Quit()
+		return nil, err
+	}
+	return client, nil
 }
 
 // Upload implements the Uploader interface
 func (u *FTPUploader) Upload(ctx context.Context, filepath string, content io.Reader, contentType string) (string, error) {
+	client, err := u.pool.acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
+	}
+
 	// Create full path
 	fullPath := path.Join(u.config.BaseDir, filepath)
 
 	// Create directories if they don't exist
-	dir := path.Dir(fullPath)
-	if err := u.client.MakeDir(dir); err != nil {
-		// Ignore error if directory already exists
+	ftpMakeDirAll(client, path.Dir(fullPath))
+
+	// Upload file, aborting the transfer if the context is cancelled
+	stop := u.pool.watch(ctx, client)
+	err = client.Stor(fullPath, content)
+	if !stop() {
+		u.pool.forget()
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, ctx.Err())
 	}
-
-	// Upload file
-	if err := u.client.Stor(fullPath, content); err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	u.pool.release(client, err)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
 	// Return FTP URL
 	return fmt.Sprintf("%s/%s", u.baseURL, fullPath), nil
 }
 
-// Download implements the Uploader interface
+// Download implements the Uploader interface. The connection stays checked out
+// of the pool until the returned reader is closed.
 func (u *FTPUploader) Download(ctx context.Context, filepath string) (io.ReadCloser, error) {
+	client, err := u.pool.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+
 	fullPath := path.Join(u.config.BaseDir, filepath)
-	reader, err := u.client.Retr(fullPath)
+	stop := u.pool.watch(ctx, client)
+	reader, err := client.Retr(fullPath)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		if !stop() {
+			u.pool.forget()
+			return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, ctx.Err())
+		}
+		u.pool.release(client, err)
+		if isFTPNotFound(err) {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
 	}
-	return reader, nil
+
+	return &pooledReader[*ftp.ServerConn]{
+		ReadCloser: reader,
+		pool:       u.pool,
+		conn:       client,
+		stop:       stop,
+	}, nil
 }
 
 // Delete implements the Uploader interface
 func (u *FTPUploader) Delete(ctx context.Context, filepath string) error {
+	client, err := u.pool.acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+
 	fullPath := path.Join(u.config.BaseDir, filepath)
-	if err := u.client.Delete(fullPath); err != nil {
-		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	err = client.Delete(fullPath)
+	u.pool.release(client, err)
+	if err != nil && !isFTPNotFound(err) {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
 	}
 	return nil
 }
@@ -108,7 +156,30 @@ func (u *FTPUploader) GetURL(ctx context.Context, filepath string) (string, erro
 	return fmt.Sprintf("%s/%s", u.baseURL, fullPath), nil
 }
 
-// Close closes the FTP connection
+// Close closes all pooled FTP connections
 func (u *FTPUploader) Close() error {
-	return u.client.Quit()
+	return u.pool.Close()
+}
+
+// ftpMakeDirAll creates a directory and any missing parents. Errors are
+// ignored because servers report existing directories as failures.
+func ftpMakeDirAll(client *ftp.ServerConn, dir string) {
+	if dir == "." || dir == "/" || dir == "" {
+		return
+	}
+
+	current := ""
+	if path.IsAbs(dir) {
+		current = "/"
+	}
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		current = path.Join(current, part)
+		client.MakeDir(current)
+	}
+}
+
+// isFTPNotFound reports whether the server rejected a command because the file is unavailable
+func isFTPNotFound(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code == ftp.StatusFileUnavailable
 }
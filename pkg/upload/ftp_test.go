@@ -0,0 +1,36 @@
+package upload_test
+
+import (
+	"strings"
+	"testing"
+
+	"go-microservice/pkg/upload"
+	"go-microservice/pkg/upload/uploadtest"
+)
+
+func TestFTPUploader(t *testing.T) {
+	uploadtest.Run(t, func(t *testing.T) upload.Uploader {
+		u, err := upload.NewUploader("ftp", uploadtest.NewFTPServer(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	})
+}
+
+func TestFTPUploaderURLHasNoCredentials(t *testing.T) {
+	cfg := uploadtest.NewFTPServer(t)
+	u, err := upload.NewUploader("ftp", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.(interface{ Close() error }).Close()
+
+	url, err := u.Upload(t.Context(), "secret.txt", strings.NewReader("x"), "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(url, cfg.Password) || strings.Contains(url, "@") {
+		t.Errorf("Upload returned URL %q, want it without credentials", url)
+	}
+}
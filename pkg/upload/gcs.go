@@ -2,6 +2,7 @@ package upload
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -40,7 +41,7 @@ func NewGCSUploader(cfg interface{}) (Uploader, error) {
 		client, err = storage.NewClient(ctx)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
 	bucket := client.Bucket(gcsCfg.Bucket)
@@ -64,11 +65,11 @@ func (u *GCSUploader) Upload(ctx context.Context, filepath string, content io.Re
 	wc.ContentType = contentType
 
 	if _, err := io.Copy(wc, content); err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
 	if err := wc.Close(); err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
 	// Generate signed URL
@@ -90,8 +91,11 @@ func (u *GCSUploader) Upload(ctx context.Context, filepath string, content io.Re
 func (u *GCSUploader) Download(ctx context.Context, filepath string) (io.ReadCloser, error) {
 	obj := u.bucket.Object(filepath)
 	reader, err := obj.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
 	}
 	return reader, nil
 }
@@ -99,8 +103,8 @@ func (u *GCSUploader) Download(ctx context.Context, filepath string) (io.ReadClo
 // Delete implements the Uploader interface
 func (u *GCSUploader) Delete(ctx context.Context, filepath string) error {
 	obj := u.bucket.Object(filepath)
-	if err := obj.Delete(ctx); err != nil {
-		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	if err := obj.Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
 	}
 	return nil
 }
@@ -3,6 +3,8 @@ package upload
 import (
 	"context"
 	"io"
+
+	"go-microservice/pkg/apperror"
 )
 
 // Uploader defines the interface for file upload operations
@@ -10,13 +12,16 @@ type Uploader interface {
 	// Upload uploads a file to the storage backend
 	Upload(ctx context.Context, path string, content io.Reader, contentType string) (string, error)
 	
-	// Download retrieves a file from the storage backend
+	// Download retrieves a file from the storage backend. A missing file
+	// results in an error wrapping ErrNotFound.
 	Download(ctx context.Context, path string) (io.ReadCloser, error)
 	
-	// Delete removes a file from the storage backend
+	// Delete removes a file from the storage backend. Deleting a file that
+	// does not exist is not an error.
 	Delete(ctx context.Context, path string) error
 	
-	// GetURL returns the public URL for a file (if supported by the backend)
+	// GetURL returns the public URL for a file. Backends that cannot build
+	// one return an error wrapping ErrURLNotSupported.
 	GetURL(ctx context.Context, path string) (string, error)
 }
 
@@ -31,34 +36,28 @@ func RegisterUploader(name string, factory UploaderFactory) {
 	uploaderFactories[name] = factory
 }
 
-// NewUploader creates a new uploader instance based on the specified backend
+// NewUploader creates a new uploader instance based on the specified backend.
+// Every key passed to the returned uploader is validated with CleanKey.
 func NewUploader(backend string, cfg interface{}) (Uploader, error) {
 	factory, exists := uploaderFactories[backend]
 	if !exists {
 		return nil, ErrUnsupportedBackend
 	}
-	return factory(cfg)
-}
-
-// Error types
-var (
-	ErrUnsupportedBackend = NewUploadError("unsupported upload backend")
-	ErrInvalidConfig     = NewUploadError("invalid configuration")
-	ErrUploadFailed     = NewUploadError("upload failed")
-	ErrDownloadFailed   = NewUploadError("download failed")
-	ErrDeleteFailed     = NewUploadError("delete failed")
-)
 
-// UploadError represents an error that occurred during upload operations
-type UploadError struct {
-	Message string
-}
-
-func (e *UploadError) Error() string {
-	return e.Message
+	uploader, err := factory(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &sanitizedUploader{Uploader: uploader}, nil
 }
 
-// NewUploadError creates a new UploadError
-func NewUploadError(message string) *UploadError {
-	return &UploadError{Message: message}
-} 
\ No newline at end of file
+// Error types. Errors of the storage backends wrap them and their cause.
+var (
+	ErrUnsupportedBackend = apperror.New(apperror.CodeInternal, "unsupported upload backend")
+	ErrInvalidConfig      = apperror.New(apperror.CodeInternal, "invalid configuration")
+	ErrUploadFailed       = apperror.New(apperror.CodeUpstream, "upload failed")
+	ErrDownloadFailed     = apperror.New(apperror.CodeUpstream, "download failed")
+	ErrDeleteFailed       = apperror.New(apperror.CodeUpstream, "delete failed")
+	ErrNotFound           = apperror.New(apperror.CodeNotFound, "file not found")
+	ErrURLNotSupported    = apperror.New(apperror.CodeNotImplemented, "URL not supported")
+) 
\ No newline at end of file
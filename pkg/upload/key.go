@@ -0,0 +1,214 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go-microservice/pkg/apperror"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalidPath is returned for storage keys that are unsafe or malformed
+var ErrInvalidPath = apperror.New(apperror.CodeValidation, "invalid path")
+
+// maxKeyLength matches the object key limit of S3-compatible stores
+const maxKeyLength = 1024
+
+// DefaultKeyTemplate is used when no key template is configured
+const DefaultKeyTemplate = "{userID}/{yyyy}/{mm}/{uuid}{ext}"
+
+// CleanKey validates a storage key and returns its normalized form. Keys are
+// NFC-normalized, backslashes become slashes, and empty and "." segments are
+// dropped. Absolute paths, ".." segments and control characters are rejected.
+func CleanKey(key string) (string, error) {
+	if !utf8.ValidString(key) {
+		return "", fmt.Errorf("%w: key is not valid UTF-8", ErrInvalidPath)
+	}
+
+	key = norm.NFC.String(key)
+	key = strings.ReplaceAll(key, "\\", "/")
+
+	for _, r := range key {
+		if unicode.IsControl(r) {
+			return "", fmt.Errorf("%w: key contains control characters", ErrInvalidPath)
+		}
+	}
+
+	if strings.HasPrefix(key, "/") || hasDriveLetter(key) {
+		return "", fmt.Errorf("%w: absolute paths are not allowed", ErrInvalidPath)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(key, "/") {
+		switch segment {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("%w: parent directory references are not allowed", ErrInvalidPath)
+		}
+		segments = append(segments, segment)
+	}
+
+	cleaned := strings.Join(segments, "/")
+	if cleaned == "" {
+		return "", fmt.Errorf("%w: key is empty", ErrInvalidPath)
+	}
+	if len(cleaned) > maxKeyLength {
+		return "", fmt.Errorf("%w: key exceeds %d bytes", ErrInvalidPath, maxKeyLength)
+	}
+	return cleaned, nil
+}
+
+// hasDriveLetter reports whether a key starts with a Windows drive such as "C:"
+func hasDriveLetter(key string) bool {
+	return len(key) >= 2 && key[1] == ':' &&
+		(key[0] >= 'a' && key[0] <= 'z' || key[0] >= 'A' && key[0] <= 'Z')
+}
+
+// KeyParams holds the values available to a key template
+type KeyParams struct {
+	ID       string
+	UserID   string
+	TenantID string
+	Filename string
+}
+
+// KeyGenerator builds server-side object keys from a template so that client
+// supplied filenames never decide where data is stored. Supported
+// placeholders are {userID}, {tenantID}, {yyyy}, {mm}, {dd}, {uuid} and {ext}.
+type KeyGenerator struct {
+	template string
+	now      func() time.Time
+}
+
+// keyPlaceholder matches a single template placeholder
+var keyPlaceholder = regexp.MustCompile(`\{[a-zA-Z]+\}`)
+
+// extPattern limits extensions taken from client filenames
+var extPattern = regexp.MustCompile(`^\.[a-z0-9]{1,16}$`)
+
+// NewKeyGenerator creates a key generator, falling back to DefaultKeyTemplate
+func NewKeyGenerator(template string) (*KeyGenerator, error) {
+	if template == "" {
+		template = DefaultKeyTemplate
+	}
+
+	for _, placeholder := range keyPlaceholder.FindAllString(template, -1) {
+		switch placeholder {
+		case "{userID}", "{tenantID}", "{yyyy}", "{mm}", "{dd}", "{uuid}", "{ext}":
+		default:
+			return nil, fmt.Errorf("%w: unknown key template placeholder %s", ErrInvalidConfig, placeholder)
+		}
+	}
+	if !strings.Contains(template, "{uuid}") {
+		return nil, fmt.Errorf("%w: key template must contain {uuid}", ErrInvalidConfig)
+	}
+
+	return &KeyGenerator{
+		template: template,
+		now:      time.Now,
+	}, nil
+}
+
+// Generate returns a new object key for the given parameters
+func (g *KeyGenerator) Generate(params KeyParams) (string, error) {
+	id := params.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	now := g.now().UTC()
+	replacer := strings.NewReplacer(
+		"{userID}", keySegment(params.UserID, "anonymous"),
+		"{tenantID}", keySegment(params.TenantID, "default"),
+		"{yyyy}", fmt.Sprintf("%04d", now.Year()),
+		"{mm}", fmt.Sprintf("%02d", now.Month()),
+		"{dd}", fmt.Sprintf("%02d", now.Day()),
+		"{uuid}", keySegment(id, ""),
+		"{ext}", safeExt(params.Filename),
+	)
+
+	return CleanKey(replacer.Replace(g.template))
+}
+
+// keySegment makes a value safe to use as a single path segment
+func keySegment(value, fallback string) string {
+	value = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, value)
+	if value == "" || value == "." || value == ".." {
+		return fallback
+	}
+	return value
+}
+
+// safeExt returns the lower-cased extension of a client filename, or nothing
+// if it does not look like a plain extension
+func safeExt(filename string) string {
+	ext := strings.ToLower(path.Ext(strings.ReplaceAll(filename, "\\", "/")))
+	if !extPattern.MatchString(ext) {
+		return ""
+	}
+	return ext
+}
+
+// sanitizedUploader validates every key before handing it to a backend
+type sanitizedUploader struct {
+	Uploader
+}
+
+// Upload implements the Uploader interface
+func (u *sanitizedUploader) Upload(ctx context.Context, key string, content io.Reader, contentType string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return u.Uploader.Upload(ctx, key, content, contentType)
+}
+
+// Download implements the Uploader interface
+func (u *sanitizedUploader) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return u.Uploader.Download(ctx, key)
+}
+
+// Delete implements the Uploader interface
+func (u *sanitizedUploader) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	return u.Uploader.Delete(ctx, key)
+}
+
+// GetURL implements the Uploader interface
+func (u *sanitizedUploader) GetURL(ctx context.Context, key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return u.Uploader.GetURL(ctx, key)
+}
+
+// Close closes the wrapped backend if it holds resources
+func (u *sanitizedUploader) Close() error {
+	if closer, ok := u.Uploader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package upload
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"plain", "user/2024/file.txt", "user/2024/file.txt"},
+		{"backslashes", `user\2024\file.txt`, "user/2024/file.txt"},
+		{"empty and dot segments", "user//./2024/./file.txt/", "user/2024/file.txt"},
+		{"dots inside names", "user/..hidden/file..txt", "user/..hidden/file..txt"},
+		{"colon after the first segment", "user/c:/file.txt", "user/c:/file.txt"},
+		{"NFD normalized to NFC", "user/cafe\u0301.txt", "user/caf\u00e9.txt"},
+		{"NFC kept", "user/caf\u00e9.txt", "user/caf\u00e9.txt"},
+		{"unicode", "用户/文件.txt", "用户/文件.txt"},
+		{"longest key", strings.Repeat("a", maxKeyLength), strings.Repeat("a", maxKeyLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CleanKey(tt.key)
+			if err != nil {
+				t.Fatalf("CleanKey(%q) error = %v", tt.key, err)
+			}
+			if got != tt.want {
+				t.Errorf("CleanKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanKeyRejectsUnsafeKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"parent directory", "../etc/passwd"},
+		{"parent directory inside", "user/../../etc/passwd"},
+		{"parent directory with backslashes", `user\..\..\etc\passwd`},
+		{"trailing parent directory", "user/.."},
+		{"absolute path", "/etc/passwd"},
+		{"absolute path with backslash", `\etc\passwd`},
+		{"UNC path", `\\server\share\file`},
+		{"drive letter", "C:/Windows/win.ini"},
+		{"drive letter with backslash", `c:\Windows\win.ini`},
+		{"drive-relative path", "D:file.txt"},
+		{"null byte", "user/file\x00.txt"},
+		{"newline", "user/file\n.txt"},
+		{"escape", "user/\x1b[31mfile.txt"},
+		{"DEL", "user/file\x7f.txt"},
+		{"C1 control", "user/file\u0085.txt"},
+		{"invalid UTF-8", "user/\xff\xfe.txt"},
+		{"empty", ""},
+		{"only separators", "/./"},
+		{"only dots", "./."},
+		{"too long", strings.Repeat("a", maxKeyLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CleanKey(tt.key)
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("CleanKey(%q) = %q, %v, want ErrInvalidPath", tt.key, got, err)
+			}
+		})
+	}
+}
+
+func TestKeyGeneratorGenerate(t *testing.T) {
+	uuidPattern := `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`
+
+	tests := []struct {
+		name     string
+		template string
+		params   KeyParams
+		want     string // a regular expression when it contains the uuid pattern
+	}{
+		{
+			name:   "default template",
+			params: KeyParams{ID: "id-1", UserID: "42", Filename: "Report.PDF"},
+			want:   "42/2024/03/id-1.pdf",
+		},
+		{
+			name:     "every placeholder",
+			template: "{tenantID}/{userID}/{yyyy}-{mm}-{dd}/{uuid}{ext}",
+			params:   KeyParams{ID: "id-1", UserID: "42", TenantID: "acme", Filename: "a.tar.gz"},
+			want:     "acme/42/2024-03-07/id-1.gz",
+		},
+		{
+			name:     "fallbacks for missing values",
+			template: "{tenantID}/{userID}/{uuid}{ext}",
+			params:   KeyParams{ID: "id-1"},
+			want:     "default/anonymous/id-1",
+		},
+		{
+			name:     "generated id",
+			template: "{userID}/{uuid}",
+			params:   KeyParams{UserID: "42"},
+			want:     "^42/" + uuidPattern + "$",
+		},
+		{
+			name:     "traversal in values",
+			template: "{tenantID}/{userID}/{uuid}{ext}",
+			params:   KeyParams{ID: "../id", UserID: "..", TenantID: `a\b`, Filename: "../../x.sh"},
+			want:     "a_b/anonymous/.._id.sh",
+		},
+		{
+			name:     "control characters in values",
+			template: "{userID}/{uuid}",
+			params:   KeyParams{ID: "id\n1", UserID: "4\x002"},
+			want:     "4_2/id_1",
+		},
+		{
+			name:     "unsafe extensions dropped",
+			template: "{uuid}{ext}",
+			params:   KeyParams{ID: "id-1", Filename: "file.p/hp"},
+			want:     "id-1",
+		},
+		{
+			name:     "overlong extension dropped",
+			template: "{uuid}{ext}",
+			params:   KeyParams{ID: "id-1", Filename: "file.abcdefghijklmnopq"},
+			want:     "id-1",
+		},
+		{
+			name:     "extension from a windows path",
+			template: "{uuid}{ext}",
+			params:   KeyParams{ID: "id-1", Filename: `C:\Users\me\photo.JPG`},
+			want:     "id-1.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewKeyGenerator(tt.template)
+			if err != nil {
+				t.Fatalf("NewKeyGenerator(%q) error = %v", tt.template, err)
+			}
+			g.now = func() time.Time { return time.Date(2024, 3, 7, 23, 30, 0, 0, time.UTC) }
+
+			got, err := g.Generate(tt.params)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if strings.Contains(tt.want, uuidPattern) {
+				if !regexp.MustCompile(tt.want).MatchString(got) {
+					t.Errorf("Generate() = %q, want a match of %s", got, tt.want)
+				}
+			} else if got != tt.want {
+				t.Errorf("Generate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyGeneratorUsesUTC(t *testing.T) {
+	g, err := NewKeyGenerator("{yyyy}/{mm}/{dd}/{uuid}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Already March 8th in UTC
+	g.now = func() time.Time { return time.Date(2024, 3, 7, 23, 30, 0, 0, time.FixedZone("EST", -5*3600)) }
+
+	if got, _ := g.Generate(KeyParams{ID: "id-1"}); got != "2024/03/08/id-1" {
+		t.Errorf("Generate() = %q, want the UTC date", got)
+	}
+}
+
+func TestNewKeyGeneratorRejectsInvalidTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"unknown placeholder", "{userID}/{filename}/{uuid}"},
+		{"misspelled placeholder", "{userid}/{uuid}"},
+		{"without uuid", "{userID}/{yyyy}/{ext}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyGenerator(tt.template); !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("NewKeyGenerator(%q) error = %v, want ErrInvalidConfig", tt.template, err)
+			}
+		})
+	}
+}
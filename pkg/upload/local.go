@@ -2,23 +2,19 @@ package upload
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
-)
 
-// LocalConfig holds local storage settings
-type LocalConfig struct {
-	BaseDir    string `mapstructure:"base_dir"`
-	BaseURL    string `mapstructure:"base_url"`
-	CreateDirs bool   `mapstructure:"create_dirs"`
-}
+	"go-microservice/internal/config"
+)
 
 // LocalUploader implements the Uploader interface for local storage
 type LocalUploader struct {
-	config *LocalConfig
+	config *config.LocalConfig
 }
 
 func init() {
@@ -27,7 +23,7 @@ func init() {
 
 // NewLocalUploader creates a new local storage uploader instance
 func NewLocalUploader(cfg interface{}) (Uploader, error) {
-	localCfg, ok := cfg.(*LocalConfig)
+	localCfg, ok := cfg.(*config.LocalConfig)
 	if !ok {
 		return nil, ErrInvalidConfig
 	}
@@ -35,7 +31,7 @@ func NewLocalUploader(cfg interface{}) (Uploader, error) {
 	// Ensure base directory exists
 	if localCfg.CreateDirs {
 		if err := os.MkdirAll(localCfg.BaseDir, 0755); err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+			return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 		}
 	}
 
@@ -46,7 +42,14 @@ func NewLocalUploader(cfg interface{}) (Uploader, error) {
 
 // Upload implements the Uploader interface
 func (u *LocalUploader) Upload(ctx context.Context, relativePath string, content io.Reader, contentType string) (string, error) {
-	fullPath := filepath.Join(u.config.BaseDir, relativePath)
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
+	}
+
+	fullPath, err := u.resolve(relativePath)
+	if err != nil {
+		return "", err
+	}
 
 	// Create directories if needed
 	if u.config.CreateDirs {
@@ -59,42 +62,72 @@ func (u *LocalUploader) Upload(ctx context.Context, relativePath string, content
 	// Create file
 	file, err := os.Create(fullPath)
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 	defer file.Close()
 
-	// Copy content
-	if _, err := io.Copy(file, content); err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+	// Copy content, stopping early if the context is cancelled
+	if _, err := io.Copy(file, newContextReader(ctx, content)); err != nil {
+		file.Close()
+		os.Remove(fullPath)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
 	// Return URL
-	return u.GetURL(ctx, relativePath)
+	url, err := u.GetURL(ctx, relativePath)
+	if errors.Is(err, ErrURLNotSupported) {
+		return "", nil
+	}
+	return url, err
 }
 
 // Download implements the Uploader interface
 func (u *LocalUploader) Download(ctx context.Context, relativePath string) (io.ReadCloser, error) {
-	fullPath := filepath.Join(u.config.BaseDir, relativePath)
+	fullPath, err := u.resolve(relativePath)
+	if err != nil {
+		return nil, err
+	}
 	file, err := os.Open(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
 	}
 	return file, nil
 }
 
 // Delete implements the Uploader interface
 func (u *LocalUploader) Delete(ctx context.Context, relativePath string) error {
-	fullPath := filepath.Join(u.config.BaseDir, relativePath)
-	if err := os.Remove(fullPath); err != nil {
-		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	fullPath, err := u.resolve(relativePath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
 	}
 	return nil
 }
 
+// resolve maps a key to a path on disk and makes sure it stays inside BaseDir
+func (u *LocalUploader) resolve(relativePath string) (string, error) {
+	key, err := CleanKey(relativePath)
+	if err != nil {
+		return "", err
+	}
+
+	fullPath := filepath.Join(u.config.BaseDir, filepath.FromSlash(key))
+	rel, err := filepath.Rel(u.config.BaseDir, fullPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w: path escapes base directory", ErrInvalidPath)
+	}
+	return fullPath, nil
+}
+
 // GetURL implements the Uploader interface
 func (u *LocalUploader) GetURL(ctx context.Context, relativePath string) (string, error) {
 	if u.config.BaseURL == "" {
-		return "", fmt.Errorf("base URL not configured")
+		return "", fmt.Errorf("%w: base URL not configured", ErrURLNotSupported)
 	}
 
 	baseURL := u.config.BaseURL
@@ -0,0 +1,23 @@
+package upload_test
+
+import (
+	"testing"
+
+	"go-microservice/internal/config"
+	"go-microservice/pkg/upload"
+	"go-microservice/pkg/upload/uploadtest"
+)
+
+func TestLocalUploader(t *testing.T) {
+	uploadtest.Run(t, func(t *testing.T) upload.Uploader {
+		u, err := upload.NewUploader("local", &config.LocalConfig{
+			BaseDir:    t.TempDir(),
+			BaseURL:    "http://localhost/files",
+			CreateDirs: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	})
+}
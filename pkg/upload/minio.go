@@ -35,7 +35,7 @@ func NewMinioUploader(cfg interface{}) (Uploader, error) {
 		Secure: minioCfg.UseSSL,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
 	// Check if bucket exists, create if not
@@ -57,11 +57,12 @@ func NewMinioUploader(cfg interface{}) (Uploader, error) {
 
 // Upload implements the Uploader interface
 func (u *MinioUploader) Upload(ctx context.Context, filepath string, content io.Reader, contentType string) (string, error) {
-	_, err := u.client.PutObject(ctx, u.bucket, filepath, content, -1, minio.PutObjectOptions{
+	// A known size is sent in one request; unknown sizes need a multipart upload
+	_, err := u.client.PutObject(ctx, u.bucket, filepath, content, contentLength(content), minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
 	// Generate presigned URL
@@ -77,7 +78,16 @@ func (u *MinioUploader) Upload(ctx context.Context, filepath string, content io.
 func (u *MinioUploader) Download(ctx context.Context, filepath string) (io.ReadCloser, error) {
 	object, err := u.client.GetObject(ctx, u.bucket, filepath, minio.GetObjectOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+
+	// GetObject is lazy, so stat the object to surface missing keys up front
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
 	}
 	return object, nil
 }
@@ -85,7 +95,7 @@ func (u *MinioUploader) Download(ctx context.Context, filepath string) (io.ReadC
 // Delete implements the Uploader interface
 func (u *MinioUploader) Delete(ctx context.Context, filepath string) error {
 	if err := u.client.RemoveObject(ctx, u.bucket, filepath, minio.RemoveObjectOptions{}); err != nil {
-		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
 	}
 	return nil
 }
@@ -0,0 +1,18 @@
+package upload_test
+
+import (
+	"testing"
+
+	"go-microservice/pkg/upload"
+	"go-microservice/pkg/upload/uploadtest"
+)
+
+func TestMinioUploader(t *testing.T) {
+	uploadtest.Run(t, func(t *testing.T) upload.Uploader {
+		u, err := upload.NewUploader("minio", uploadtest.NewS3Server(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	})
+}
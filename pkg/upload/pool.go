@@ -0,0 +1,301 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go-microservice/internal/config"
+)
+
+// ErrPoolClosed is returned when a connection is requested from a closed pool
+var ErrPoolClosed = errors.New("connection pool closed")
+
+// poolDefaults returns a copy of the pool config with zero values replaced by defaults
+func poolDefaults(c config.PoolConfig) config.PoolConfig {
+	if c.MaxConns <= 0 {
+		c.MaxConns = 4
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 5 * time.Minute
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = 5 * time.Second
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = 3
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 200 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Second
+	}
+	return c
+}
+
+// idleConn is a pooled connection together with the time it was returned
+type idleConn[C any] struct {
+	conn     C
+	returned time.Time
+}
+
+// connPool is a bounded pool of connections with health checking and reconnects
+type connPool[C any] struct {
+	config config.PoolConfig
+	dial   func(ctx context.Context) (C, error)
+	ping   func(C) error
+	close  func(C) error
+
+	slots  chan struct{}
+	mu     sync.Mutex
+	idle   []idleConn[C]
+	closed bool
+	done   chan struct{}
+}
+
+// newConnPool creates a connection pool and starts its idle reaper
+func newConnPool[C any](cfg config.PoolConfig, dial func(context.Context) (C, error), ping, closeFn func(C) error) *connPool[C] {
+	cfg = poolDefaults(cfg)
+	p := &connPool[C]{
+		config: cfg,
+		dial:   dial,
+		ping:   ping,
+		close:  closeFn,
+		slots:  make(chan struct{}, cfg.MaxConns),
+		done:   make(chan struct{}),
+	}
+	go p.reapLoop()
+	return p
+}
+
+// acquire returns a healthy connection, reusing an idle one or dialing a new one
+func (p *connPool[C]) acquire(ctx context.Context) (C, error) {
+	var zero C
+
+	// Wait for a free slot so the number of open connections stays bounded
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case <-p.done:
+		return zero, ErrPoolClosed
+	}
+
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		<-p.slots
+		return zero, ErrPoolClosed
+	}
+
+	// Prefer the most recently used idle connection
+	for {
+		conn, ok := p.popIdle()
+		if !ok {
+			break
+		}
+		if err := p.ping(conn); err != nil {
+			p.close(conn)
+			continue
+		}
+		return conn, nil
+	}
+
+	conn, err := p.dialWithRetry(ctx)
+	if err != nil {
+		<-p.slots
+		return zero, err
+	}
+	return conn, nil
+}
+
+// release returns a connection to the pool. When the operation that used the
+// connection failed, the connection is health checked before it is reused.
+func (p *connPool[C]) release(conn C, opErr error) {
+	if opErr != nil {
+		if err := p.ping(conn); err != nil {
+			p.discard(conn)
+			return
+		}
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.discard(conn)
+		return
+	}
+	p.idle = append(p.idle, idleConn[C]{conn: conn, returned: time.Now()})
+	p.mu.Unlock()
+
+	<-p.slots
+}
+
+// discard closes a broken connection and frees its slot
+func (p *connPool[C]) discard(conn C) {
+	p.close(conn)
+	p.forget()
+}
+
+// forget frees the slot of a connection that was closed already, such as
+// one closed by watch when its context was cancelled
+func (p *connPool[C]) forget() {
+	<-p.slots
+}
+
+// popIdle removes the most recently returned connection that has not
+// expired. Expired connections are closed outside the lock, since closing
+// may block on the network.
+func (p *connPool[C]) popIdle() (C, bool) {
+	var expired []C
+	defer func() {
+		for _, conn := range expired {
+			p.close(conn)
+		}
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var zero C
+	for len(p.idle) > 0 {
+		last := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if time.Since(last.returned) > p.config.IdleTimeout {
+			expired = append(expired, last.conn)
+			continue
+		}
+		return last.conn, true
+	}
+	return zero, false
+}
+
+// dialWithRetry dials a new connection, retrying with exponential backoff
+func (p *connPool[C]) dialWithRetry(ctx context.Context) (C, error) {
+	var zero C
+	backoff := p.config.InitialBackoff
+
+	var lastErr error
+	for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			}
+			backoff *= 2
+			if backoff > p.config.MaxBackoff {
+				backoff = p.config.MaxBackoff
+			}
+		}
+
+		dialCtx, cancel := context.WithTimeout(ctx, p.config.DialTimeout)
+		conn, err := p.dial(dialCtx)
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+	}
+	return zero, fmt.Errorf("failed to connect after %d attempts: %w", p.config.MaxRetries+1, lastErr)
+}
+
+// reapLoop periodically closes connections that have been idle too long
+func (p *connPool[C]) reapLoop() {
+	ticker := time.NewTicker(p.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.reapIdle()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// reapIdle closes expired idle connections after taking them out of the pool
+func (p *connPool[C]) reapIdle() {
+	var expired []C
+	p.mu.Lock()
+	kept := p.idle[:0]
+	for _, ic := range p.idle {
+		if time.Since(ic.returned) > p.config.IdleTimeout {
+			expired = append(expired, ic.conn)
+			continue
+		}
+		kept = append(kept, ic)
+	}
+	clear(p.idle[len(kept):])
+	p.idle = kept
+	p.mu.Unlock()
+
+	for _, conn := range expired {
+		p.close(conn)
+	}
+}
+
+// Close closes all idle connections and stops the pool. Connections that are
+// currently in use are closed when they are released.
+func (p *connPool[C]) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	var errs []error
+	for _, ic := range idle {
+		if err := p.close(ic.conn); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// watch closes conn when ctx is cancelled so that blocking transfers are
+// interrupted. The returned stop function reports whether the connection is
+// still usable, i.e. the context did not fire first. When it is not, the
+// connection is closed already and only its slot is freed with forget.
+func (p *connPool[C]) watch(ctx context.Context, conn C) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		p.close(conn)
+	})
+}
+
+// pooledReader holds a pooled connection until the download stream is closed
+type pooledReader[C any] struct {
+	io.ReadCloser
+	pool *connPool[C]
+	conn C
+	stop func() bool
+	once sync.Once
+}
+
+// Close closes the stream and hands the connection back to the pool
+func (r *pooledReader[C]) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		if !r.stop() {
+			r.pool.forget()
+			return
+		}
+		r.pool.release(r.conn, err)
+	})
+	return err
+}
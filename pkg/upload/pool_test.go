@@ -0,0 +1,120 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-microservice/internal/config"
+)
+
+// fakeConn counts how often the pool closes it
+type fakeConn struct {
+	closes atomic.Int32
+}
+
+// newFakePool returns a pool of fakeConns with a single slot. Closing a
+// connection while the pool's lock is held fails the test.
+func newFakePool(t *testing.T) *connPool[*fakeConn] {
+	t.Helper()
+
+	var p *connPool[*fakeConn]
+	p = newConnPool(config.PoolConfig{MaxConns: 1, IdleTimeout: time.Hour},
+		func(context.Context) (*fakeConn, error) { return &fakeConn{}, nil },
+		func(*fakeConn) error { return nil },
+		func(conn *fakeConn) error {
+			if !p.mu.TryLock() {
+				t.Error("connection closed while the pool lock is held")
+			} else {
+				p.mu.Unlock()
+			}
+			conn.closes.Add(1)
+			return nil
+		})
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// expireIdle backdates the idle connections past the idle timeout
+func expireIdle(p *connPool[*fakeConn]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.idle {
+		p.idle[i].returned = time.Now().Add(-2 * p.config.IdleTimeout)
+	}
+}
+
+func TestConnPoolClosesExpiredConnectionsOutsideLock(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(t *testing.T, p *connPool[*fakeConn])
+	}{
+		{"when acquiring", func(t *testing.T, p *connPool[*fakeConn]) {
+			conn, err := p.acquire(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.release(conn, nil)
+		}},
+		{"when reaping", func(t *testing.T, p *connPool[*fakeConn]) { p.reapIdle() }},
+		{"when closing the pool", func(t *testing.T, p *connPool[*fakeConn]) { p.Close() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakePool(t)
+			conn, err := p.acquire(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.release(conn, nil)
+			expireIdle(p)
+
+			tt.close(t, p)
+			if n := conn.closes.Load(); n != 1 {
+				t.Errorf("expired connection closed %d times, want once", n)
+			}
+		})
+	}
+}
+
+func TestConnPoolClosesInterruptedConnectionsOnce(t *testing.T) {
+	p := newFakePool(t)
+	ctx, cancel := context.WithCancel(t.Context())
+	conn, err := p.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A download whose context is cancelled while its stream is open
+	reader := &pooledReader[*fakeConn]{
+		ReadCloser: io.NopCloser(strings.NewReader("")),
+		pool:       p,
+		conn:       conn,
+		stop:       p.watch(ctx, conn),
+	}
+	cancel()
+	if err := reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The slot is free again
+	acquireCtx, cancelAcquire := context.WithTimeout(t.Context(), time.Second)
+	defer cancelAcquire()
+	next, err := p.acquire(acquireCtx)
+	if err != nil {
+		t.Fatalf("acquire() after the interrupted download error = %v", err)
+	}
+	if next == conn {
+		t.Error("acquire() reused the closed connection")
+	}
+
+	// Gives the cancellation time to finish closing the connection
+	time.Sleep(20 * time.Millisecond)
+	if n := conn.closes.Load(); n != 1 {
+		t.Errorf("interrupted connection closed %d times, want once", n)
+	}
+}
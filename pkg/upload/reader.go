@@ -0,0 +1,34 @@
+package upload
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader stops a transfer as soon as its context is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// newContextReader wraps r so that reads fail once ctx is done
+func newContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+// Read implements io.Reader
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// contentLength returns the number of unread bytes of readers that report
+// it, such as bytes.Reader and strings.Reader, and -1 for other readers
+func contentLength(r io.Reader) int64 {
+	if sized, ok := r.(interface{ Len() int }); ok {
+		return int64(sized.Len())
+	}
+	return -1
+}
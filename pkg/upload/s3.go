@@ -2,6 +2,7 @@ package upload
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // S3Uploader implements the Uploader interface for AWS S3
@@ -58,7 +60,7 @@ func (u *S3Uploader) Upload(ctx context.Context, filepath string, content io.Rea
 
 	_, err := u.client.PutObject(ctx, input)
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
 	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", u.bucket, u.region, filepath)
@@ -73,8 +75,12 @@ func (u *S3Uploader) Download(ctx context.Context, filepath string) (io.ReadClos
 	}
 
 	result, err := u.client.GetObject(ctx, input)
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
 	}
 
 	return result.Body, nil
@@ -89,7 +95,7 @@ func (u *S3Uploader) Delete(ctx context.Context, filepath string) error {
 
 	_, err := u.client.DeleteObject(ctx, input)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
 	}
 
 	return nil
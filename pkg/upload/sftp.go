@@ -2,31 +2,44 @@ package upload
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path"
-	"time"
+
+	"go-microservice/internal/config"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
-// SFTPConfig holds SFTP connection settings
-type SFTPConfig struct {
-	Host       string `mapstructure:"host"`
-	Port       string `mapstructure:"port"`
-	Username   string `mapstructure:"username"`
-	Password   string `mapstructure:"password"`
-	PrivateKey string `mapstructure:"private_key"`
-	BaseDir    string `mapstructure:"base_dir"`
+// sftpConn is a pooled SFTP session together with its SSH transport
+type sftpConn struct {
+	ssh    *ssh.Client
+	client *sftp.Client
+}
+
+// ping checks that the SFTP session is still alive
+func (c *sftpConn) ping() error {
+	_, err := c.client.Getwd()
+	return err
+}
+
+// close closes the SFTP session and the underlying SSH connection
+func (c *sftpConn) close() error {
+	c.client.Close()
+	return c.ssh.Close()
 }
 
 // SFTPUploader implements the Uploader interface for SFTP
 type SFTPUploader struct {
-	client  *sftp.Client
-	config  *SFTPConfig
-	baseURL string
+	pool      *connPool[*sftpConn]
+	sshConfig *ssh.ClientConfig
+	config    *config.SFTPConfig
+	baseURL   string
 }
 
 func init() {
@@ -35,16 +48,21 @@ func init() {
 
 // NewSFTPUploader creates a new SFTP uploader instance
 func NewSFTPUploader(cfg interface{}) (Uploader, error) {
-	sftpCfg, ok := cfg.(*SFTPConfig)
+	sftpCfg, ok := cfg.(*config.SFTPConfig)
 	if !ok {
 		return nil, ErrInvalidConfig
 	}
 
+	// Configure host key verification
+	hostKeyCallback, err := sftpHostKeyCallback(sftpCfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
 	// Configure SSH client
 	config := &ssh.ClientConfig{
 		User:            sftpCfg.Username,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         5 * time.Second,
+		HostKeyCallback: hostKeyCallback,
 	}
 
 	// Set authentication method
@@ -64,75 +82,172 @@ func NewSFTPUploader(cfg interface{}) (Uploader, error) {
 		config.Auth = []ssh.AuthMethod{ssh.Password(sftpCfg.Password)}
 	}
 
-	// Connect to SSH server
-	addr := fmt.Sprintf("%s:%s", sftpCfg.Host, sftpCfg.Port)
-	sshClient, err := ssh.Dial("tcp", addr, config)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	u := &SFTPUploader{
+		sshConfig: config,
+		config:    sftpCfg,
+		baseURL:   fmt.Sprintf("sftp://%s@%s:%s", sftpCfg.Username, sftpCfg.Host, sftpCfg.Port),
 	}
+	u.pool = newConnPool(sftpCfg.Pool, u.dial, (*sftpConn).ping, (*sftpConn).close)
 
-	// Create SFTP client
-	client, err := sftp.NewClient(sshClient)
+	// Establish the first connection eagerly so that bad settings fail fast
+	conn, err := u.pool.acquire(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		u.pool.Close()
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
 	// Create base directory if it doesn't exist
 	if sftpCfg.BaseDir != "" {
-		if err := client.MkdirAll(sftpCfg.BaseDir); err != nil {
+		if err := conn.client.MkdirAll(sftpCfg.BaseDir); err != nil {
 			// Ignore error if directory already exists
 		}
 	}
+	u.pool.release(conn, nil)
 
-	return &SFTPUploader{
-		client:  client,
-		config:  sftpCfg,
-		baseURL: fmt.Sprintf("sftp://%s@%s:%s", sftpCfg.Username, sftpCfg.Host, sftpCfg.Port),
-	}, nil
+	return u, nil
+}
+
+// sftpHostKeyCallback builds the host key check from the known_hosts file.
+// Skipping verification has to be requested explicitly.
+func sftpHostKeyCallback(cfg *config.SFTPConfig) (ssh.HostKeyCallback, error) {
+	if cfg.KnownHostsFile != "" {
+		callback, err := knownhosts.New(cfg.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known_hosts file: %w", err)
+		}
+		return callback, nil
+	}
+	if cfg.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	return nil, errors.New("known_hosts_file is required unless insecure_ignore_host_key is set")
+}
+
+// dial opens a new SSH connection and starts an SFTP session on it
+func (u *SFTPUploader) dial(ctx context.Context) (*sftpConn, error) {
+	addr := fmt.Sprintf("%s:%s", u.config.Host, u.config.Port)
+
+	// Connect to SSH server
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Abort the SSH handshake if the dial context expires
+	stop := context.AfterFunc(ctx, func() { netConn.Close() })
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, u.sshConfig)
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+
+	// Create SFTP client
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+
+	return &sftpConn{ssh: sshClient, client: client}, nil
 }
 
 // Upload implements the Uploader interface
 func (u *SFTPUploader) Upload(ctx context.Context, filepath string, content io.Reader, contentType string) (string, error) {
+	conn, err := u.pool.acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
+	}
+
+	// Abort the transfer if the context is cancelled
+	stop := u.pool.watch(ctx, conn)
+	fullPath, err := u.upload(conn, filepath, content)
+	if !stop() {
+		u.pool.forget()
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, ctx.Err())
+	}
+	u.pool.release(conn, err)
+	if err != nil {
+		return "", err
+	}
+
+	// Return SFTP URL
+	return fmt.Sprintf("%s/%s", u.baseURL, fullPath), nil
+}
+
+// upload writes content to the given path over an established session
+func (u *SFTPUploader) upload(conn *sftpConn, filepath string, content io.Reader) (string, error) {
 	// Create full path
 	fullPath := path.Join(u.config.BaseDir, filepath)
 
 	// Create directories if they don't exist
 	dir := path.Dir(fullPath)
-	if err := u.client.MkdirAll(dir); err != nil {
+	if err := conn.client.MkdirAll(dir); err != nil {
 		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	// Create file
-	file, err := u.client.Create(fullPath)
+	file, err := conn.client.Create(fullPath)
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 	defer file.Close()
 
 	// Copy content
 	if _, err := io.Copy(file, content); err != nil {
-		return "", fmt.Errorf("%w: %v", ErrUploadFailed, err)
+		return "", fmt.Errorf("%w: %w", ErrUploadFailed, err)
 	}
 
-	// Return SFTP URL
-	return fmt.Sprintf("%s/%s", u.baseURL, fullPath), nil
+	return fullPath, nil
 }
 
-// Download implements the Uploader interface
+// Download implements the Uploader interface. The connection stays checked out
+// of the pool until the returned reader is closed.
 func (u *SFTPUploader) Download(ctx context.Context, filepath string) (io.ReadCloser, error) {
+	conn, err := u.pool.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
+	}
+
 	fullPath := path.Join(u.config.BaseDir, filepath)
-	file, err := u.client.Open(fullPath)
+	stop := u.pool.watch(ctx, conn)
+	file, err := conn.client.Open(fullPath)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDownloadFailed, err)
+		if !stop() {
+			u.pool.forget()
+			return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, ctx.Err())
+		}
+		u.pool.release(conn, err)
+		if isSFTPNotFound(err) {
+			return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrDownloadFailed, err)
 	}
-	return file, nil
+
+	return &pooledReader[*sftpConn]{
+		ReadCloser: file,
+		pool:       u.pool,
+		conn:       conn,
+		stop:       stop,
+	}, nil
 }
 
 // Delete implements the Uploader interface
 func (u *SFTPUploader) Delete(ctx context.Context, filepath string) error {
+	conn, err := u.pool.acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
+	}
+
 	fullPath := path.Join(u.config.BaseDir, filepath)
-	if err := u.client.Remove(fullPath); err != nil {
-		return fmt.Errorf("%w: %v", ErrDeleteFailed, err)
+	err = conn.client.Remove(fullPath)
+	u.pool.release(conn, err)
+	if err != nil && !isSFTPNotFound(err) {
+		return fmt.Errorf("%w: %w", ErrDeleteFailed, err)
 	}
 	return nil
 }
@@ -143,7 +258,16 @@ func (u *SFTPUploader) GetURL(ctx context.Context, filepath string) (string, err
 	return fmt.Sprintf("%s/%s", u.baseURL, fullPath), nil
 }
 
-// Close closes the SFTP connection
+// Close closes all pooled SFTP connections
 func (u *SFTPUploader) Close() error {
-	return u.client.Close()
+	return u.pool.Close()
+}
+
+// isSFTPNotFound reports whether the server answered that the file does not exist
+func isSFTPNotFound(err error) bool {
+	if errors.Is(err, os.ErrNotExist) {
+		return true
+	}
+	var statusErr *sftp.StatusError
+	return errors.As(err, &statusErr) && statusErr.FxCode() == sftp.ErrSSHFxNoSuchFile
 }
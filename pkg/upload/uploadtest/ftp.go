@@ -0,0 +1,251 @@
+package uploadtest
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-microservice/internal/config"
+)
+
+// Credentials accepted by the in-process FTP server
+const (
+	FTPUsername = "uploadtest"
+	FTPPassword = "uploadtest"
+)
+
+// NewFTPServer starts an in-process FTP server with an in-memory filesystem
+// and returns a config for connecting to it. The server implements the
+// commands the uploader needs, with passive data connections over EPSV.
+// The server stops when the test finishes.
+func NewFTPServer(t *testing.T) *config.FTPConfig {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	// All sessions share one filesystem, like clients of a real server
+	fs := &ftpFS{
+		files: make(map[string][]byte),
+		dirs:  map[string]bool{"/": true},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go (&ftpSession{fs: fs, conn: textproto.NewConn(conn)}).serve()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return &config.FTPConfig{
+		Host:     addr.IP.String(),
+		Port:     strconv.Itoa(addr.Port),
+		Username: FTPUsername,
+		Password: FTPPassword,
+		BaseDir:  "/uploads",
+	}
+}
+
+// ftpFS is the in-memory filesystem of the FTP server
+type ftpFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+// ftpSession is a single control connection
+type ftpSession struct {
+	fs       *ftpFS
+	conn     *textproto.Conn
+	user     string
+	loggedIn bool
+	passive  net.Listener // listener of the next data connection
+}
+
+// serve handles commands until the client quits or disconnects
+func (s *ftpSession) serve() {
+	defer s.conn.Close()
+	defer s.closePassive()
+
+	s.reply(220, "uploadtest FTP server ready")
+	for {
+		line, err := s.conn.ReadLine()
+		if err != nil {
+			return
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		command = strings.ToUpper(command)
+
+		if !s.loggedIn && command != "USER" && command != "PASS" && command != "QUIT" {
+			s.reply(530, "not logged in")
+			continue
+		}
+
+		switch command {
+		case "USER":
+			s.user = arg
+			s.reply(331, "password required")
+		case "PASS":
+			if s.user != FTPUsername || arg != FTPPassword {
+				s.reply(530, "login incorrect")
+				continue
+			}
+			s.loggedIn = true
+			s.reply(230, "logged in")
+		case "FEAT":
+			s.reply(211, "no features")
+		case "TYPE", "NOOP":
+			s.reply(200, "ok")
+		case "EPSV":
+			s.epsv()
+		case "MKD":
+			s.mkd(clean(arg))
+		case "STOR":
+			s.stor(clean(arg))
+		case "RETR":
+			s.retr(clean(arg))
+		case "DELE":
+			s.dele(clean(arg))
+		case "QUIT":
+			s.reply(221, "bye")
+			return
+		default:
+			s.reply(502, "command not implemented")
+		}
+	}
+}
+
+// epsv opens the listener of a passive data connection
+func (s *ftpSession) epsv() {
+	s.closePassive()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		s.reply(425, "cannot open data connection")
+		return
+	}
+	s.passive = listener
+	port := listener.Addr().(*net.TCPAddr).Port
+	s.reply(229, "entering extended passive mode (|||"+strconv.Itoa(port)+"|)")
+}
+
+// dataConn accepts the data connection opened after EPSV
+func (s *ftpSession) dataConn() (net.Conn, bool) {
+	if s.passive == nil {
+		s.reply(425, "use EPSV first")
+		return nil, false
+	}
+	defer s.closePassive()
+
+	conn, err := s.passive.Accept()
+	if err != nil {
+		s.reply(425, "cannot open data connection")
+		return nil, false
+	}
+	return conn, true
+}
+
+func (s *ftpSession) closePassive() {
+	if s.passive != nil {
+		s.passive.Close()
+		s.passive = nil
+	}
+}
+
+func (s *ftpSession) mkd(dir string) {
+	s.fs.mu.Lock()
+	defer s.fs.mu.Unlock()
+
+	if s.fs.dirs[dir] || s.fs.files[dir] != nil || !s.fs.dirs[path.Dir(dir)] {
+		s.reply(550, "cannot create directory")
+		return
+	}
+	s.fs.dirs[dir] = true
+	s.reply(257, strconv.Quote(dir)+" created")
+}
+
+func (s *ftpSession) stor(file string) {
+	s.fs.mu.Lock()
+	parentExists := s.fs.dirs[path.Dir(file)] && !s.fs.dirs[file]
+	s.fs.mu.Unlock()
+	if !parentExists {
+		s.closePassive()
+		s.reply(553, "cannot store file")
+		return
+	}
+
+	s.reply(150, "ok to send data")
+	conn, ok := s.dataConn()
+	if !ok {
+		return
+	}
+	var content bytes.Buffer
+	_, err := io.Copy(&content, conn)
+	conn.Close()
+	if err != nil {
+		s.reply(426, "transfer aborted")
+		return
+	}
+
+	s.fs.mu.Lock()
+	s.fs.files[file] = content.Bytes()
+	s.fs.mu.Unlock()
+	s.reply(226, "transfer complete")
+}
+
+func (s *ftpSession) retr(file string) {
+	s.fs.mu.Lock()
+	content, exists := s.fs.files[file]
+	s.fs.mu.Unlock()
+	if !exists {
+		s.closePassive()
+		s.reply(550, "file unavailable")
+		return
+	}
+
+	s.reply(150, "opening data connection")
+	conn, ok := s.dataConn()
+	if !ok {
+		return
+	}
+	_, err := conn.Write(content)
+	conn.Close()
+	if err != nil {
+		s.reply(426, "transfer aborted")
+		return
+	}
+	s.reply(226, "transfer complete")
+}
+
+func (s *ftpSession) dele(file string) {
+	s.fs.mu.Lock()
+	defer s.fs.mu.Unlock()
+
+	if _, exists := s.fs.files[file]; !exists {
+		s.reply(550, "file unavailable")
+		return
+	}
+	delete(s.fs.files, file)
+	s.reply(250, "deleted")
+}
+
+func (s *ftpSession) reply(code int, message string) {
+	s.conn.PrintfLine("%d %s", code, message)
+}
+
+// clean resolves a path argument against the root directory
+func clean(p string) string {
+	return path.Clean("/" + p)
+}
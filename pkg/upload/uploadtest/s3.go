@@ -0,0 +1,97 @@
+package uploadtest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+
+	"go-microservice/internal/config"
+)
+
+// Credentials accepted by the in-process S3 server. Signatures are not checked.
+const (
+	S3AccessKeyID     = "uploadtest"
+	S3SecretAccessKey = "uploadtest"
+)
+
+// NewS3Server starts an in-process S3-compatible server with in-memory
+// buckets and returns a MinIO config for connecting to it. The bucket is left
+// for the uploader to create. The server stops when the test finishes.
+func NewS3Server(t *testing.T) *config.MinioConfig {
+	t.Helper()
+
+	fake := gofakes3.New(s3mem.New())
+	server := httptest.NewServer(decodeChunked(fake.Server()))
+	t.Cleanup(server.Close)
+
+	return &config.MinioConfig{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		Bucket:          "uploadtest",
+		AccessKeyID:     S3AccessKeyID,
+		SecretAccessKey: S3SecretAccessKey,
+	}
+}
+
+// decodeChunked turns request bodies sent with streaming signatures
+// (aws-chunked encoding, optionally with trailing checksums) into plain
+// bodies, which is all the fake understands
+func decodeChunked(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := readChunked(bufio.NewReader(r.Body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		r.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+		for _, header := range []string{"Content-Encoding", "X-Amz-Decoded-Content-Length", "X-Amz-Trailer"} {
+			r.Header.Del(header)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readChunked reads an aws-chunked body: chunks of "<hex size>;chunk-signature=...\r\n<data>\r\n"
+// up to an empty chunk, which may be followed by trailing headers
+func readChunked(r *bufio.Reader) ([]byte, error) {
+	var body bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("read chunk header: %w", err)
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse chunk size %q: %w", sizeHex, err)
+		}
+		if size == 0 {
+			// Trailers and the final CRLF are not needed
+			return body.Bytes(), nil
+		}
+
+		if _, err := io.CopyN(&body, r, size); err != nil {
+			return nil, fmt.Errorf("read chunk: %w", err)
+		}
+		if _, err := r.Discard(2); err != nil {
+			return nil, fmt.Errorf("read chunk: %w", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package uploadtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"go-microservice/internal/config"
+)
+
+// Credentials accepted by the in-process SFTP server
+const (
+	SFTPUsername = "uploadtest"
+	SFTPPassword = "uploadtest"
+)
+
+// NewSFTPServer starts an in-process SSH server with an in-memory SFTP
+// filesystem and returns a config for connecting to it. The server's host key
+// is written to a known_hosts file so host key verification is exercised.
+// The server stops when the test finishes.
+func NewSFTPServer(t *testing.T) *config.SFTPConfig {
+	t.Helper()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate host key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatalf("create host key signer: %v", err)
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == SFTPUsername && string(password) == SFTPPassword {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	// All sessions share one in-memory filesystem, like clients of a real server
+	handlers := sftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, serverConfig, handlers)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr.String())}, signer.PublicKey())
+	if err := os.WriteFile(knownHostsFile, []byte(line+"\n"), 0600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+
+	return &config.SFTPConfig{
+		Host:           addr.IP.String(),
+		Port:           strconv.Itoa(addr.Port),
+		Username:       SFTPUsername,
+		Password:       SFTPPassword,
+		BaseDir:        "/uploads",
+		KnownHostsFile: knownHostsFile,
+	}
+}
+
+// serveSFTP handles a single SSH connection, serving SFTP on session channels
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig, handlers sftp.Handlers) {
+	defer conn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}(requests)
+
+		go func() {
+			defer channel.Close()
+			server := sftp.NewRequestServer(channel, handlers)
+			server.Serve()
+			server.Close()
+		}()
+	}
+}
@@ -0,0 +1,300 @@
+// Package uploadtest provides a conformance suite that every upload.Uploader
+// implementation is expected to pass.
+//
+// A backend test only needs a factory returning a fresh uploader:
+//
+//	func TestLocalUploader(t *testing.T) {
+//		uploadtest.Run(t, func(t *testing.T) upload.Uploader {
+//			u, err := upload.NewUploader("local", &config.LocalConfig{
+//				BaseDir:    t.TempDir(),
+//				CreateDirs: true,
+//			})
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return u
+//		})
+//	}
+package uploadtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"go-microservice/pkg/upload"
+)
+
+// Factory creates a fresh uploader for a single test. Uploaders that
+// implement io.Closer are closed when the test finishes.
+type Factory func(t *testing.T) upload.Uploader
+
+// largeStreamSize is the size of the payload used by the large stream test
+const largeStreamSize = 8 << 20
+
+// Run runs the conformance suite against the uploader returned by factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, u upload.Uploader)
+	}{
+		{"RoundTrip", testRoundTrip},
+		{"Overwrite", testOverwrite},
+		{"NestedPath", testNestedPath},
+		{"DownloadMissing", testDownloadMissing},
+		{"DeleteMissing", testDeleteMissing},
+		{"DeleteThenDownload", testDeleteThenDownload},
+		{"GetURL", testGetURL},
+		{"EmptyFile", testEmptyFile},
+		{"LargeStream", testLargeStream},
+		{"CancelledContext", testCancelledContext},
+		{"CancelMidTransfer", testCancelMidTransfer},
+		{"PathTraversal", testPathTraversal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := factory(t)
+			if closer, ok := u.(io.Closer); ok {
+				t.Cleanup(func() { closer.Close() })
+			}
+			tt.fn(t, u)
+		})
+	}
+}
+
+func testRoundTrip(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+	content := []byte("hello, conformance suite")
+
+	mustUpload(t, u, ctx, "roundtrip.txt", content)
+	assertContent(t, u, ctx, "roundtrip.txt", content)
+}
+
+func testOverwrite(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	mustUpload(t, u, ctx, "overwrite.txt", []byte("first version with more bytes"))
+	mustUpload(t, u, ctx, "overwrite.txt", []byte("second"))
+	assertContent(t, u, ctx, "overwrite.txt", []byte("second"))
+}
+
+func testNestedPath(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+	content := []byte("nested")
+
+	mustUpload(t, u, ctx, "a/b/c/nested.txt", content)
+	assertContent(t, u, ctx, "a/b/c/nested.txt", content)
+
+	if err := u.Delete(ctx, "a/b/c/nested.txt"); err != nil {
+		t.Fatalf("Delete nested path: %v", err)
+	}
+}
+
+func testDownloadMissing(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	reader, err := u.Download(ctx, "does-not-exist.txt")
+	if err == nil {
+		reader.Close()
+		t.Fatal("Download of missing file succeeded, want ErrNotFound")
+	}
+	if !errors.Is(err, upload.ErrNotFound) {
+		t.Fatalf("Download of missing file returned %v, want ErrNotFound", err)
+	}
+}
+
+func testDeleteMissing(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	if err := u.Delete(ctx, "does-not-exist.txt"); err != nil {
+		t.Fatalf("Delete of missing file returned %v, want nil", err)
+	}
+}
+
+func testDeleteThenDownload(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	mustUpload(t, u, ctx, "deleted.txt", []byte("soon gone"))
+	if err := u.Delete(ctx, "deleted.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	reader, err := u.Download(ctx, "deleted.txt")
+	if err == nil {
+		reader.Close()
+		t.Fatal("Download after Delete succeeded, want ErrNotFound")
+	}
+	if !errors.Is(err, upload.ErrNotFound) {
+		t.Fatalf("Download after Delete returned %v, want ErrNotFound", err)
+	}
+}
+
+func testGetURL(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	mustUpload(t, u, ctx, "url.txt", []byte("url"))
+	url, err := u.GetURL(ctx, "url.txt")
+	if errors.Is(err, upload.ErrURLNotSupported) {
+		return
+	}
+	if err != nil {
+		t.Fatalf("GetURL: %v", err)
+	}
+	if url == "" {
+		t.Fatal("GetURL returned an empty URL without ErrURLNotSupported")
+	}
+	if !strings.Contains(url, "url.txt") {
+		t.Errorf("GetURL returned %q, want it to reference url.txt", url)
+	}
+}
+
+func testEmptyFile(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	mustUpload(t, u, ctx, "empty.txt", nil)
+	assertContent(t, u, ctx, "empty.txt", nil)
+}
+
+func testLargeStream(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	// Stream through a plain io.Reader so backends cannot rely on knowing the size
+	source := io.LimitReader(rand.New(rand.NewSource(1)), largeStreamSize)
+	hash := sha256.New()
+	if _, err := u.Upload(ctx, "large.bin", io.TeeReader(source, hash), "application/octet-stream"); err != nil {
+		t.Fatalf("Upload large stream: %v", err)
+	}
+	want := hash.Sum(nil)
+
+	reader, err := u.Download(ctx, "large.bin")
+	if err != nil {
+		t.Fatalf("Download large stream: %v", err)
+	}
+	defer reader.Close()
+
+	hash.Reset()
+	n, err := io.Copy(hash, reader)
+	if err != nil {
+		t.Fatalf("Read large stream: %v", err)
+	}
+	if n != largeStreamSize {
+		t.Fatalf("Downloaded %d bytes, want %d", n, largeStreamSize)
+	}
+	if !bytes.Equal(hash.Sum(nil), want) {
+		t.Fatal("Downloaded content does not match uploaded content")
+	}
+}
+
+func testCancelledContext(t *testing.T, u upload.Uploader) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := u.Upload(ctx, "cancelled.txt", strings.NewReader("never stored"), "text/plain"); err == nil {
+		t.Fatal("Upload with cancelled context succeeded")
+	}
+}
+
+func testCancelMidTransfer(t *testing.T, u upload.Uploader) {
+	ctx, cancel := context.WithCancel(testContext(t))
+	defer cancel()
+
+	// Cancel once part of the payload has been consumed
+	source := &cancellingReader{
+		r:      io.LimitReader(rand.New(rand.NewSource(2)), largeStreamSize),
+		after:  largeStreamSize / 4,
+		cancel: cancel,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := u.Upload(ctx, "cancel-mid.bin", source, "application/octet-stream")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Upload cancelled mid-transfer succeeded")
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("Upload did not return after its context was cancelled")
+	}
+
+	// The backend must remain usable after an aborted transfer
+	mustUpload(t, u, testContext(t), "after-cancel.txt", []byte("still works"))
+	assertContent(t, u, testContext(t), "after-cancel.txt", []byte("still works"))
+}
+
+func testPathTraversal(t *testing.T, u upload.Uploader) {
+	ctx := testContext(t)
+
+	for _, key := range []string{"../escape.txt", "a/../../escape.txt", "/etc/escape.txt"} {
+		if _, err := u.Upload(ctx, key, strings.NewReader("escape"), "text/plain"); err == nil {
+			u.Delete(ctx, key)
+			t.Errorf("Upload(%q) succeeded, want the key to be rejected", key)
+		}
+	}
+}
+
+// cancellingReader cancels a context once a number of bytes has been read
+type cancellingReader struct {
+	r      io.Reader
+	read   int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += n
+	if r.read >= r.after {
+		r.cancel()
+	}
+	return n, err
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func mustUpload(t *testing.T, u upload.Uploader, ctx context.Context, path string, content []byte) {
+	t.Helper()
+	if _, err := u.Upload(ctx, path, bytes.NewReader(content), "text/plain"); err != nil {
+		t.Fatalf("Upload(%q): %v", path, err)
+	}
+}
+
+func assertContent(t *testing.T, u upload.Uploader, ctx context.Context, path string, want []byte) {
+	t.Helper()
+
+	reader, err := u.Download(ctx, path)
+	if err != nil {
+		t.Fatalf("Download(%q): %v", path, err)
+	}
+	defer reader.Close()
+
+	got, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Read(%q): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Download(%q) = %s, want %s", path, preview(got), preview(want))
+	}
+}
+
+func preview(b []byte) string {
+	if len(b) > 64 {
+		return fmt.Sprintf("%q... (%d bytes)", b[:64], len(b))
+	}
+	return fmt.Sprintf("%q", b)
+}